@@ -1,22 +1,48 @@
 package main
 
 import (
+	"airline-booking/internal/aircraft"
 	"airline-booking/internal/booking"
+	"airline-booking/internal/pricing"
+	"airline-booking/internal/tax"
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/cache"
 	"airline-booking/pkg/config"
 	"airline-booking/pkg/db"
 	"airline-booking/pkg/kafka"
+	"airline-booking/pkg/logger"
+	"airline-booking/pkg/money"
+	"airline-booking/pkg/ratelimit"
 	"airline-booking/pkg/redis"
+	"airline-booking/pkg/server"
+	"context"
 	"log"
 	"net/http"
+	"time"
 )
 
 func main() {
+	logger.Init()
+
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		log.Fatalf("Error loading config: %v", err)
 	}
+	go cfg.SecretStore.Watch(context.Background(), cfg.Secrets.RefreshInterval)
+
+	svc, err := cfg.Service("booking-service")
+	if err != nil {
+		log.Fatalf("Error loading config: %v", err)
+	}
 
-	pg, err := db.ConnectPostgres(&cfg.Postgres)
+	// Apply runtime settings now and again whenever the config files change
+	reloader := config.NewReloader(cfg)
+	go reloader.Watch(context.Background())
+	config.Subscribe(reloader, func(rt config.RuntimeConfig) string { return rt.LogLevel }, func(level string) {
+		logger.SetLevel(level)
+	})
+
+	pg, err := db.Connect(&cfg.Postgres)
 	if err != nil {
 		log.Fatalf("Database connection failed: %v", err)
 	}
@@ -25,7 +51,6 @@ func main() {
 
 	redisClient := redis.NewRedisClient(&cfg.Redis)
 	defer redisClient.Close()
-	log.Println("Connected to Redis")
 
 	producer, err := kafka.NewProducer(&cfg.Kafka)
 	if err != nil {
@@ -34,20 +59,60 @@ func main() {
 	defer producer.Close()
 	log.Println("Connected to Kafka")
 
-	repo := booking.NewRepository(pg, redisClient.GetClient())
-	handler := booking.NewHandler(repo, producer, cfg.Kafka.Topic)
-
-	http.HandleFunc("/bookings", func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodPost:
-			handler.AddBooking(w, r)
-		case http.MethodGet:
-			handler.GetBookings(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	// Rates for showing prices in other currencies, loaded again when the
+	// rate file or its setting changes
+	rates := money.NewRates()
+	config.Subscribe(reloader, func(rt config.RuntimeConfig) string { return rt.ExchangeRates.File }, func(file string) {
+		if err := rates.LoadFile(file); err != nil {
+			log.Printf("Failed to load exchange rates: %v", err)
+		}
+	})
+	go rates.Watch(context.Background(), time.Minute)
+
+	locker := redis.NewLocker(redisClient)
+	bookingCache := cache.New(redisClient)
+	repo := booking.NewRepository(pg, bookingCache, locker, aircraft.NewRepository(pg, bookingCache), pricing.NewRepository(pg, bookingCache),
+		rates, tax.NewRepository(pg, bookingCache))
+	config.Subscribe(reloader, func(rt config.RuntimeConfig) time.Duration { return rt.Cache.BookingsTTL }, repo.SetCacheTTL)
+	handler := booking.NewHandler(repo, producer, svc.Topics.Produce)
+
+	// Release seats of expired holds, on one instance at a time
+	if svc.Enabled("holdExpiry") {
+		go locker.RunSingleton(context.Background(), "hold-expiry", time.Minute, repo.ExpireHolds)
+	}
+
+	// Follow flight-service reschedules and cancellations: flag, move or
+	// refund the affected bookings and notify their passengers
+	if svc.Enabled("flightEvents") {
+		consumerCfg := cfg.Kafka
+		consumerCfg.GroupID = svc.ConsumerGroup
+		consumer, err := kafka.NewConsumer(&consumerCfg)
+		if err != nil {
+			log.Fatalf("Kafka consumer connection failed: %v", err)
+		}
+		defer consumer.Close()
+
+		events := &booking.FlightEvents{
+			Repo:     repo,
+			Notifier: booking.EventNotifier{Producer: producer, Topic: svc.Topics.Produce},
+			Producer: producer,
+			Topic:    svc.Topics.Produce,
 		}
+		go consumer.Consume(context.Background(), svc.Topics.Consume, kafka.EventHandler(events.Handle))
+	}
+
+	mux := http.NewServeMux()
+	if svc.Enabled("api") {
+		handler.Routes(mux)
+	}
+
+	limiter := ratelimit.New()
+	config.Subscribe(reloader, func(rt config.RuntimeConfig) config.RateLimitConfig { return rt.RateLimit }, func(rl config.RateLimitConfig) {
+		limiter.SetLimit(rl.RequestsPerSecond, rl.Burst)
 	})
 
-	log.Println("Booking service running on port 8081...")
-	log.Fatal(http.ListenAndServe(":8081", nil))
+	mux.HandleFunc("GET /health/cache", redisClient.HealthHandler)
+	mux.HandleFunc("GET /health/db", db.StatsHandler(pg))
+
+	log.Fatal(server.ListenAndServe("Booking service", svc, pg.ReadYourWrites(limiter.Middleware(apperr.Fallback(mux)))))
 }
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"airline-booking/pkg/config"
+)
+
+// config-check validates a configuration offline, without connecting to
+// Postgres, Kafka or Redis. It accepts the same flags as the services:
+//
+//	config-check --config-dir ./configs
+//	config-check --config-file prod.yml --set postgres.host=db
+func main() {
+	opts, err := config.ParseFlags(os.Args[1:])
+	if err != nil {
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load(opts)
+	if err != nil {
+		var invalid *config.ValidationError
+		if errors.As(err, &invalid) {
+			fmt.Fprintf(os.Stderr, "Configuration has %d problems:\n", len(invalid.Problems))
+			for _, p := range invalid.Problems {
+				fmt.Fprintf(os.Stderr, "  %-35s %s\n", p.Key, p.Message)
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		}
+		os.Exit(1)
+	}
+
+	if opts.PrintConfig {
+		config.Print(os.Stdout, cfg)
+	}
+	fmt.Println("Configuration is valid")
+}
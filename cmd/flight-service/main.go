@@ -1,25 +1,53 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"time"
 
+	"airline-booking/internal/aircraft"
+	"airline-booking/internal/fare"
 	"airline-booking/internal/flight"
+	"airline-booking/internal/pricing"
+	"airline-booking/internal/reference"
+	"airline-booking/internal/tax"
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/cache"
 	"airline-booking/pkg/config"
 	"airline-booking/pkg/db"
 	"airline-booking/pkg/kafka"
+	"airline-booking/pkg/logger"
+	"airline-booking/pkg/money"
+	"airline-booking/pkg/ratelimit"
 	"airline-booking/pkg/redis"
+	"airline-booking/pkg/server"
 )
 
 func main() {
+	logger.Init()
+
 	// Load configuration
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		log.Fatalf("Error loading config: %v", err)
 	}
+	go cfg.SecretStore.Watch(context.Background(), cfg.Secrets.RefreshInterval)
+
+	svc, err := cfg.Service("flight-service")
+	if err != nil {
+		log.Fatalf("Error loading config: %v", err)
+	}
+
+	// Apply runtime settings now and again whenever the config files change
+	reloader := config.NewReloader(cfg)
+	go reloader.Watch(context.Background())
+	config.Subscribe(reloader, func(rt config.RuntimeConfig) string { return rt.LogLevel }, func(level string) {
+		logger.SetLevel(level)
+	})
 
 	// Connect to PostgreSQL
-	pg, err := db.ConnectPostgres(&cfg.Postgres)
+	pg, err := db.Connect(&cfg.Postgres)
 	if err != nil {
 		log.Fatalf("Database connection failed: %v", err)
 	}
@@ -29,7 +57,6 @@ func main() {
 	// Connect to Redis
 	redisClient := redis.NewRedisClient(&cfg.Redis)
 	defer redisClient.Close()
-	log.Println("Connected to Redis")
 
 	// Initialize Kafka Producer (Sarama)
 	producer, err := kafka.NewProducer(&cfg.Kafka)
@@ -39,25 +66,53 @@ func main() {
 	defer producer.Close()
 	log.Println("Connected to Kafka Producer")
 
-	// Initialize Repository and Handler
-	repo := flight.NewRepository(pg, redisClient.GetClient())
-	handler := flight.NewHandler(repo, producer, cfg.Kafka.Topic)
+	// Rates for showing prices in other currencies, loaded again when the
+	// rate file or its setting changes
+	rates := money.NewRates()
+	config.Subscribe(reloader, func(rt config.RuntimeConfig) string { return rt.ExchangeRates.File }, func(file string) {
+		if err := rates.LoadFile(file); err != nil {
+			log.Printf("Failed to load exchange rates: %v", err)
+		}
+	})
+	go rates.Watch(context.Background(), time.Minute)
+
+	// Initialize Repositories and Handlers
+	flightCache := cache.New(redisClient)
+	repo := flight.NewRepository(pg, flightCache)
+	config.Subscribe(reloader, func(rt config.RuntimeConfig) time.Duration { return rt.Cache.FlightsTTL }, repo.SetCacheTTL)
+
+	// Flights are checked against the airport and airline reference data
+	// unless the reference feature is switched off
+	var refRepo *reference.Repository
+	if svc.Enabled("reference") {
+		refRepo = reference.NewRepository(pg, flightCache)
+	}
+	fleet := aircraft.NewRepository(pg, flightCache)
+	prices := pricing.NewRepository(pg, flightCache)
+	handler := flight.NewHandler(repo, producer, svc.Topics.Produce, refRepo, fleet, fare.NewRepository(pg), prices, rates)
 
 	// Define HTTP routes
-	http.HandleFunc("/flights", func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodGet:
-			handler.GetFlights(w, r)
-		case http.MethodPost:
-			handler.AddFlight(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	mux := http.NewServeMux()
+	if svc.Enabled("api") {
+		handler.Routes(mux)
+		aircraft.NewHandler(fleet).Routes(mux)
+		pricing.NewHandler(prices, rates).Routes(mux)
+		tax.NewHandler(tax.NewRepository(pg, flightCache), rates).Routes(mux)
+		if refRepo != nil {
+			reference.NewHandler(refRepo).Routes(mux)
 		}
+	}
+
+	limiter := ratelimit.New()
+	config.Subscribe(reloader, func(rt config.RuntimeConfig) config.RateLimitConfig { return rt.RateLimit }, func(rl config.RateLimitConfig) {
+		limiter.SetLimit(rl.RequestsPerSecond, rl.Burst)
 	})
 
+	mux.HandleFunc("GET /health/cache", redisClient.HealthHandler)
+	mux.HandleFunc("GET /health/db", db.StatsHandler(pg))
+
 	log.Println("Flight service started successfully — all connections active.")
-	log.Println("Listening on port 8080...")
-	if err := http.ListenAndServe(":8080", nil); err != nil {
+	if err := server.ListenAndServe("Flight service", svc, pg.ReadYourWrites(limiter.Middleware(apperr.Fallback(mux)))); err != nil {
 		log.Fatalf("Server failed: %v", err)
 	}
 }
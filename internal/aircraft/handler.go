@@ -0,0 +1,64 @@
+package aircraft
+
+import (
+	"net/http"
+	"strings"
+
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/request"
+	"airline-booking/pkg/response"
+)
+
+// Handler serves the aircraft type definitions.
+type Handler struct {
+	Repo *Repository
+}
+
+// NewHandler creates a new aircraft type handler.
+func NewHandler(repo *Repository) *Handler {
+	return &Handler{Repo: repo}
+}
+
+// Routes registers the aircraft type endpoints on mux.
+func (h *Handler) Routes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /aircraft", h.ListTypes)
+	mux.HandleFunc("GET /aircraft/{code}", h.GetType)
+	mux.HandleFunc("PUT /aircraft/{code}", h.PutType)
+}
+
+// ListTypes returns all aircraft types.
+func (h *Handler) ListTypes(w http.ResponseWriter, r *http.Request) {
+	types, err := h.Repo.ListTypes(r.Context())
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, types)
+}
+
+// GetType returns the aircraft type given by the code path parameter.
+func (h *Handler) GetType(w http.ResponseWriter, r *http.Request) {
+	t, err := h.Repo.Type(r.Context(), r.PathValue("code"))
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, t)
+}
+
+// PutType creates or replaces the aircraft type given by the code path
+// parameter.
+func (h *Handler) PutType(w http.ResponseWriter, r *http.Request) {
+	t := Type{Code: strings.ToUpper(r.PathValue("code"))}
+	if err := request.Decode(w, r, &t); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	t.Code = strings.ToUpper(r.PathValue("code"))
+
+	if err := h.Repo.SaveType(r.Context(), t); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, t)
+}
@@ -0,0 +1,291 @@
+package aircraft
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/jsonb"
+	"airline-booking/pkg/money"
+	"airline-booking/pkg/validate"
+)
+
+// Cabin classes, from the front of the aircraft to the back
+const (
+	ClassFirst          = "first"
+	ClassBusiness       = "business"
+	ClassPremiumEconomy = "premium_economy"
+	ClassEconomy        = "economy"
+)
+
+// aisle separates seat letters in Cabin.Letters.
+const aisle = '-'
+
+var (
+	// ErrTypeNotFound is returned when no aircraft type has the given code.
+	ErrTypeNotFound = apperr.NotFound("aircraft_type_not_found", "aircraft type not found")
+	// ErrSeatsStranded is returned when a layout change would leave
+	// passengers in seats the new layout does not sell.
+	ErrSeatsStranded = apperr.Conflict("seats_stranded", "passengers are assigned seats the new layout does not have")
+)
+
+// Type is an aircraft type and its seating, e.g. A320 in a two-class layout.
+type Type struct {
+	Code   string `db:"code" json:"code" validate:"required,max=10,code"`
+	Name   string `db:"name" json:"name" validate:"required,max=100"`
+	Cabins Cabins `db:"cabins" json:"cabins" validate:"required"`
+}
+
+// Cabin is a block of rows sharing a class and seat letters.
+type Cabin struct {
+	Class    string `json:"class" validate:"required,oneof=first business premium_economy economy"`
+	FirstRow int    `json:"first_row" validate:"min=1"`
+	LastRow  int    `json:"last_row" validate:"min=1"`
+	// Letters are the seats of a row from left to right with "-" for each
+	// aisle, e.g. "ABC-DEF"
+	Letters  string `json:"letters" validate:"required,max=20"`
+	ExitRows []int  `json:"exit_rows,omitempty"`
+	// Blocked seats are never sold, e.g. "12C" for a crew rest seat
+	Blocked []string `json:"blocked,omitempty"`
+	// SeatPrice is charged for choosing a seat in the cabin and
+	// ExitRowPrice on top of it for an exit row, in the same currency.
+	// Assigned seats are free.
+	SeatPrice    money.Money `json:"seat_price,omitzero" validate:"min=0"`
+	ExitRowPrice money.Money `json:"exit_row_price,omitzero" validate:"min=0"`
+}
+
+func init() {
+	validate.Register(Type{}, Cabin{})
+}
+
+// Cabins is stored as a JSON column.
+type Cabins []Cabin
+
+// Value implements driver.Valuer.
+func (c Cabins) Value() (driver.Value, error) {
+	return jsonb.Value(c)
+}
+
+// Scan implements sql.Scanner.
+func (c *Cabins) Scan(src any) error {
+	return jsonb.Scan(src, c)
+}
+
+// Seat is one seat of a layout.
+type Seat struct {
+	Number  string `json:"number"`
+	Row     int    `json:"row"`
+	Letter  string `json:"letter"`
+	Class   string `json:"class"`
+	Window  bool   `json:"window,omitempty"`
+	Aisle   bool   `json:"aisle,omitempty"`
+	Exit    bool   `json:"exit,omitempty"`
+	Blocked bool   `json:"blocked,omitempty"`
+	// Price is the charge for choosing the seat
+	Price money.Money `json:"price,omitzero"`
+}
+
+// Seats lists every seat of the layout, blocked ones included, row by row
+// from left to right.
+func (t Type) Seats() []Seat {
+	var seats []Seat
+	for _, c := range t.Cabins {
+		letters := []rune(c.Letters)
+		for row := c.FirstRow; row <= c.LastRow; row++ {
+			for i, l := range letters {
+				if l == aisle {
+					continue
+				}
+				number := strconv.Itoa(row) + string(l)
+				exit := slices.Contains(c.ExitRows, row)
+				price := c.SeatPrice
+				if exit {
+					// Validate rejects cabins whose prices differ in currency
+					price, _ = price.Add(c.ExitRowPrice)
+				}
+				seats = append(seats, Seat{
+					Number:  number,
+					Row:     row,
+					Letter:  string(l),
+					Class:   c.Class,
+					Window:  i == 0 || i == len(letters)-1,
+					Aisle:   (i > 0 && letters[i-1] == aisle) || (i < len(letters)-1 && letters[i+1] == aisle),
+					Exit:    exit,
+					Blocked: slices.Contains(c.Blocked, number),
+					Price:   price,
+				})
+			}
+		}
+	}
+	return seats
+}
+
+// Seat returns the seat with the given number, e.g. "12A".
+func (t Type) Seat(number string) (Seat, bool) {
+	number = strings.ToUpper(strings.TrimSpace(number))
+	for _, s := range t.Seats() {
+		if s.Number == number {
+			return s, true
+		}
+	}
+	return Seat{}, false
+}
+
+// CabinSeats counts the seats that can be sold in each cabin class.
+func (t Type) CabinSeats() map[string]int {
+	seats := map[string]int{}
+	for _, s := range t.Seats() {
+		if !s.Blocked {
+			seats[s.Class]++
+		}
+	}
+	return seats
+}
+
+// Stranded returns the seats of assigned, by number, that the layout does
+// not have or blocks.
+func (t Type) Stranded(assigned []string) []string {
+	var stranded []string
+	for _, number := range assigned {
+		if s, ok := t.Seat(number); !ok || s.Blocked {
+			stranded = append(stranded, number)
+		}
+	}
+	return stranded
+}
+
+// Capacity is the number of seats that can be sold.
+func (t Type) Capacity() int {
+	n := 0
+	for _, s := range t.Seats() {
+		if !s.Blocked {
+			n++
+		}
+	}
+	return n
+}
+
+// Validate checks each cabin, which the tag rules do not reach, and that
+// the cabins follow each other without overlapping.
+func (t Type) Validate() []apperr.FieldError {
+	var problems []apperr.FieldError
+	lastRow := 0
+	for i, c := range t.Cabins {
+		prefix := fmt.Sprintf("cabins[%d].", i)
+		fieldProblems := validate.Struct(c)
+		for _, p := range fieldProblems {
+			problems = append(problems, apperr.FieldError{Field: prefix + p.Field, Message: p.Message})
+		}
+		if len(fieldProblems) > 0 {
+			continue
+		}
+
+		if c.LastRow < c.FirstRow {
+			problems = append(problems, apperr.FieldError{Field: prefix + "last_row", Message: "must not be before first_row"})
+		}
+		if c.FirstRow <= lastRow {
+			problems = append(problems, apperr.FieldError{Field: prefix + "first_row", Message: "must come after the rows of the previous cabin"})
+		}
+		lastRow = max(lastRow, c.LastRow)
+
+		if msg := checkLetters(c.Letters); msg != "" {
+			problems = append(problems, apperr.FieldError{Field: prefix + "letters", Message: msg})
+		}
+		for _, row := range c.ExitRows {
+			if row < c.FirstRow || row > c.LastRow {
+				problems = append(problems, apperr.FieldError{Field: prefix + "exit_rows", Message: fmt.Sprintf("row %d is not in the cabin", row)})
+				break
+			}
+		}
+		if _, err := c.SeatPrice.Add(c.ExitRowPrice); err != nil {
+			problems = append(problems, apperr.FieldError{Field: prefix + "exit_row_price", Message: "must be in the currency of seat_price"})
+		}
+		layout := Type{Cabins: Cabins{c}}
+		for _, number := range c.Blocked {
+			if _, ok := layout.Seat(number); !ok || number != strings.ToUpper(number) {
+				problems = append(problems, apperr.FieldError{Field: prefix + "blocked", Message: fmt.Sprintf("seat %q is not in the cabin", number)})
+				break
+			}
+		}
+	}
+	if len(problems) == 0 && len(t.Cabins) > 0 && t.Capacity() == 0 {
+		problems = append(problems, apperr.FieldError{Field: "cabins", Message: "must have at least one seat that is not blocked"})
+	}
+	return problems
+}
+
+// checkLetters describes what is wrong with the seat letters of a row.
+func checkLetters(letters string) string {
+	if letters[0] == aisle || letters[len(letters)-1] == aisle || strings.Contains(letters, "--") {
+		return `must not start or end with an aisle, or have two aisles in a row`
+	}
+	seen := map[rune]bool{}
+	for _, l := range letters {
+		if l == aisle {
+			continue
+		}
+		if l < 'A' || l > 'Z' {
+			return `must contain only uppercase letters and "-"`
+		}
+		if seen[l] {
+			return fmt.Sprintf("has seat %c twice", l)
+		}
+		seen[l] = true
+	}
+	return ""
+}
+
+// Seat statuses in a seat map
+const (
+	SeatFree     = "free"
+	SeatOccupied = "occupied"
+	SeatBlocked  = "blocked"
+)
+
+// MapSeat is a seat of a seat map.
+type MapSeat struct {
+	Seat
+	Status string `json:"status"`
+}
+
+// Row is a row of a seat map. Layout gives its seat letters with "-" for
+// each aisle, as in Cabin.Letters.
+type Row struct {
+	Number int       `json:"number"`
+	Class  string    `json:"class"`
+	Layout string    `json:"layout"`
+	Exit   bool      `json:"exit,omitempty"`
+	Seats  []MapSeat `json:"seats"`
+}
+
+// Map lays out the seats row by row, marking the occupied ones, and
+// counts the seats still free.
+func (t Type) Map(occupied []string) (rows []Row, free int) {
+	layouts := map[int]string{}
+	for _, c := range t.Cabins {
+		for row := c.FirstRow; row <= c.LastRow; row++ {
+			layouts[row] = c.Letters
+		}
+	}
+
+	for _, s := range t.Seats() {
+		if len(rows) == 0 || rows[len(rows)-1].Number != s.Row {
+			rows = append(rows, Row{Number: s.Row, Class: s.Class, Layout: layouts[s.Row], Exit: s.Exit})
+		}
+		status := SeatFree
+		switch {
+		case s.Blocked:
+			status = SeatBlocked
+		case slices.Contains(occupied, s.Number):
+			status = SeatOccupied
+		default:
+			free++
+		}
+		row := &rows[len(rows)-1]
+		row.Seats = append(row.Seats, MapSeat{Seat: s, Status: status})
+	}
+	return rows, free
+}
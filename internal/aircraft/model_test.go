@@ -0,0 +1,60 @@
+package aircraft
+
+import (
+	"slices"
+	"testing"
+)
+
+func testType() Type {
+	return Type{Code: "A320", Cabins: Cabins{
+		{Class: ClassBusiness, FirstRow: 1, LastRow: 2, Letters: "AC-DF"},
+		{Class: ClassEconomy, FirstRow: 3, LastRow: 10, Letters: "ABC-DEF", ExitRows: []int{8}, Blocked: []string{"10C"}},
+	}}
+}
+
+func TestCapacity(t *testing.T) {
+	if got := testType().Capacity(); got != 2*4+8*6-1 {
+		t.Errorf("Capacity() = %d, want %d", got, 2*4+8*6-1)
+	}
+}
+
+func TestCabinSeats(t *testing.T) {
+	seats := testType().CabinSeats()
+	if len(seats) != 2 || seats[ClassBusiness] != 8 || seats[ClassEconomy] != 47 {
+		t.Errorf("CabinSeats() = %v, want business 8 and economy 47", seats)
+	}
+}
+
+func TestSeat(t *testing.T) {
+	s, ok := testType().Seat(" 8a ")
+	if !ok || s.Number != "8A" || !s.Window || !s.Exit || s.Class != ClassEconomy {
+		t.Errorf("Seat(8a) = %+v, %v", s, ok)
+	}
+	if s, _ := testType().Seat("3C"); !s.Aisle || s.Window {
+		t.Errorf("Seat(3C) = %+v, want an aisle seat", s)
+	}
+	if _, ok := testType().Seat("1B"); ok {
+		t.Error("Seat(1B) found a seat business rows do not have")
+	}
+}
+
+func TestStranded(t *testing.T) {
+	tests := []struct {
+		name     string
+		assigned []string
+		want     []string
+	}{
+		{name: "none assigned"},
+		{name: "all in the layout", assigned: []string{"1A", "10F"}},
+		{name: "row beyond the layout", assigned: []string{"1A", "11A"}, want: []string{"11A"}},
+		{name: "letter not in the row", assigned: []string{"2B"}, want: []string{"2B"}},
+		{name: "blocked seat", assigned: []string{"10C"}, want: []string{"10C"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testType().Stranded(tt.assigned); !slices.Equal(got, tt.want) {
+				t.Errorf("Stranded(%v) = %v, want %v", tt.assigned, got, tt.want)
+			}
+		})
+	}
+}
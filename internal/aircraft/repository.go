@@ -0,0 +1,105 @@
+package aircraft
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"airline-booking/pkg/cache"
+	"airline-booking/pkg/db"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// aircraftTag groups every cached aircraft type; saves evict it
+	aircraftTag = "aircraft"
+	aircraftTTL = time.Hour
+	notFoundTTL = time.Minute
+)
+
+type Repository struct {
+	DB       *sqlx.DB
+	Replicas *db.Cluster
+	Cache    *cache.Cache
+}
+
+func NewRepository(cluster *db.Cluster, c *cache.Cache) *Repository {
+	return &Repository{DB: cluster.Primary, Replicas: cluster, Cache: c}
+}
+
+// Type looks up an aircraft type by code, case-insensitively.
+func (r *Repository) Type(ctx context.Context, code string) (Type, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	opts := cache.Options{TTL: aircraftTTL, NegativeTTL: notFoundTTL, Tags: []string{aircraftTag}}
+	t, err := cache.GetOrLoad(ctx, r.Cache, "aircraft:"+code, opts, func(ctx context.Context) (Type, error) {
+		var t Type
+		err := r.Replicas.Reader(ctx).GetContext(ctx, &t, `SELECT code, name, cabins FROM aircraft_types WHERE code = $1`, code)
+		if errors.Is(err, sql.ErrNoRows) {
+			return t, cache.ErrNotFound
+		}
+		if err != nil {
+			return t, fmt.Errorf("failed to fetch aircraft type %s: %w", code, err)
+		}
+		return t, nil
+	})
+	if errors.Is(err, cache.ErrNotFound) {
+		return t, ErrTypeNotFound
+	}
+	return t, err
+}
+
+// ListTypes returns all aircraft types by code.
+func (r *Repository) ListTypes(ctx context.Context) ([]Type, error) {
+	types := []Type{}
+	if err := r.Replicas.Reader(ctx).SelectContext(ctx, &types, `SELECT code, name, cabins FROM aircraft_types ORDER BY code`); err != nil {
+		return nil, fmt.Errorf("failed to list aircraft types: %w", err)
+	}
+	return types, nil
+}
+
+// SaveType inserts or replaces an aircraft type. Flights already using it
+// keep the inventory they have, but a layout without the seats their
+// passengers are assigned is rejected with ErrSeatsStranded.
+func (r *Repository) SaveType(ctx context.Context, t Type) error {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Locking the flights holds off bookings taking seats on them
+	_, err = tx.ExecContext(ctx, `SELECT id FROM flights WHERE aircraft = $1 AND status <> 'cancelled' FOR SHARE`, t.Code)
+	if err != nil {
+		return fmt.Errorf("failed to lock flights of aircraft type %s: %w", t.Code, err)
+	}
+	var assigned []string
+	query := `
+		SELECT DISTINCT s.seat FROM seat_assignments s JOIN flights f ON f.id = s.flight_id
+		WHERE f.aircraft = $1 AND f.status <> 'cancelled'`
+	if err := tx.SelectContext(ctx, &assigned, query, t.Code); err != nil {
+		return fmt.Errorf("failed to fetch seats assigned on aircraft type %s: %w", t.Code, err)
+	}
+	if stranded := t.Stranded(assigned); len(stranded) > 0 {
+		return fmt.Errorf("%w: %s", ErrSeatsStranded, strings.Join(stranded, ", "))
+	}
+
+	query = `
+		INSERT INTO aircraft_types (code, name, cabins) VALUES ($1, $2, $3)
+		ON CONFLICT (code) DO UPDATE SET name = EXCLUDED.name, cabins = EXCLUDED.cabins`
+	if _, err := tx.ExecContext(ctx, query, t.Code, t.Name, t.Cabins); err != nil {
+		return fmt.Errorf("failed to save aircraft type %s: %w", t.Code, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+
+	if err := r.Cache.InvalidateTags(ctx, aircraftTag); err != nil {
+		log.Printf("Failed to invalidate aircraft cache: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package aircraft
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"airline-booking/pkg/cache"
+	"airline-booking/pkg/redis"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jmoiron/sqlx"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func TestSaveTypeStrandedSeats(t *testing.T) {
+	tests := []struct {
+		name     string
+		assigned []string
+		want     error
+	}{
+		{name: "no seats assigned"},
+		{name: "assigned seats kept", assigned: []string{"3A", "10F"}},
+		{name: "assigned seat removed", assigned: []string{"3A", "12A"}, want: ErrSeatsStranded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("sqlmock: %v", err)
+			}
+			defer mockDB.Close()
+			mr := miniredis.RunT(t)
+			client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+			defer client.Close()
+			r := &Repository{DB: sqlx.NewDb(mockDB, "sqlmock"), Cache: cache.New(&redis.RedisClient{Client: client})}
+
+			rows := sqlmock.NewRows([]string{"seat"})
+			for _, s := range tt.assigned {
+				rows.AddRow(s)
+			}
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`SELECT id FROM flights WHERE aircraft = $1`)).WithArgs("A320").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT s.seat FROM seat_assignments`)).WithArgs("A320").WillReturnRows(rows)
+			if tt.want == nil {
+				mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO aircraft_types`)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			if err := r.SaveType(context.Background(), testType()); !errors.Is(err, tt.want) {
+				t.Fatalf("SaveType() = %v, want %v", err, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
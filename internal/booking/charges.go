@@ -0,0 +1,94 @@
+package booking
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"airline-booking/internal/tax"
+	"airline-booking/pkg/money"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// passengers returns who travels on b; without passenger types every
+// traveller is an adult.
+func (b Booking) passengers() tax.Passengers {
+	if len(b.PassengerTypes) == 0 {
+		return tax.Passengers{tax.PassengerAdult: b.Seats}
+	}
+	return b.PassengerTypes
+}
+
+// breakdown returns the charges of b. Bookings made before charges were
+// itemized were charged their total price and seat charge alone.
+func (b Booking) breakdown() tax.Breakdown {
+	if b.Charges != nil {
+		return *b.Charges
+	}
+	bd, _ := tax.Rules{}.Apply(tax.Trip{Fare: b.TotalPrice, SeatCharge: b.SeatCharge, Passengers: b.passengers()}, nil)
+	return bd
+}
+
+// AmountDue is what the passenger pays for b, taxes and fees included.
+func (b Booking) AmountDue() money.Money {
+	return b.breakdown().Total
+}
+
+// charge works out the taxes and fees of b on its route under the rules
+// in force and stores the breakdown. Fixed amounts in other currencies
+// are converted at the current rate; a missing rate is a fault of the
+// rules or rates configured rather than of the booking.
+func (r *Repository) charge(ctx context.Context, tx *sqlx.Tx, b *Booking) error {
+	var route struct {
+		Source      string `db:"source"`
+		Destination string `db:"destination"`
+	}
+	if err := tx.GetContext(ctx, &route, `SELECT source, destination FROM flights WHERE id = $1`, b.FlightID); err != nil {
+		return fmt.Errorf("failed to fetch route of flight %d: %w", b.FlightID, err)
+	}
+	var rules tax.Rules
+	if r.Taxes != nil {
+		rs, err := r.Taxes.RuleSet(ctx)
+		if err != nil {
+			return err
+		}
+		rules = rs.Rules
+	}
+
+	trip := tax.Trip{
+		Origin:      route.Source,
+		Destination: route.Destination,
+		Fare:        b.TotalPrice,
+		SeatCharge:  b.SeatCharge,
+		Passengers:  b.passengers(),
+	}
+	bd, err := rules.Apply(trip, func(m money.Money, currency string) (money.Money, error) {
+		converted, _, err := r.Rates.Convert(m, currency)
+		if err != nil {
+			return converted, fmt.Errorf("failed to convert %s to %s: %v", m.Currency, currency, err)
+		}
+		return converted, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to charge booking %d: %w", b.ID, err)
+	}
+	b.Charges = &bd
+
+	if _, err := tx.ExecContext(ctx, `UPDATE bookings SET charges = $1 WHERE id = $2`, bd, b.ID); err != nil {
+		return fmt.Errorf("failed to update charges of booking %d: %w", b.ID, err)
+	}
+	return nil
+}
+
+// recharge charges an updated booking again if what it is charged for
+// changed, and otherwise keeps the charges it was made with even if the
+// taxes and fees in force have changed since.
+func (r *Repository) recharge(ctx context.Context, tx *sqlx.Tx, old Booking, b *Booking) error {
+	if old.Charges != nil && old.FlightID == b.FlightID && old.TotalPrice == b.TotalPrice && old.SeatCharge == b.SeatCharge &&
+		maps.Equal(old.passengers(), b.passengers()) {
+		b.Charges = old.Charges
+		return nil
+	}
+	return r.charge(ctx, tx, b)
+}
@@ -0,0 +1,54 @@
+package booking
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"airline-booking/internal/tax"
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/cache"
+	"airline-booking/pkg/db"
+	"airline-booking/pkg/money"
+	"airline-booking/pkg/redis"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func TestChargeWithoutRate(t *testing.T) {
+	r, mock, mr := newSeatRepo(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	r.Taxes = &tax.Repository{DB: r.DB, Replicas: &db.Cluster{Primary: r.DB}, Cache: cache.New(&redis.RedisClient{Client: client})}
+
+	rules, _ := json.Marshal(tax.Rules{Charges: []tax.Charge{
+		{Code: "YQ", Name: "Fuel surcharge", Kind: tax.KindFuelSurcharge, Amount: money.New(1500, "USD")},
+	}})
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT source, destination FROM flights WHERE id = $1`)).WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"source", "destination"}).AddRow("DEL", "BOM"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT version, rules, created_at FROM tax_rules`)).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "rules", "created_at"}).AddRow(1, rules, time.Now()))
+	mock.ExpectRollback()
+
+	tx, err := r.DB.Beginx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	b := Booking{ID: 1, FlightID: 5, Seats: 1, TotalPrice: money.New(450000, "INR")}
+	err = r.charge(context.Background(), tx, &b)
+	if err == nil {
+		t.Fatal("charge() = nil, want an error")
+	}
+	// The booking is not at fault, so the error must not reach the client
+	// as exchange_rate_not_found
+	var ae *apperr.Error
+	if errors.As(err, &ae) {
+		t.Errorf("charge() = %v (%s), want an internal error", err, ae.Code)
+	}
+}
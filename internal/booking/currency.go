@@ -0,0 +1,83 @@
+package booking
+
+import (
+	"context"
+
+	"airline-booking/internal/pricing"
+	"airline-booking/pkg/money"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Display shows the amounts of a booking in another currency, at Rate.
+type Display struct {
+	TotalPrice money.Money `json:"total_price"`
+	SeatCharge money.Money `json:"seat_charge"`
+	// Taxes and Total are of the charges, taxes and fees included
+	Taxes money.Money `json:"taxes"`
+	Total money.Money `json:"total"`
+	Rate  money.Rate  `json:"rate"`
+}
+
+// lockRate locks the rate from the currency of b to currency into b, or
+// clears it when currency is that of b or empty. The rate is that of the
+// quote b was made from when it was shown in currency, and the current
+// one otherwise.
+func (r *Repository) lockRate(ctx context.Context, q sqlx.QueryerContext, b *Booking, currency string) error {
+	if currency == "" || currency == b.TotalPrice.Currency {
+		b.ExchangeRate = nil
+		return nil
+	}
+	if b.QuoteID != nil {
+		quoted, err := pricing.QuotedRate(ctx, q, *b.QuoteID, b.FlightID)
+		if err != nil {
+			return err
+		}
+		if quoted != nil && quoted.From == b.TotalPrice.Currency && quoted.To == currency {
+			b.ExchangeRate = quoted
+			return nil
+		}
+	}
+	rate, err := r.Rates.Rate(b.TotalPrice.Currency, currency)
+	if err != nil {
+		return err
+	}
+	b.ExchangeRate = &rate
+	return nil
+}
+
+// Show fills in b.Display in currency, at the rate locked into b when it
+// is the currency locked and at the current rate otherwise. Without a
+// currency, a booking with a locked rate is shown in the currency locked.
+func (r *Repository) Show(b *Booking, currency string) error {
+	b.Display = nil
+	rate := b.ExchangeRate
+	if (currency == "" && rate == nil) || currency == b.TotalPrice.Currency {
+		return nil
+	}
+	if rate == nil || (currency != "" && currency != rate.To) {
+		current, err := r.Rates.Rate(b.TotalPrice.Currency, currency)
+		if err != nil {
+			return err
+		}
+		rate = &current
+	}
+
+	d := Display{Rate: *rate}
+	var err error
+	if d.TotalPrice, err = rate.Convert(b.TotalPrice); err != nil {
+		return err
+	}
+	if d.SeatCharge, err = rate.Convert(b.SeatCharge); err != nil {
+		return err
+	}
+	charges := b.breakdown()
+	if d.Taxes, err = rate.Convert(charges.Taxes); err != nil {
+		return err
+	}
+	if d.Total, err = rate.Convert(charges.Total); err != nil {
+		return err
+	}
+	b.Display = &d
+	return nil
+}
@@ -0,0 +1,74 @@
+package booking
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+
+	"airline-booking/internal/pricing"
+	"airline-booking/pkg/money"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestLockRate(t *testing.T) {
+	quoted := money.Rate{From: "INR", To: "USD", Rate: 0.0125}
+	quotedJSON, _ := json.Marshal(quoted)
+	current := 0.0119
+	quoteID := int64(7)
+
+	tests := []struct {
+		name     string
+		quoteID  *int64
+		currency string
+		// row is the exchange rate of the quote; nil without a quote row
+		row  any
+		want *money.Rate
+		err  error
+	}{
+		{name: "own currency", currency: "INR"},
+		{name: "current rate without a quote", currency: "USD", want: &money.Rate{From: "INR", To: "USD", Rate: current}},
+		{name: "rate of the quote", quoteID: &quoteID, currency: "USD", row: quotedJSON, want: &quoted},
+		{name: "quote in the booking currency", quoteID: &quoteID, currency: "USD", row: sql.NullString{},
+			want: &money.Rate{From: "INR", To: "USD", Rate: current}},
+		{name: "quote shown in another currency", quoteID: &quoteID, currency: "EUR", row: quotedJSON,
+			want: &money.Rate{From: "INR", To: "EUR", Rate: 0.0109}},
+		{name: "expired or unknown quote", quoteID: &quoteID, currency: "USD", err: pricing.ErrQuoteNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mock, _ := newSeatRepo(t)
+			r.Rates = money.NewRates()
+			r.Rates.Set(money.RateTable{Base: "INR", Rates: map[string]float64{"USD": current, "EUR": 0.0109}})
+
+			mock.ExpectBegin()
+			if tt.quoteID != nil {
+				q := mock.ExpectQuery(regexp.QuoteMeta(`SELECT exchange_rate FROM price_quotes WHERE id = $1 AND flight_id = $2`)).
+					WithArgs(*tt.quoteID, 5, sqlmock.AnyArg())
+				if tt.row == nil {
+					q.WillReturnError(sql.ErrNoRows)
+				} else {
+					q.WillReturnRows(sqlmock.NewRows([]string{"exchange_rate"}).AddRow(tt.row))
+				}
+			}
+			mock.ExpectRollback()
+
+			tx, err := r.DB.Beginx()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tx.Rollback()
+			b := Booking{FlightID: 5, QuoteID: tt.quoteID, TotalPrice: money.New(900000, "INR")}
+			err = r.lockRate(context.Background(), tx, &b, tt.currency)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("lockRate() = %v, want %v", err, tt.err)
+			}
+			if (b.ExchangeRate == nil) != (tt.want == nil) || (tt.want != nil && *b.ExchangeRate != *tt.want) {
+				t.Errorf("ExchangeRate = %v, want %v", b.ExchangeRate, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package booking
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"airline-booking/pkg/kafka"
+)
+
+// flightEvent is the part of a flight-service update or cancellation event
+// booking-service needs.
+type flightEvent struct {
+	Flight struct {
+		ID          int       `json:"id"`
+		Source      string    `json:"source"`
+		Destination string    `json:"destination"`
+		Departure   time.Time `json:"departure"`
+	} `json:"flight"`
+	Changes         map[string]json.RawMessage `json:"changes"`
+	Reason          string                     `json:"reason"`
+	Reaccommodation string                     `json:"reaccommodation"`
+}
+
+// FlightEvents reacts to flight-service events.
+type FlightEvents struct {
+	Repo     *Repository
+	Notifier Notifier
+	// Producer and Topic receive the booking events and reports that result
+	Producer *kafka.Producer
+	Topic    string
+}
+
+// Handle processes one event; events of other types are ignored. It is a
+// kafka.EventHandler.
+func (e *FlightEvents) Handle(ctx context.Context, eventType string, value []byte) error {
+	switch eventType {
+	case "flight_rescheduled":
+		var ev flightEvent
+		if err := json.Unmarshal(value, &ev); err != nil {
+			return fmt.Errorf("invalid %s event: %w", eventType, err)
+		}
+		return e.rescheduled(ctx, ev)
+	case "flight_cancelled":
+		var ev flightEvent
+		if err := json.Unmarshal(value, &ev); err != nil {
+			return fmt.Errorf("invalid %s event: %w", eventType, err)
+		}
+		return e.cancelled(ctx, ev)
+	}
+	return nil
+}
+
+// rescheduled flags the flight's bookings for re-accommodation and tells
+// their passengers what changed.
+func (e *FlightEvents) rescheduled(ctx context.Context, ev flightEvent) error {
+	bookings, err := e.Repo.MarkForReaccommodation(ctx, ev.Flight.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range bookings {
+		n := Notification{
+			Kind:      NotifyScheduleChange,
+			BookingID: b.ID,
+			FlightID:  b.FlightID,
+			Passenger: b.Passenger,
+			Details:   ev.Changes,
+		}
+		if err := e.Notifier.Notify(ctx, n); err != nil {
+			log.Printf("Failed to notify %s of schedule change on booking %d: %v", b.Passenger, b.ID, err)
+		}
+	}
+	log.Printf("Flight %d rescheduled: %d bookings need re-accommodation", ev.Flight.ID, len(bookings))
+	return nil
+}
+
+// publish sends an event about the outcome of a flight event to Kafka
+func (e *FlightEvents) publish(eventType string, v any) {
+	data, _ := json.Marshal(v)
+	if err := e.Producer.SendMessage(e.Topic, eventType, string(data)); err != nil {
+		log.Printf("Kafka publish error: %v", err)
+	}
+}
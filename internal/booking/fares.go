@@ -0,0 +1,75 @@
+package booking
+
+import (
+	"context"
+	"errors"
+
+	"airline-booking/internal/fare"
+	"airline-booking/internal/pricing"
+	"airline-booking/pkg/money"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// takeFare sells the seats of b in its booking class, if it has one, and
+// prices the booking at the live fare of the class or, without one, of the
+// flight; the total price sent by the client is ignored. The quote is
+// returned for recording once the booking has its ID.
+func (r *Repository) takeFare(ctx context.Context, tx *sqlx.Tx, b *Booking) (*pricing.Quote, error) {
+	var base money.Money
+	if b.FareClass == "" {
+		var err error
+		base, err = pricing.BaseFare(ctx, tx, b.FlightID, "")
+		if errors.Is(err, pricing.ErrFlightNotFound) {
+			return nil, ErrFlightNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		bucket, err := fare.Take(ctx, tx, b.FlightID, b.FareClass, b.Seats)
+		if err != nil {
+			return nil, err
+		}
+		base = bucket.Price
+	}
+	if r.Pricing == nil {
+		b.TotalPrice = base.Times(b.Seats)
+		return nil, nil
+	}
+
+	quote, err := r.Pricing.Quote(ctx, tx, b.FlightID, b.FareClass, b.Seats, base)
+	if err != nil {
+		return nil, err
+	}
+	b.TotalPrice = quote.Total
+	return &quote, nil
+}
+
+// rebookFare moves the seats of an updated booking between booking classes
+// and reprices it if it takes seats anew. b comes in at the price of old;
+// a booking that keeps its flight, class and seats, or gives them up,
+// keeps that price even if the class has closed or its fare moved since.
+func (r *Repository) rebookFare(ctx context.Context, tx *sqlx.Tx, old Booking, b *Booking) (*pricing.Quote, error) {
+	if old.FlightID == b.FlightID && old.FareClass == b.FareClass && old.Seats == b.Seats && holdsSeats(old.Status) == holdsSeats(b.Status) {
+		return nil, nil
+	}
+	if holdsSeats(old.Status) && old.FareClass != "" {
+		if err := fare.Release(ctx, tx, old.FlightID, old.FareClass, old.Seats); err != nil {
+			return nil, err
+		}
+	}
+	if holdsSeats(b.Status) && !b.keepFare {
+		return r.takeFare(ctx, tx, b)
+	}
+	return nil, nil
+}
+
+// recordQuote stores the quote a booking was priced at.
+func recordQuote(ctx context.Context, tx *sqlx.Tx, quote *pricing.Quote, bookingID int) error {
+	if quote == nil {
+		return nil
+	}
+	quote.BookingID = &bookingID
+	return pricing.Record(ctx, tx, quote)
+}
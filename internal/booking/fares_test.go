@@ -0,0 +1,102 @@
+package booking
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+
+	"airline-booking/internal/fare"
+	"airline-booking/pkg/money"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestTakeFare(t *testing.T) {
+	tests := []struct {
+		name   string
+		class  string
+		expect func(mock sqlmock.Sqlmock)
+		want   money.Money
+		err    error
+	}{
+		{
+			name: "flight fare without a class",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT price AS amount, currency FROM flights WHERE id = $1`)).WithArgs(5).
+					WillReturnRows(sqlmock.NewRows([]string{"amount", "currency"}).AddRow(450000, "INR"))
+			},
+			want: money.New(900000, "INR"),
+		},
+		{
+			name: "unknown flight",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM flights WHERE id = $1`)).WithArgs(5).WillReturnError(sql.ErrNoRows)
+			},
+			err: ErrFlightNotFound,
+		},
+		{
+			name:  "class fare",
+			class: "M",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(`FROM fare_buckets WHERE flight_id = $1 FOR UPDATE`)).WithArgs(5).WillReturnRows(
+					sqlmock.NewRows([]string{"flight_id", "code", "family", "price.amount", "price.currency", "allocation", "sold"}).
+						AddRow(5, "M", fare.FamilyEconomy, 300000, "INR", 60, 0))
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT available_seats FROM flights`)).WithArgs(5).
+					WillReturnRows(sqlmock.NewRows([]string{"available_seats"}).AddRow(100))
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE fare_buckets SET sold = sold + $1`)).WithArgs(2, 5, "M").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			want: money.New(600000, "INR"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mock, _ := newSeatRepo(t)
+			mock.ExpectBegin()
+			tt.expect(mock)
+			mock.ExpectRollback()
+
+			tx, err := r.DB.Beginx()
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := Booking{FlightID: 5, Seats: 2, FareClass: tt.class, TotalPrice: money.New(1, "INR")}
+			quote, err := r.takeFare(context.Background(), tx, &b)
+			tx.Rollback()
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("takeFare() = %v, want %v", err, tt.err)
+			}
+			if err == nil && (b.TotalPrice != tt.want || quote != nil) {
+				t.Errorf("TotalPrice = %v, quote %v, want %v and no quote", b.TotalPrice, quote, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRebookFareKeepsFare(t *testing.T) {
+	r, mock, _ := newSeatRepo(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE fare_buckets SET sold = GREATEST(sold - $1, 0)`)).WithArgs(2, 5, "M").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectRollback()
+
+	tx, err := r.DB.Beginx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	old := Booking{FlightID: 5, Seats: 2, FareClass: "M", Status: StatusConfirmed, TotalPrice: money.New(600000, "INR")}
+	moved := old
+	moved.FlightID, moved.FareClass, moved.keepFare = 6, "", true
+	if quote, err := r.rebookFare(context.Background(), tx, old, &moved); err != nil || quote != nil {
+		t.Fatalf("rebookFare() = %v, %v", quote, err)
+	}
+	if moved.TotalPrice != old.TotalPrice {
+		t.Errorf("TotalPrice = %v, want the fare paid %v", moved.TotalPrice, old.TotalPrice)
+	}
+}
@@ -2,10 +2,18 @@ package booking
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/db"
 	"airline-booking/pkg/kafka"
+	"airline-booking/pkg/redis"
+	"airline-booking/pkg/request"
+	"airline-booking/pkg/response"
 )
 
 type Handler struct {
@@ -18,39 +26,298 @@ func NewHandler(repo *Repository, producer *kafka.Producer, topic string) *Handl
 	return &Handler{Repo: repo, Producer: producer, Topic: topic}
 }
 
+// Routes registers the booking endpoints on mux. PUT /bookings with the ID
+// in the body and DELETE /bookings?id= are kept for existing clients.
+// Every endpoint takes ?currency=USD to show amounts in another currency;
+// on POST /bookings it also locks the exchange rate into the booking.
+func (h *Handler) Routes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /bookings", h.GetBookings)
+	mux.HandleFunc("POST /bookings", h.AddBooking)
+	mux.HandleFunc("PUT /bookings", h.UpdateBooking)
+	mux.HandleFunc("DELETE /bookings", h.CancelBooking)
+	mux.HandleFunc("GET /bookings/{id}", h.GetBooking)
+	mux.HandleFunc("PUT /bookings/{id}", h.UpdateBooking)
+	mux.HandleFunc("PATCH /bookings/{id}", h.PatchBooking)
+	mux.HandleFunc("DELETE /bookings/{id}", h.CancelBooking)
+	mux.HandleFunc("PUT /bookings/{id}/seats", h.ChangeSeats)
+	mux.HandleFunc("GET /bookings/{id}/invoice", h.GetInvoice)
+}
+
 // AddBooking handles booking creation
 func (h *Handler) AddBooking(w http.ResponseWriter, r *http.Request) {
+	currency, err := request.Currency(r)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
 	var b Booking
-	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
-		http.Error(w, "Invalid payload", http.StatusBadRequest)
+	if err := request.Decode(w, r, &b); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	if b.Status != "" && !holdsSeats(b.Status) {
+		apperr.Write(w, r, apperr.Validation("validation_failed", "request has invalid fields",
+			apperr.FieldError{Field: "status", Message: "must be confirmed or held for a new booking"}))
 		return
 	}
 
-	if err := h.Repo.AddBooking(b); err != nil {
-		http.Error(w, "Failed to save booking", http.StatusInternalServerError)
-		log.Println("DB error:", err)
+	if err := h.Repo.AddBooking(&b, currency); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	// Publish Kafka event
-	event, _ := json.Marshal(b)
-	err := h.Producer.SendMessage(h.Topic, "booking_created", string(event))
+	h.publish("booking_created", b)
+	if err := h.Repo.Show(&b, currency); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/bookings/%d", b.ID))
+	response.JSON(w, http.StatusCreated, b)
+}
+
+// GetBooking returns the booking given by the id path parameter
+func (h *Handler) GetBooking(w http.ResponseWriter, r *http.Request) {
+	id, ok := bookingID(w, r)
+	if !ok {
+		return
+	}
+	currency, err := request.Currency(r)
 	if err != nil {
-		log.Printf("Kafka publish error: %v", err)
+		apperr.Write(w, r, err)
+		return
+	}
+
+	b, err := h.Repo.GetBooking(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.Repo.Show(&b, currency); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, b)
+}
+
+// GetInvoice returns the invoice of the booking given by the id path
+// parameter, itemizing its fare, taxes, fees and surcharges
+func (h *Handler) GetInvoice(w http.ResponseWriter, r *http.Request) {
+	id, ok := bookingID(w, r)
+	if !ok {
+		return
+	}
+	currency, err := request.Currency(r)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	b, err := h.Repo.GetBooking(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	inv, err := h.Repo.Invoice(b, currency)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, inv)
+}
+
+// UpdateBooking handles modification of an existing booking
+func (h *Handler) UpdateBooking(w http.ResponseWriter, r *http.Request) {
+	var b Booking
+	if err := request.Decode(w, r, &b); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	if r.PathValue("id") != "" {
+		id, ok := bookingID(w, r)
+		if !ok {
+			return
+		}
+		b.ID = id
+	}
+	if b.ID == 0 {
+		apperr.Write(w, r, apperr.Validation("invalid_request", "booking id is required",
+			apperr.FieldError{Field: "id", Message: "is required"}))
+		return
+	}
+
+	h.saveBooking(w, r, b)
+}
+
+// PatchBooking changes only the fields present in the request body
+func (h *Handler) PatchBooking(w http.ResponseWriter, r *http.Request) {
+	id, ok := bookingID(w, r)
+	if !ok {
+		return
+	}
+
+	b, err := h.Repo.GetBooking(db.WithPrimary(r.Context()), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	// Fields missing from the body keep their current values
+	if err := request.Decode(w, r, &b); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	b.ID = id
+
+	h.saveBooking(w, r, b)
+}
+
+// ChangeSeats moves the party to the seats listed in the body, one per
+// traveller, charging for the seats chosen
+func (h *Handler) ChangeSeats(w http.ResponseWriter, r *http.Request) {
+	id, ok := bookingID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		SeatNumbers []string `json:"seat_numbers" validate:"required,max=9"`
+	}
+	if err := request.Decode(w, r, &req); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	b, err := h.Repo.GetBooking(db.WithPrimary(r.Context()), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !holdsSeats(b.Status) {
+		writeError(w, r, apperr.Conflict("booking_inactive", "seats can only be changed on a confirmed or held booking"))
+		return
+	}
+	b.SeatNumbers = req.SeatNumbers
+	if problems := b.Validate(); len(problems) > 0 {
+		apperr.Write(w, r, apperr.Validation("validation_failed", "request has invalid fields", problems...))
+		return
+	}
+
+	h.saveBooking(w, r, b)
+}
+
+func (h *Handler) saveBooking(w http.ResponseWriter, r *http.Request, b Booking) {
+	currency, err := request.Currency(r)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	if err := h.Repo.UpdateBooking(&b); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.publish("booking_updated", b)
+	if err := h.Repo.Show(&b, currency); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, b)
+}
+
+// CancelBooking handles cancellation of the booking given by the id path
+// or query parameter
+func (h *Handler) CancelBooking(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("id") == "" {
+		// Legacy form: DELETE /bookings?id=
+		r.SetPathValue("id", r.URL.Query().Get("id"))
+	}
+	id, ok := bookingID(w, r)
+	if !ok {
+		return
+	}
+	currency, err := request.Currency(r)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Booking created successfully"})
+	b, err := h.Repo.CancelBooking(id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.publish("booking_cancelled", b)
+	if err := h.Repo.Show(&b, currency); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, b)
 }
 
-// GetBookings returns all bookings
+// GetBookings returns all bookings, optionally filtered by passenger or flight_id
 func (h *Handler) GetBookings(w http.ResponseWriter, r *http.Request) {
-	bookings, err := h.Repo.GetAllBookings()
+	var (
+		bookings []Booking
+		err      error
+	)
+
+	currency, err := request.Currency(r)
 	if err != nil {
-		http.Error(w, "Failed to fetch bookings", http.StatusInternalServerError)
-		log.Println("Error:", err)
+		apperr.Write(w, r, err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(bookings)
+	q := r.URL.Query()
+	switch {
+	case q.Get("passenger") != "":
+		bookings, err = h.Repo.GetBookingsByPassenger(r.Context(), q.Get("passenger"))
+	case q.Get("flight_id") != "":
+		flightID, convErr := strconv.Atoi(q.Get("flight_id"))
+		if convErr != nil {
+			apperr.Write(w, r, apperr.Validation("invalid_request", "invalid flight_id",
+				apperr.FieldError{Field: "flight_id", Message: "must be a number"}))
+			return
+		}
+		bookings, err = h.Repo.GetBookingsByFlight(r.Context(), flightID)
+	default:
+		bookings, err = h.Repo.GetAllBookings(r.Context())
+	}
+	if err != nil {
+		writeError(w, r, fmt.Errorf("error fetching bookings: %w", err))
+		return
+	}
+	for i := range bookings {
+		if err := h.Repo.Show(&bookings[i], currency); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	response.JSON(w, http.StatusOK, bookings)
+}
+
+// bookingID parses the id path parameter, answering 400 if it is not a number
+func bookingID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("invalid booking id %q", r.PathValue("id")))
+		return 0, false
+	}
+	return id, true
+}
+
+// writeError answers err as problem details, reporting lost or contended
+// seat locks as ErrFlightBusy.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, redis.ErrLockNotAcquired) || errors.Is(err, redis.ErrLockLost) {
+		err = ErrFlightBusy
+	}
+	apperr.Write(w, r, err)
+}
+
+// publish sends a booking event to Kafka
+func (h *Handler) publish(eventType string, b Booking) {
+	event, _ := json.Marshal(b)
+	if err := h.Producer.SendMessage(h.Topic, eventType, string(event)); err != nil {
+		log.Printf("Kafka publish error: %v", err)
+	}
 }
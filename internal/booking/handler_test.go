@@ -0,0 +1,96 @@
+package booking
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"airline-booking/pkg/cache"
+	"airline-booking/pkg/db"
+	"airline-booking/pkg/kafka"
+	"airline-booking/pkg/money"
+	"airline-booking/pkg/redis"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/IBM/sarama"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// fakeProducer accepts every message.
+type fakeProducer struct {
+	sarama.SyncProducer
+	sent int
+}
+
+func (p *fakeProducer) SendMessage(*sarama.ProducerMessage) (int32, int64, error) {
+	p.sent++
+	return 0, 0, nil
+}
+
+func TestUpdateBookingKeepsPrice(t *testing.T) {
+	tests := []struct {
+		name, method, body string
+	}{
+		{"put with a price", http.MethodPut, `{"flight_id": 5, "passenger": "Asha", "seats": 2, "status": "confirmed", "total_price": {"amount": 1, "currency": "INR"}}`},
+		{"put without a price", http.MethodPut, `{"flight_id": 5, "passenger": "Asha", "seats": 2, "status": "confirmed"}`},
+		{"patch with a price", http.MethodPatch, `{"total_price": {"amount": 100, "currency": "USD"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mock, mr := newSeatRepo(t)
+			client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+			t.Cleanup(func() { client.Close() })
+			r.Cache = cache.New(&redis.RedisClient{Client: client})
+			r.Replicas = &db.Cluster{Primary: r.DB}
+			producer := &fakeProducer{}
+			h := NewHandler(r, &kafka.Producer{Client: producer}, "bookings")
+
+			row := func() *sqlmock.Rows {
+				return sqlmock.NewRows([]string{"id", "flight_id", "passenger", "seats", "passenger_types", "total_price.amount",
+					"total_price.currency", "fare_class", "status", "held_until", "needs_reaccommodation", "seat_charge.amount",
+					"seat_charge.currency", "exchange_rate", "charges", "booked_at", "seat_numbers"}).
+					AddRow(1, 5, "Asha", 2, nil, 900000, "INR", "", StatusConfirmed, nil, false, 0, "INR", nil,
+						[]byte(`{"items":[],"total":{"amount":900000,"currency":"INR"}}`), time.Now(), "")
+			}
+			byID := regexp.QuoteMeta(`FROM bookings WHERE id = $1`)
+			if tt.method == http.MethodPatch {
+				mock.ExpectQuery(byID).WithArgs(1).WillReturnRows(row())
+			}
+			mock.ExpectQuery(byID).WithArgs(1).WillReturnRows(row())
+			mock.ExpectBegin()
+			mock.ExpectQuery(byID).WithArgs(1).WillReturnRows(row())
+			mock.ExpectExec(updateSeats).WithArgs(-2, sqlmock.AnyArg(), 5).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(updateSeats).WithArgs(2, sqlmock.AnyArg(), 5).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE bookings SET flight_id = $1`)).
+				WithArgs(5, "Asha", 2, sqlmock.AnyArg(), 900000, "INR", "", StatusConfirmed, sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			mux := http.NewServeMux()
+			h.Routes(mux)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(tt.method, "/bookings/1", strings.NewReader(tt.body)))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+			}
+			var got Booking
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if want := money.New(900000, "INR"); got.TotalPrice != want {
+				t.Errorf("total_price = %v, want %v", got.TotalPrice, want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+			if producer.sent != 1 {
+				t.Errorf("published %d events, want 1", producer.sent)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package booking
+
+import (
+	"fmt"
+	"time"
+
+	"airline-booking/internal/tax"
+	"airline-booking/pkg/money"
+)
+
+// Invoice itemizes what the passenger of a booking is charged.
+type Invoice struct {
+	Number    string `json:"number"`
+	BookingID int    `json:"booking_id"`
+	FlightID  int    `json:"flight_id"`
+	Passenger string `json:"passenger"`
+	Status    string `json:"status"`
+	// IssuedAt is when the booking was made, if known
+	IssuedAt *time.Time `json:"issued_at,omitempty"`
+	Lines    []tax.Item `json:"lines"`
+	// Subtotal is the fare and seat charges, before taxes and fees
+	Subtotal money.Money `json:"subtotal"`
+	Taxes    money.Money `json:"taxes"`
+	Total    money.Money `json:"total"`
+	// Display is set on invoices shown in another currency
+	Display *Display `json:"display,omitempty"`
+}
+
+// Invoice returns the invoice of b, with its amounts also in currency as
+// Show gives them.
+func (r *Repository) Invoice(b Booking, currency string) (Invoice, error) {
+	charges := b.breakdown()
+	inv := Invoice{
+		Number:    fmt.Sprintf("INV-%08d", b.ID),
+		BookingID: b.ID,
+		FlightID:  b.FlightID,
+		Passenger: b.Passenger,
+		Status:    b.Status,
+		IssuedAt:  b.BookedAt,
+		Lines:     charges.Items,
+		Subtotal:  charges.Fare,
+		Taxes:     charges.Taxes,
+		Total:     charges.Total,
+	}
+	if err := r.Show(&b, currency); err != nil {
+		return inv, err
+	}
+	inv.Display = b.Display
+	return inv, nil
+}
@@ -1,11 +1,114 @@
 package booking
 
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	"airline-booking/internal/tax"
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/money"
+	"airline-booking/pkg/validate"
+)
+
+// Booking statuses
+const (
+	StatusConfirmed = "confirmed"
+	StatusCancelled = "cancelled"
+	StatusHeld      = "held"
+	StatusExpired   = "expired"
+	StatusRefunded  = "refunded"
+)
+
 // Booking represents a flight booking record
 type Booking struct {
-	ID         int     `db:"id" json:"id"`
-	FlightID   int     `db:"flight_id" json:"flight_id"`
-	Passenger  string  `db:"passenger" json:"passenger"`
-	Seats      int     `db:"seats" json:"seats"`
-	TotalPrice float64 `db:"total_price" json:"total_price"`
-	Status     string  `db:"status" json:"status"`
+	ID        int    `db:"id" json:"id"`
+	FlightID  int    `db:"flight_id" json:"flight_id" validate:"required,min=1"`
+	Passenger string `db:"passenger" json:"passenger" validate:"required,max=100"`
+	Seats     int    `db:"seats" json:"seats" validate:"min=1,max=9"`
+	// PassengerTypes counts the travellers by type, e.g. {"adult": 2,
+	// "child": 1}; without it every traveller is an adult
+	PassengerTypes tax.Passengers `db:"passenger_types" json:"passenger_types,omitempty"`
+	// TotalPrice is the fare of every seat, worked out when the booking
+	// takes seats; clients cannot set it
+	TotalPrice money.Money `db:"total_price" json:"total_price" validate:"min=0"`
+	// FareClass is the booking class sold, e.g. Y. With one, TotalPrice is
+	// priced from its fare rather than the flight's and seats are assigned
+	// in its cabin
+	FareClass string `db:"fare_class" json:"fare_class,omitempty" validate:"max=2,code"`
+	Status    string `db:"status" json:"status" validate:"oneof=confirmed held cancelled expired refunded"`
+	// BookedAt is when the booking was made; it is unknown for bookings
+	// made before it was recorded
+	BookedAt *time.Time `db:"booked_at" json:"booked_at,omitempty"`
+	// HeldUntil is set for held bookings whose seats are released when it passes
+	HeldUntil *time.Time `db:"held_until" json:"held_until,omitempty"`
+	// NeedsReaccommodation is set when the flight's schedule changed after booking
+	NeedsReaccommodation bool `db:"needs_reaccommodation" json:"needs_reaccommodation"`
+	// SeatNumbers are the seats of the party in traveller order, e.g.
+	// ["12A", "12B"]. Requested seats are charged SeatCharge on top of
+	// TotalPrice; on flights with a seat map the others are assigned free.
+	SeatNumbers SeatNumbers `db:"seat_numbers" json:"seat_numbers,omitempty" validate:"max=9"`
+	SeatCharge  money.Money `db:"seat_charge" json:"seat_charge"`
+	// Charges itemizes the fare, seat charges, taxes, fees and surcharges,
+	// worked out when the booking is made or repriced
+	Charges *tax.Breakdown `db:"charges" json:"charges,omitempty"`
+	// ExchangeRate is locked when the booking is made with a currency to
+	// show it in; it is kept while the booking stays in its own currency
+	ExchangeRate *money.Rate `db:"exchange_rate" json:"exchange_rate,omitempty"`
+	// QuoteID is the quote the booking is made from, whose exchange rate is
+	// locked if it was shown in the currency asked for
+	QuoteID *int64 `db:"-" json:"quote_id,omitempty"`
+	// Display is set in responses shown in another currency
+	Display *Display `db:"-" json:"display,omitempty"`
+
+	// keepFare moves the booking to another flight at the fare paid, as
+	// re-accommodation does
+	keepFare bool
+}
+
+func init() {
+	validate.Register(Booking{})
+}
+
+// Validate checks the rules that involve more than one field.
+func (b Booking) Validate() []apperr.FieldError {
+	var problems []apperr.FieldError
+	if len(b.SeatNumbers) > 0 && len(b.SeatNumbers) != b.Seats {
+		problems = append(problems, apperr.FieldError{Field: "seat_numbers", Message: fmt.Sprintf("must list one seat for each of the %d seats", b.Seats)})
+	}
+	if len(b.PassengerTypes) > 0 {
+		problems = append(problems, b.PassengerTypes.Validate("passenger_types")...)
+		if n := b.PassengerTypes.Total(); n != b.Seats {
+			problems = append(problems, apperr.FieldError{Field: "passenger_types", Message: fmt.Sprintf("counts %d travellers for %d seats", n, b.Seats)})
+		}
+	}
+	return problems
+}
+
+// SeatNumbers is read from the database as a comma-separated list.
+type SeatNumbers []string
+
+// Scan implements sql.Scanner.
+func (s *SeatNumbers) Scan(src any) error {
+	var list string
+	switch v := src.(type) {
+	case string:
+		list = v
+	case []byte:
+		list = string(v)
+	case nil:
+	default:
+		return fmt.Errorf("cannot scan %T into SeatNumbers", src)
+	}
+	*s = nil
+	if list != "" {
+		*s = strings.Split(list, ",")
+	}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (s SeatNumbers) Value() (driver.Value, error) {
+	return strings.Join(s, ","), nil
 }
@@ -0,0 +1,43 @@
+package booking
+
+import (
+	"context"
+	"encoding/json"
+
+	"airline-booking/pkg/kafka"
+)
+
+// Notification kinds
+const (
+	NotifyScheduleChange = "schedule_change"
+)
+
+// Notification is a message to the passenger of a booking.
+type Notification struct {
+	Kind      string `json:"kind"`
+	BookingID int    `json:"booking_id"`
+	FlightID  int    `json:"flight_id"`
+	Passenger string `json:"passenger"`
+	// Details carries kind-specific data, e.g. the changed flight fields
+	Details any `json:"details,omitempty"`
+}
+
+// Notifier delivers notifications to passengers.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// EventNotifier publishes notifications as passenger_notification events
+// for a notification service to deliver by email or SMS.
+type EventNotifier struct {
+	Producer *kafka.Producer
+	Topic    string
+}
+
+func (e EventNotifier) Notify(_ context.Context, n Notification) error {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	return e.Producer.SendMessage(e.Topic, "passenger_notification", string(data))
+}
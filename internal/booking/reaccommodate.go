@@ -0,0 +1,199 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// maxAlternatives bounds how many later flights are tried per cancellation.
+const maxAlternatives = 10
+
+// Re-accommodation policies, as set by flight-service on the cancellation
+const (
+	reaccommodateAuto  = "auto"
+	reaccommodateOffer = "offer"
+)
+
+// Outcomes of re-accommodating one booking
+const (
+	OutcomeMoved    = "moved"
+	OutcomeOffered  = "offered"
+	OutcomeRefunded = "refunded"
+	OutcomeReleased = "released"
+	OutcomeFailed   = "failed"
+)
+
+// Notification kinds sent on cancellation
+const (
+	NotifyReaccommodated = "reaccommodated"
+	NotifyAlternative    = "alternative_offered"
+	NotifyRefunded       = "refunded"
+)
+
+// Outcome is what happened to one booking of a cancelled flight.
+type Outcome struct {
+	BookingID   int    `json:"booking_id"`
+	Passenger   string `json:"passenger"`
+	Result      string `json:"result"`
+	NewFlightID int    `json:"new_flight_id,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// CancellationReport lists the outcome for every booking of a cancelled
+// flight. It is published as flight_cancellation_report.
+type CancellationReport struct {
+	FlightID int            `json:"flight_id"`
+	Policy   string         `json:"policy"`
+	Totals   map[string]int `json:"totals"`
+	Outcomes []Outcome      `json:"outcomes"`
+}
+
+func (rep *CancellationReport) add(o Outcome) {
+	rep.Outcomes = append(rep.Outcomes, o)
+	rep.Totals[o.Result]++
+}
+
+// alternative is a flight the passengers of a cancelled flight can move to.
+type alternative struct {
+	ID             int       `db:"id"`
+	Departure      time.Time `db:"departure"`
+	AvailableSeats int       `db:"available_seats"`
+}
+
+// alternativeFlights lists scheduled flights on the same route departing no
+// earlier than the cancelled one, soonest first.
+func (r *Repository) alternativeFlights(ctx context.Context, ev flightEvent) ([]alternative, error) {
+	query := `
+		SELECT id, departure, available_seats FROM flights
+		WHERE source = $1 AND destination = $2 AND departure >= $3 AND id <> $4
+		AND status <> 'cancelled' AND available_seats > 0
+		ORDER BY departure, id
+		LIMIT $5`
+	var alts []alternative
+	f := ev.Flight
+	if err := r.DB.SelectContext(ctx, &alts, query, f.Source, f.Destination, f.Departure, f.ID, maxAlternatives); err != nil {
+		return nil, fmt.Errorf("failed to find alternatives to flight %d: %w", f.ID, err)
+	}
+	return alts, nil
+}
+
+// cancelled moves or refunds every passenger of a cancelled flight. Held
+// bookings are simply released. Replaying the event is safe: bookings
+// already handled no longer hold seats on the flight.
+func (e *FlightEvents) cancelled(ctx context.Context, ev flightEvent) error {
+	bookings, err := e.Repo.activeBookings(ctx, ev.Flight.ID)
+	if err != nil {
+		return err
+	}
+	alts, err := e.Repo.alternativeFlights(ctx, ev)
+	if err != nil {
+		return err
+	}
+
+	policy := ev.Reaccommodation
+	if policy != reaccommodateOffer {
+		policy = reaccommodateAuto
+	}
+	report := &CancellationReport{FlightID: ev.Flight.ID, Policy: policy, Totals: map[string]int{}}
+
+	for _, b := range bookings {
+		var o Outcome
+		switch {
+		case b.Status == StatusHeld:
+			o = e.release(b)
+		case policy == reaccommodateOffer:
+			o = e.offer(ctx, b, alts, ev.Reason)
+		default:
+			o = e.move(ctx, b, alts, ev.Reason)
+		}
+		report.add(o)
+	}
+
+	if len(bookings) > 0 {
+		e.publish("flight_cancellation_report", report)
+	}
+	log.Printf("Flight %d cancelled: %d bookings, outcomes %v", ev.Flight.ID, len(bookings), report.Totals)
+	return nil
+}
+
+// move puts a booking on the first alternative with enough seats left that
+// the passenger is not already booked on, and refunds it if there is none.
+func (e *FlightEvents) move(ctx context.Context, b Booking, alts []alternative, reason string) Outcome {
+	for i := range alts {
+		alt := &alts[i]
+		if alt.AvailableSeats < b.Seats {
+			continue
+		}
+		// The passenger keeps the fare paid, outside the booking classes
+		// of the new flight
+		moved := b
+		moved.FlightID = alt.ID
+		moved.FareClass = ""
+		moved.keepFare = true
+		err := e.Repo.UpdateBooking(&moved)
+		if errors.Is(err, ErrSoldOut) || errors.Is(err, ErrFlightCancelled) || errors.Is(err, ErrFlightNotFound) ||
+			errors.Is(err, ErrDuplicateBooking) {
+			continue
+		}
+		if err != nil {
+			return e.failed(b, err)
+		}
+
+		alt.AvailableSeats -= b.Seats
+		moved.NeedsReaccommodation = false
+		e.publish("booking_reaccommodated", moved)
+		e.notify(ctx, moved, NotifyReaccommodated, map[string]any{"old_flight_id": b.FlightID, "reason": reason})
+		return Outcome{BookingID: b.ID, Passenger: b.Passenger, Result: OutcomeMoved, NewFlightID: alt.ID}
+	}
+	return e.refund(ctx, b, reason)
+}
+
+// offer flags a booking and tells the passenger about the first alternative
+// with enough seats, leaving the choice to them. Seats are not reserved.
+func (e *FlightEvents) offer(ctx context.Context, b Booking, alts []alternative, reason string) Outcome {
+	for _, alt := range alts {
+		if alt.AvailableSeats < b.Seats {
+			continue
+		}
+		if err := e.Repo.flagForReaccommodation(ctx, b); err != nil {
+			return e.failed(b, err)
+		}
+		e.notify(ctx, b, NotifyAlternative, map[string]any{"alternative_flight_id": alt.ID, "departure": alt.Departure, "reason": reason})
+		return Outcome{BookingID: b.ID, Passenger: b.Passenger, Result: OutcomeOffered, NewFlightID: alt.ID}
+	}
+	return e.refund(ctx, b, reason)
+}
+
+func (e *FlightEvents) refund(ctx context.Context, b Booking, reason string) Outcome {
+	refunded, err := e.Repo.RefundBooking(ctx, b.ID)
+	if err != nil {
+		return e.failed(b, err)
+	}
+	e.publish("booking_refunded", refunded)
+	e.notify(ctx, refunded, NotifyRefunded, map[string]any{"amount": refunded.AmountDue(), "reason": reason})
+	return Outcome{BookingID: b.ID, Passenger: b.Passenger, Result: OutcomeRefunded}
+}
+
+func (e *FlightEvents) release(b Booking) Outcome {
+	released, err := e.Repo.CancelBooking(b.ID)
+	if err != nil {
+		return e.failed(b, err)
+	}
+	e.publish("booking_cancelled", released)
+	return Outcome{BookingID: b.ID, Passenger: b.Passenger, Result: OutcomeReleased}
+}
+
+func (e *FlightEvents) failed(b Booking, err error) Outcome {
+	log.Printf("Failed to re-accommodate booking %d: %v", b.ID, err)
+	return Outcome{BookingID: b.ID, Passenger: b.Passenger, Result: OutcomeFailed, Error: err.Error()}
+}
+
+func (e *FlightEvents) notify(ctx context.Context, b Booking, kind string, details map[string]any) {
+	n := Notification{Kind: kind, BookingID: b.ID, FlightID: b.FlightID, Passenger: b.Passenger, Details: details}
+	if err := e.Notifier.Notify(ctx, n); err != nil {
+		log.Printf("Failed to notify %s about booking %d: %v", b.Passenger, b.ID, err)
+	}
+}
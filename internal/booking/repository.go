@@ -2,103 +2,510 @@ package booking
 
 import (
 	"context"
-	"encoding/json"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
+	"sync/atomic"
 	"time"
 
+	"airline-booking/internal/aircraft"
+	"airline-booking/internal/fare"
+	"airline-booking/internal/pricing"
+	"airline-booking/internal/tax"
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/cache"
+	"airline-booking/pkg/db"
+	"airline-booking/pkg/money"
+	"airline-booking/pkg/redis"
+
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jmoiron/sqlx"
-	"github.com/redis/go-redis/v9"
 )
 
+const (
+	bookingsAllKey = "bookings:all"
+	bookingsTTL    = 30 * time.Second
+	duplicateTTL   = time.Hour
+	holdTTL        = 15 * time.Minute
+
+	// flightsTag is the cache tag of the flight listings owned by flight-service
+	flightsTag = "flights"
+
+	// duplicateIndex allows one booking holding seats per passenger and
+	// flight; uniqueViolation is the SQLSTATE of breaking it
+	duplicateIndex  = "bookings_passenger_flight_key"
+	uniqueViolation = "23505"
+)
+
+var (
+	// ErrBookingNotFound is returned when no booking matches the given ID.
+	ErrBookingNotFound = apperr.NotFound("booking_not_found", "booking not found")
+	// ErrDuplicateBooking is returned when a passenger books the same flight twice.
+	ErrDuplicateBooking = apperr.Duplicate("duplicate_booking", "passenger already has a booking on this flight")
+)
+
+const bookingColumns = `id, flight_id, passenger, seats, passenger_types, total_price AS "total_price.amount", currency AS "total_price.currency", fare_class, status,
+	held_until, needs_reaccommodation, seat_charge AS "seat_charge.amount", currency AS "seat_charge.currency", exchange_rate, charges, booked_at,
+	COALESCE((SELECT string_agg(seat, ',' ORDER BY traveller) FROM seat_assignments s WHERE s.booking_id = bookings.id), '') AS seat_numbers`
+
 type Repository struct {
-	DB    *sqlx.DB
-	Cache *redis.Client
+	DB       *sqlx.DB
+	Replicas *db.Cluster
+	Cache    *cache.Cache
+	Locker   *redis.Locker
+	// Fleet provides the seat layouts seats are assigned from
+	Fleet *aircraft.Repository
+	// Pricing sets the live fare of bookings in a booking class
+	Pricing *pricing.Repository
+	// Rates converts bookings for display in other currencies
+	Rates *money.Rates
+	// Taxes holds the taxes and fees bookings are charged; nil charges the
+	// fare alone
+	Taxes *tax.Repository
 	Ctx   context.Context
+
+	ttl atomic.Int64
 }
 
-func NewRepository(db *sqlx.DB, cache *redis.Client) *Repository {
+func NewRepository(cluster *db.Cluster, c *cache.Cache, locker *redis.Locker, fleet *aircraft.Repository, prices *pricing.Repository,
+	rates *money.Rates, taxes *tax.Repository) *Repository {
 	return &Repository{
-		DB:    db,
-		Cache: cache,
-		Ctx:   context.Background(),
+		DB:       cluster.Primary,
+		Replicas: cluster,
+		Cache:    c,
+		Locker:   locker,
+		Fleet:    fleet,
+		Pricing:  prices,
+		Rates:    rates,
+		Taxes:    taxes,
+		Ctx:      context.Background(),
 	}
 }
 
-// AddBooking inserts a new booking into DB and caches it in Redis to prevent duplicates.
-func (r *Repository) AddBooking(b Booking) error {
-	cacheKey := fmt.Sprintf("booking:%s:%d", b.Passenger, b.FlightID)
+// Cache keys for the booking list views. Each view is also its own
+// invalidation tag so a mutation can evict exactly the views it touches.
+func passengerKey(passenger string) string { return "bookings:passenger:" + passenger }
+func flightKey(flightID int) string        { return fmt.Sprintf("bookings:flight:%d", flightID) }
+func duplicateKey(b Booking) string        { return fmt.Sprintf("booking:%s:%d", b.Passenger, b.FlightID) }
+
+// AddBooking allocates seats on the flight and inserts the booking, caching
+// it in Redis to prevent duplicates. b is filled in with its ID, defaults
+// and, on flights with a seat map, its seat numbers, and charged the taxes
+// and fees in force. A currency other than that of the booking locks the
+// exchange rate to it into b: that of the quote b was made from, if it was
+// shown in currency, or the current one.
+func (r *Repository) AddBooking(b *Booking, currency string) error {
+	cacheKey := duplicateKey(*b)
 
 	// Check if user already booked this flight (from cache)
-	exists, err := r.Cache.Exists(r.Ctx, cacheKey).Result()
-	if err != nil {
-		log.Printf("Redis check failed: %v", err)
+	if r.Cache.Exists(r.Ctx, cacheKey) {
+		return fmt.Errorf("%w: passenger %s, flight %d", ErrDuplicateBooking, b.Passenger, b.FlightID)
+	}
+
+	if b.Status == "" {
+		b.Status = StatusConfirmed
 	}
-	if exists > 0 {
-		return fmt.Errorf("duplicate booking detected for user %s and flight %d", b.Passenger, b.FlightID)
+	if b.TotalPrice.Currency == "" {
+		b.TotalPrice.Currency = money.DefaultCurrency
+	}
+	if b.Status == StatusHeld && b.HeldUntil == nil {
+		until := time.Now().Add(holdTTL)
+		b.HeldUntil = &until
 	}
 
-	// Insert booking into DB
-	query := `
-		INSERT INTO bookings (flight_id, passenger, seats, total_price, status)
-		VALUES ($1, $2, $3, $4, $5)`
-	_, err = r.DB.Exec(query, b.FlightID, b.Passenger, b.Seats, b.TotalPrice, b.Status)
+	err := r.withSeatLocks(r.Ctx, []int{b.FlightID}, func(tx *sqlx.Tx, g *seatGuard) error {
+		var quote *pricing.Quote
+		if holdsSeats(b.Status) {
+			// Priced before the seats are taken, as the flight stood when booked
+			var err error
+			if quote, err = r.takeFare(g.ctx, tx, b); err != nil {
+				return err
+			}
+			if err := g.adjustSeats(tx, b.FlightID, b.Seats); err != nil {
+				return err
+			}
+		}
+		if err := r.lockRate(g.ctx, tx, b, currency); err != nil {
+			return err
+		}
+		if quote != nil && b.ExchangeRate != nil {
+			if err := quote.Convert(*b.ExchangeRate); err != nil {
+				return err
+			}
+		}
+
+		query := `
+			INSERT INTO bookings (flight_id, passenger, seats, passenger_types, total_price, currency, fare_class, status, held_until, exchange_rate)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			RETURNING id, booked_at`
+		err := tx.QueryRowxContext(g.ctx, query, b.FlightID, b.Passenger, b.Seats, b.PassengerTypes, b.TotalPrice.Amount, b.TotalPrice.Currency,
+			b.FareClass, b.Status, b.HeldUntil, b.ExchangeRate).
+			Scan(&b.ID, &b.BookedAt)
+		if isDuplicate(err) {
+			return fmt.Errorf("%w: passenger %s, flight %d", ErrDuplicateBooking, b.Passenger, b.FlightID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to insert booking: %w", err)
+		}
+		if err := recordQuote(g.ctx, tx, quote, b.ID); err != nil {
+			return err
+		}
+		if holdsSeats(b.Status) {
+			if err := r.assignSeats(g.ctx, tx, b); err != nil {
+				return err
+			}
+		} else {
+			b.SeatNumbers = nil
+		}
+		return r.charge(g.ctx, tx, b)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to insert booking: %w", err)
+		return err
 	}
 
-	// Cache the booking for 1 hour to prevent duplicate submissions
-	data, _ := json.Marshal(b)
-	err = r.Cache.Set(r.Ctx, cacheKey, data, time.Hour).Err()
-	if err != nil {
+	r.invalidate(*b)
+	r.remember(*b)
+	return nil
+}
+
+// remember caches b for 1 hour so the passenger cannot book its flight a
+// second time while it holds seats.
+func (r *Repository) remember(b Booking) {
+	cacheKey := duplicateKey(b)
+	if err := r.Cache.Set(r.Ctx, cacheKey, b, cache.Options{TTL: duplicateTTL}); err != nil {
 		log.Printf("Failed to cache booking in Redis: %v", err)
 	} else {
-		log.Printf("Booking cached in Redis: %s", cacheKey)
+		slog.Debug("Booking cached in Redis", "key", cacheKey)
+	}
+}
+
+// UpdateBooking modifies an existing booking, moving its seats if the flight,
+// seat count or status changed, and evicts the views of both the old and the
+// new passenger and flight. Moving to another flight settles a pending
+// re-accommodation. The total price sent is ignored: the booking keeps its
+// price unless it is repriced for another flight, class or seat count.
+// Seat numbers are reassigned when they change or no longer fit; b is
+// filled in with the seats it ends up with. The booking is charged again
+// only if its flight, travellers or prices changed. While
+// it holds seats it is cached under its passenger and flight as a new
+// booking is, to prevent duplicates; taking seats on a flight where the
+// passenger already holds some returns ErrDuplicateBooking.
+func (r *Repository) UpdateBooking(b *Booking) error {
+	current, err := r.getBooking(r.Ctx, r.DB, b.ID, false)
+	if err != nil {
+		return err
 	}
 
+	var old Booking
+	err = r.withSeatLocks(r.Ctx, []int{current.FlightID, b.FlightID}, func(tx *sqlx.Tx, g *seatGuard) error {
+		old, err = r.getBooking(g.ctx, tx, b.ID, true)
+		if err != nil {
+			return err
+		}
+		if old.FlightID != current.FlightID {
+			return fmt.Errorf("booking %d changed concurrently", b.ID)
+		}
+		if holdsSeats(b.Status) && (duplicateKey(old) != duplicateKey(*b) || !holdsSeats(old.Status)) {
+			if err := checkDuplicate(g.ctx, tx, *b); err != nil {
+				return err
+			}
+		}
+		b.BookedAt = old.BookedAt
+		// Clients cannot set the price; only repricing below changes it
+		b.TotalPrice = old.TotalPrice
+
+		if holdsSeats(old.Status) {
+			if err := g.adjustSeats(tx, old.FlightID, -old.Seats); err != nil {
+				return err
+			}
+		}
+		if holdsSeats(b.Status) {
+			if err := g.adjustSeats(tx, b.FlightID, b.Seats); err != nil {
+				return err
+			}
+		}
+		quote, err := r.rebookFare(g.ctx, tx, old, b)
+		if err != nil {
+			return err
+		}
+		// A booking repriced in another currency locks a new rate to the
+		// currency it is shown in
+		b.ExchangeRate = old.ExchangeRate
+		if old.ExchangeRate != nil && old.ExchangeRate.From != b.TotalPrice.Currency {
+			if err := r.lockRate(g.ctx, tx, b, old.ExchangeRate.To); err != nil {
+				return err
+			}
+		}
+		if quote != nil && b.ExchangeRate != nil {
+			if err := quote.Convert(*b.ExchangeRate); err != nil {
+				return err
+			}
+		}
+		if err := recordQuote(g.ctx, tx, quote, b.ID); err != nil {
+			return err
+		}
+
+		query := `
+			UPDATE bookings SET flight_id = $1, passenger = $2, seats = $3, passenger_types = $4, total_price = $5, currency = $6,
+				fare_class = $7, status = $8, held_until = $9, exchange_rate = $10, needs_reaccommodation = needs_reaccommodation AND flight_id = $1
+			WHERE id = $11`
+		_, err = tx.ExecContext(g.ctx, query, b.FlightID, b.Passenger, b.Seats, b.PassengerTypes, b.TotalPrice.Amount, b.TotalPrice.Currency,
+			b.FareClass, b.Status, b.HeldUntil, b.ExchangeRate, b.ID)
+		if isDuplicate(err) {
+			return fmt.Errorf("%w: passenger %s, flight %d", ErrDuplicateBooking, b.Passenger, b.FlightID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update booking: %w", err)
+		}
+		if err := r.reassignSeats(g.ctx, tx, old, b); err != nil {
+			return err
+		}
+		return r.recharge(g.ctx, tx, old, b)
+	})
+	if err != nil {
+		return err
+	}
+
+	r.invalidate(old, *b)
+	if duplicateKey(old) != duplicateKey(*b) || !holdsSeats(b.Status) {
+		r.Cache.Delete(r.Ctx, duplicateKey(old))
+	}
+	if holdsSeats(b.Status) {
+		r.remember(*b)
+	}
 	return nil
 }
 
-// GetAllBookings retrieves all bookings, using Redis cache if available.
-func (r *Repository) GetAllBookings() ([]Booking, error) {
-	cacheKey := "bookings:all"
+// MarkForReaccommodation flags every booking holding seats on a flight as
+// needing re-accommodation and returns them.
+func (r *Repository) MarkForReaccommodation(ctx context.Context, flightID int) ([]Booking, error) {
+	query := `
+		UPDATE bookings SET needs_reaccommodation = true
+		WHERE flight_id = $1 AND status IN ($2, $3)
+		RETURNING ` + bookingColumns
+	var bookings []Booking
+	if err := r.DB.SelectContext(ctx, &bookings, query, flightID, StatusConfirmed, StatusHeld); err != nil {
+		return nil, fmt.Errorf("failed to mark bookings on flight %d: %w", flightID, err)
+	}
 
-	// Try fetching from Redis cache first
-	cachedData, err := r.Cache.Get(r.Ctx, cacheKey).Result()
-	if err == nil && cachedData != "" {
-		var cachedBookings []Booking
-		if err := json.Unmarshal([]byte(cachedData), &cachedBookings); err == nil {
-			log.Println("Bookings served from Redis cache")
-			return cachedBookings, nil
-		}
+	if len(bookings) > 0 {
+		r.invalidate(bookings...)
 	}
+	return bookings, nil
+}
 
-	// Fetch from database if cache miss
-	rows, err := r.DB.Queryx(`SELECT id, flight_id, passenger, seats, total_price, status FROM bookings`)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch bookings: %w", err)
+// flagForReaccommodation marks a single booking as needing re-accommodation.
+func (r *Repository) flagForReaccommodation(ctx context.Context, b Booking) error {
+	if _, err := r.DB.ExecContext(ctx, `UPDATE bookings SET needs_reaccommodation = true WHERE id = $1`, b.ID); err != nil {
+		return fmt.Errorf("failed to flag booking %d: %w", b.ID, err)
 	}
-	defer rows.Close()
+	r.invalidate(b)
+	return nil
+}
 
+// activeBookings lists the bookings holding seats on a flight, oldest first,
+// read from the primary.
+func (r *Repository) activeBookings(ctx context.Context, flightID int) ([]Booking, error) {
+	query := `SELECT ` + bookingColumns + ` FROM bookings WHERE flight_id = $1 AND status IN ($2, $3) ORDER BY id`
 	var bookings []Booking
-	for rows.Next() {
-		var b Booking
-		if err := rows.StructScan(&b); err != nil {
-			return nil, err
+	if err := r.DB.SelectContext(ctx, &bookings, query, flightID, StatusConfirmed, StatusHeld); err != nil {
+		return nil, fmt.Errorf("failed to fetch bookings on flight %d: %w", flightID, err)
+	}
+	return bookings, nil
+}
+
+// CancelBooking marks a booking as cancelled, returns its seats to the
+// flight and returns the updated record.
+func (r *Repository) CancelBooking(id int) (Booking, error) {
+	return r.release(r.Ctx, id, StatusCancelled)
+}
+
+// RefundBooking marks a booking as refunded and returns its seats to the flight.
+func (r *Repository) RefundBooking(ctx context.Context, id int) (Booking, error) {
+	return r.release(ctx, id, StatusRefunded)
+}
+
+// ExpireHolds releases the seats of held bookings whose hold has passed.
+// It is meant to run as a singleton background job.
+func (r *Repository) ExpireHolds(ctx context.Context) error {
+	var ids []int
+	err := r.DB.SelectContext(ctx, &ids, `SELECT id FROM bookings WHERE status = $1 AND held_until < now()`, StatusHeld)
+	if err != nil {
+		return fmt.Errorf("failed to find expired holds: %w", err)
+	}
+
+	expired := 0
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if _, err := r.release(ctx, id, StatusExpired); err != nil {
+			log.Printf("Failed to expire hold on booking %d: %v", id, err)
+			continue
 		}
-		bookings = append(bookings, b)
+		expired++
 	}
+	if expired > 0 {
+		log.Printf("Expired %d held bookings", expired)
+	}
+	return nil
+}
 
-	// Store in Redis cache for faster future access (30s TTL)
-	if len(bookings) > 0 {
-		data, _ := json.Marshal(bookings)
-		err = r.Cache.Set(r.Ctx, cacheKey, data, 30*time.Second).Err()
+// release moves a booking to a status without seats and returns its seats
+// to the flight. Releasing an already released booking is a no-op.
+func (r *Repository) release(ctx context.Context, id int, status string) (Booking, error) {
+	b, err := r.getBooking(ctx, r.DB, id, false)
+	if err != nil {
+		return b, err
+	}
+
+	err = r.withSeatLocks(ctx, []int{b.FlightID}, func(tx *sqlx.Tx, g *seatGuard) error {
+		flightID := b.FlightID
+		b, err = r.getBooking(g.ctx, tx, id, true)
 		if err != nil {
-			log.Printf("Failed to cache bookings list: %v", err)
-		} else {
-			log.Println("Cached bookings list in Redis")
+			return err
+		}
+		if b.FlightID != flightID {
+			return fmt.Errorf("booking %d changed concurrently", id)
+		}
+		if !holdsSeats(b.Status) {
+			return nil
+		}
+		if err := g.adjustSeats(tx, b.FlightID, -b.Seats); err != nil {
+			return err
+		}
+		if err := releaseSeats(g.ctx, tx, id); err != nil {
+			return err
+		}
+		if b.FareClass != "" {
+			if err := fare.Release(g.ctx, tx, b.FlightID, b.FareClass, b.Seats); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.ExecContext(g.ctx, `UPDATE bookings SET status = $1 WHERE id = $2`, status, id); err != nil {
+			return fmt.Errorf("failed to update booking %d: %w", id, err)
 		}
+		b.Status = status
+		b.SeatNumbers = nil
+		return nil
+	})
+	if err != nil {
+		return b, err
 	}
 
-	return bookings, nil
+	r.invalidate(b)
+	// Let the passenger book this flight again
+	r.Cache.Delete(ctx, duplicateKey(b))
+	return b, nil
+}
+
+// checkDuplicate returns ErrDuplicateBooking if the passenger of b holds
+// seats on its flight under another booking.
+func checkDuplicate(ctx context.Context, tx *sqlx.Tx, b Booking) error {
+	var exists bool
+	query := `
+		SELECT EXISTS (SELECT 1 FROM bookings WHERE passenger = $1 AND flight_id = $2 AND id <> $3 AND status IN ($4, $5))`
+	if err := tx.GetContext(ctx, &exists, query, b.Passenger, b.FlightID, b.ID, StatusConfirmed, StatusHeld); err != nil {
+		return fmt.Errorf("failed to check bookings of %s on flight %d: %w", b.Passenger, b.FlightID, err)
+	}
+	if exists {
+		return fmt.Errorf("%w: passenger %s, flight %d", ErrDuplicateBooking, b.Passenger, b.FlightID)
+	}
+	return nil
+}
+
+// isDuplicate reports whether err is a violation of the index allowing one
+// booking holding seats per passenger and flight.
+func isDuplicate(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == duplicateIndex
+}
+
+// GetBooking fetches a single booking, from a replica when possible.
+func (r *Repository) GetBooking(ctx context.Context, id int) (Booking, error) {
+	return r.getBooking(ctx, r.Replicas.Reader(ctx), id, false)
+}
+
+// getBooking loads a booking, optionally locking its row for the rest of the transaction.
+func (r *Repository) getBooking(ctx context.Context, q sqlx.QueryerContext, id int, forUpdate bool) (Booking, error) {
+	query := `SELECT ` + bookingColumns + ` FROM bookings WHERE id = $1`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	var b Booking
+	err := sqlx.GetContext(ctx, q, &b, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return b, ErrBookingNotFound
+	}
+	if err != nil {
+		return b, fmt.Errorf("failed to fetch booking %d: %w", id, err)
+	}
+	return b, nil
+}
+
+// GetAllBookings retrieves all bookings, using Redis cache if available.
+func (r *Repository) GetAllBookings(ctx context.Context) ([]Booking, error) {
+	return r.cachedList(ctx, bookingsAllKey, `SELECT `+bookingColumns+` FROM bookings`)
+}
+
+// GetBookingsByPassenger retrieves the bookings of a single passenger.
+func (r *Repository) GetBookingsByPassenger(ctx context.Context, passenger string) ([]Booking, error) {
+	return r.cachedList(ctx, passengerKey(passenger), `SELECT `+bookingColumns+` FROM bookings WHERE passenger = $1`, passenger)
+}
+
+// GetBookingsByFlight retrieves the bookings made on a single flight.
+func (r *Repository) GetBookingsByFlight(ctx context.Context, flightID int) ([]Booking, error) {
+	return r.cachedList(ctx, flightKey(flightID), `SELECT `+bookingColumns+` FROM bookings WHERE flight_id = $1`, flightID)
+}
+
+// SetCacheTTL changes how long booking list views stay cached.
+func (r *Repository) SetCacheTTL(ttl time.Duration) {
+	r.ttl.Store(int64(ttl))
+}
+
+func (r *Repository) cacheTTL() time.Duration {
+	if ttl := time.Duration(r.ttl.Load()); ttl > 0 {
+		return ttl
+	}
+	return bookingsTTL
+}
+
+// cachedList serves a booking list view from cache, loading it from a
+// replica with query on a miss. Requests that must see their own writes
+// bypass the cached copy and read from the primary.
+func (r *Repository) cachedList(ctx context.Context, key, query string, args ...any) ([]Booking, error) {
+	opts := cache.Options{TTL: r.cacheTTL(), Tags: []string{key}, Refresh: db.PrimaryForced(ctx)}
+	return cache.GetOrLoad(ctx, r.Cache, key, opts, func(ctx context.Context) ([]Booking, error) {
+		rows, err := r.Replicas.Reader(ctx).QueryxContext(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch bookings: %w", err)
+		}
+		defer rows.Close()
+
+		// Non-nil so an empty result is cached and served as []
+		bookings := []Booking{}
+		for rows.Next() {
+			var b Booking
+			if err := rows.StructScan(&b); err != nil {
+				return nil, err
+			}
+			bookings = append(bookings, b)
+		}
+		return bookings, rows.Err()
+	})
+}
+
+// invalidate evicts every cached view that contains any of the given bookings.
+func (r *Repository) invalidate(bookings ...Booking) {
+	// Seat counts changed too, so drop the flight-service listings as well
+	tags := []string{bookingsAllKey, flightsTag}
+	for _, b := range bookings {
+		tags = append(tags, passengerKey(b.Passenger), flightKey(b.FlightID))
+	}
+	if err := r.Cache.InvalidateTags(r.Ctx, tags...); err != nil {
+		log.Printf("Failed to invalidate booking cache: %v", err)
+	}
 }
@@ -0,0 +1,66 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestCheckDuplicate(t *testing.T) {
+	tests := []struct {
+		name   string
+		exists bool
+		want   error
+	}{
+		{name: "no other booking", exists: false},
+		{name: "passenger already booked", exists: true, want: ErrDuplicateBooking},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mock, _ := newSeatRepo(t)
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM bookings WHERE passenger = \$1 AND flight_id = \$2 AND id <> \$3`).
+				WithArgs("ana", 9, 4, StatusConfirmed, StatusHeld).
+				WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(tt.exists))
+			mock.ExpectRollback()
+
+			tx, err := r.DB.Beginx()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = checkDuplicate(context.Background(), tx, Booking{ID: 4, Passenger: "ana", FlightID: 9})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("checkDuplicate() = %v, want %v", err, tt.want)
+			}
+			tx.Rollback()
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestIsDuplicate(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "duplicate booking", err: &pgconn.PgError{Code: uniqueViolation, ConstraintName: duplicateIndex}, want: true},
+		{name: "wrapped", err: fmt.Errorf("insert: %w", &pgconn.PgError{Code: uniqueViolation, ConstraintName: duplicateIndex}), want: true},
+		{name: "other unique index", err: &pgconn.PgError{Code: uniqueViolation, ConstraintName: "seat_assignments_pkey"}},
+		{name: "other error", err: errors.New("connection reset")},
+		{name: "no error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isDuplicate(tt.err); got != tt.want {
+				t.Errorf("isDuplicate(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package booking
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"airline-booking/internal/aircraft"
+	"airline-booking/internal/fare"
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/money"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	// ErrSeatTaken is returned when a requested seat is already occupied.
+	ErrSeatTaken = apperr.Conflict("seat_taken", "seat is already taken")
+	// ErrNoSeatMap is returned when requesting seats on a flight without an aircraft.
+	ErrNoSeatMap = apperr.Validation("no_seat_map", "flight has no seat map",
+		apperr.FieldError{Field: "seat_numbers", Message: "cannot be chosen on this flight"})
+	// ErrSeatCurrency is returned when seats are priced in another currency than the booking.
+	ErrSeatCurrency = apperr.Conflict("seat_currency_mismatch", "seats are priced in another currency than the booking")
+)
+
+// flightLayout returns the seat layout of a flight, or false if it has no
+// aircraft assigned.
+func (r *Repository) flightLayout(ctx context.Context, tx *sqlx.Tx, flightID int) (aircraft.Type, bool, error) {
+	var code string
+	if err := tx.GetContext(ctx, &code, `SELECT aircraft FROM flights WHERE id = $1`, flightID); err != nil {
+		return aircraft.Type{}, false, fmt.Errorf("failed to fetch aircraft of flight %d: %w", flightID, err)
+	}
+	if code == "" || r.Fleet == nil {
+		return aircraft.Type{}, false, nil
+	}
+	t, err := r.Fleet.Type(ctx, code)
+	if err != nil {
+		return t, false, err
+	}
+	return t, true, nil
+}
+
+// assignSeats gives b the seats it requested, or picks seats for the party
+// when it requested none, and sets its seat charge in the currency of the
+// booking. Bookings in a booking
+// class sit in its cabin. It must run with the seat lock of the flight
+// held and after b's seats have been taken. Flights without a seat map
+// only take a seat count.
+func (r *Repository) assignSeats(ctx context.Context, tx *sqlx.Tx, b *Booking) error {
+	layout, ok, err := r.flightLayout(ctx, tx, b.FlightID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if len(b.SeatNumbers) > 0 {
+			return ErrNoSeatMap
+		}
+		b.SeatCharge = money.New(0, b.TotalPrice.Currency)
+		return nil
+	}
+	var cabin string
+	if b.FareClass != "" {
+		if cabin, err = fare.CabinOf(ctx, tx, b.FlightID, b.FareClass); err != nil {
+			return err
+		}
+	}
+
+	var seats []aircraft.Seat
+	b.SeatCharge = money.New(0, b.TotalPrice.Currency)
+	if len(b.SeatNumbers) > 0 {
+		for i, number := range b.SeatNumbers {
+			seat, ok := layout.Seat(number)
+			if !ok || seat.Blocked {
+				return apperr.Validation("seat_not_found", "seat is not available on this aircraft",
+					apperr.FieldError{Field: "seat_numbers", Message: fmt.Sprintf("seat %q is not on the aircraft", number)})
+			}
+			if cabin != "" && seat.Class != cabin {
+				return apperr.Validation("seat_not_in_cabin", "seat is outside the cabin of the booking class",
+					apperr.FieldError{Field: "seat_numbers", Message: fmt.Sprintf("seat %s is not in the %s cabin", seat.Number, cabin)})
+			}
+			if slices.ContainsFunc(seats, func(s aircraft.Seat) bool { return s.Number == seat.Number }) {
+				return apperr.Validation("validation_failed", "request has invalid fields",
+					apperr.FieldError{Field: "seat_numbers", Message: fmt.Sprintf("lists seat %s twice", seat.Number)})
+			}
+			seats = append(seats, seat)
+			b.SeatNumbers[i] = seat.Number
+			if b.SeatCharge, err = b.SeatCharge.Add(seat.Price); err != nil {
+				return fmt.Errorf("%w: seat %s costs %s", ErrSeatCurrency, seat.Number, seat.Price)
+			}
+		}
+	} else {
+		var occupied []string
+		if err := tx.SelectContext(ctx, &occupied, `SELECT seat FROM seat_assignments WHERE flight_id = $1`, b.FlightID); err != nil {
+			return fmt.Errorf("failed to fetch seats of flight %d: %w", b.FlightID, err)
+		}
+		if seats = pickSeats(layout, occupied, b.Seats, cabin); seats == nil {
+			return ErrSoldOut
+		}
+		b.SeatNumbers = nil
+		for _, s := range seats {
+			b.SeatNumbers = append(b.SeatNumbers, s.Number)
+		}
+	}
+
+	// The primary key on (flight_id, seat) settles races the seat lock
+	// does not cover, such as a lock lost to Redis failing over
+	for i, s := range seats {
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO seat_assignments (flight_id, seat, booking_id, traveller) VALUES ($1, $2, $3, $4)
+			ON CONFLICT DO NOTHING`, b.FlightID, s.Number, b.ID, i)
+		if err != nil {
+			return fmt.Errorf("failed to assign seat %s: %w", s.Number, err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return fmt.Errorf("%w: %s", ErrSeatTaken, s.Number)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE bookings SET seat_charge = $1 WHERE id = $2`, b.SeatCharge.Amount, b.ID); err != nil {
+		return fmt.Errorf("failed to update seat charge of booking %d: %w", b.ID, err)
+	}
+	return nil
+}
+
+// reassignSeats brings the seats of an updated booking in line with b.
+// Seats are kept while the flight, seat count, booking class, currency and
+// requested seats stay the same. Requested seats that were not changed along with
+// the rest are dropped in favour of assigned ones.
+func (r *Repository) reassignSeats(ctx context.Context, tx *sqlx.Tx, old Booking, b *Booking) error {
+	sameSeats := slices.Equal(b.SeatNumbers, old.SeatNumbers)
+	moved := b.FlightID != old.FlightID || b.Seats != old.Seats || b.FareClass != old.FareClass ||
+		b.TotalPrice.Currency != old.TotalPrice.Currency
+	if holdsSeats(old.Status) && holdsSeats(b.Status) && sameSeats && !moved {
+		b.SeatCharge = old.SeatCharge
+		return nil
+	}
+
+	if err := releaseSeats(ctx, tx, b.ID); err != nil {
+		return err
+	}
+	if !holdsSeats(b.Status) {
+		b.SeatNumbers = nil
+		return nil
+	}
+	if sameSeats && moved {
+		b.SeatNumbers = nil
+	}
+	return r.assignSeats(ctx, tx, b)
+}
+
+// releaseSeats frees the seats of a booking.
+func releaseSeats(ctx context.Context, tx *sqlx.Tx, bookingID int) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM seat_assignments WHERE booking_id = $1`, bookingID); err != nil {
+		return fmt.Errorf("failed to release seats of booking %d: %w", bookingID, err)
+	}
+	return nil
+}
+
+// pickSeats chooses n free seats for a party, in the given cabin unless it
+// is empty, cheapest rows first. It prefers seats side by side in one row,
+// split by an aisle if need be, and falls back to the free seats of
+// neighbouring rows. It returns nil if fewer than n seats are free.
+func pickSeats(layout aircraft.Type, occupied []string, n int, cabin string) []aircraft.Seat {
+	rows, _ := layout.Map(occupied)
+
+	type candidate struct {
+		price int64
+		row   aircraft.Row
+	}
+	var candidates []candidate
+	for _, row := range rows {
+		if cabin != "" && row.Class != cabin {
+			continue
+		}
+		price := int64(-1)
+		for _, s := range row.Seats {
+			if s.Status == aircraft.SeatFree && (price < 0 || s.Price.Amount < price) {
+				price = s.Price.Amount
+			}
+		}
+		if price >= 0 {
+			candidates = append(candidates, candidate{price, row})
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b candidate) int { return cmp.Compare(a.price, b.price) })
+
+	// A run of n free seats in one row, crossing an aisle only if need be
+	for _, c := range candidates {
+		for _, acrossAisle := range []bool{false, true} {
+			if run := freeRun(c.row, n, acrossAisle); run != nil {
+				return run
+			}
+		}
+	}
+
+	var seats []aircraft.Seat
+	for _, c := range candidates {
+		for _, s := range c.row.Seats {
+			if s.Status == aircraft.SeatFree {
+				seats = append(seats, s.Seat)
+				if len(seats) == n {
+					return seats
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// freeRun finds n adjacent free seats in a row.
+func freeRun(row aircraft.Row, n int, acrossAisle bool) []aircraft.Seat {
+	var run []aircraft.Seat
+	prev := -1
+	for _, s := range row.Seats {
+		pos := strings.Index(row.Layout, s.Letter)
+		adjacent := prev >= 0 && (pos == prev+1 || (acrossAisle && pos == prev+2))
+		switch {
+		case s.Status != aircraft.SeatFree:
+			run = nil
+		case adjacent:
+			run = append(run, s.Seat)
+		default:
+			run = []aircraft.Seat{s.Seat}
+		}
+		prev = pos
+		if len(run) == n {
+			return run
+		}
+	}
+	return nil
+}
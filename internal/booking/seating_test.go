@@ -0,0 +1,90 @@
+package booking
+
+import (
+	"slices"
+	"testing"
+
+	"airline-booking/internal/aircraft"
+	"airline-booking/pkg/money"
+)
+
+// testLayout has two business rows of two seats across an aisle and three
+// economy rows, the middle one an exit row charged extra.
+var testLayout = aircraft.Type{Code: "T1", Cabins: aircraft.Cabins{
+	{Class: aircraft.ClassBusiness, FirstRow: 1, LastRow: 2, Letters: "A-C"},
+	{Class: aircraft.ClassEconomy, FirstRow: 3, LastRow: 5, Letters: "ABC-DEF", ExitRows: []int{4},
+		ExitRowPrice: money.New(100000, "INR")},
+}}
+
+func numbers(seats []aircraft.Seat) []string {
+	var n []string
+	for _, s := range seats {
+		n = append(n, s.Number)
+	}
+	return n
+}
+
+func TestPickSeats(t *testing.T) {
+	tests := []struct {
+		name     string
+		occupied []string
+		n        int
+		cabin    string
+		want     []string
+	}{
+		{name: "side by side", n: 2, cabin: aircraft.ClassEconomy, want: []string{"3A", "3B"}},
+		{name: "across the aisle", occupied: []string{"3B", "3E"}, n: 2, cabin: aircraft.ClassEconomy, want: []string{"3C", "3D"}},
+		{
+			name:     "cheapest row with room",
+			occupied: []string{"3B", "3C", "3D", "3E", "3F"},
+			n:        3,
+			cabin:    aircraft.ClassEconomy,
+			want:     []string{"5A", "5B", "5C"},
+		},
+		{name: "split over rows", n: 3, cabin: aircraft.ClassBusiness, want: []string{"1A", "1C", "2A"}},
+		{name: "not enough free", occupied: []string{"2C"}, n: 4, cabin: aircraft.ClassBusiness},
+		{name: "any cabin", n: 1, want: []string{"1A"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := numbers(pickSeats(testLayout, tt.occupied, tt.n, tt.cabin))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("pickSeats() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFreeRun(t *testing.T) {
+	layout := testLayout
+	layout.Cabins = aircraft.Cabins{{Class: aircraft.ClassEconomy, FirstRow: 3, LastRow: 3, Letters: "ABC-DEF", Blocked: []string{"3C"}}}
+	rows, _ := layout.Map([]string{"3E"})
+	row := rows[0]
+
+	tests := []struct {
+		n           int
+		acrossAisle bool
+		want        []string
+	}{
+		{n: 2, want: []string{"3A", "3B"}},
+		// 3C is blocked and 3E taken
+		{n: 3},
+		{n: 3, acrossAisle: true},
+		{n: 1, want: []string{"3A"}},
+	}
+	for _, tt := range tests {
+		if got := numbers(freeRun(row, tt.n, tt.acrossAisle)); !slices.Equal(got, tt.want) {
+			t.Errorf("freeRun(%d, %v) = %v, want %v", tt.n, tt.acrossAisle, got, tt.want)
+		}
+	}
+
+	// Across the aisle once 3C is free
+	layout.Cabins[0].Blocked = nil
+	rows, _ = layout.Map([]string{"3A", "3E"})
+	if got := numbers(freeRun(rows[0], 3, true)); !slices.Equal(got, []string{"3B", "3C", "3D"}) {
+		t.Errorf("freeRun across the aisle = %v", got)
+	}
+	if got := freeRun(rows[0], 3, false); got != nil {
+		t.Errorf("freeRun without crossing the aisle = %v, want nil", numbers(got))
+	}
+}
@@ -0,0 +1,159 @@
+package booking
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/redis"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	seatLockWait  = 3 * time.Second
+	seatLockRetry = 50 * time.Millisecond
+)
+
+// seatLockTTL is how long a seat lock lives without renewal; tests shorten it.
+var seatLockTTL = 10 * time.Second
+
+var (
+	// ErrSoldOut is returned when a flight has fewer seats left than requested.
+	ErrSoldOut = apperr.SoldOut("sold_out", "not enough seats available")
+	// ErrFlightNotFound is returned when a booking refers to an unknown flight.
+	ErrFlightNotFound = apperr.Validation("flight_not_found", "flight not found")
+	// ErrFlightCancelled is returned when taking seats on a cancelled flight.
+	ErrFlightCancelled = apperr.Conflict("flight_cancelled", "flight is cancelled")
+	// ErrFlightBusy is returned when the seat lock of a flight cannot be
+	// taken or is lost, which a retry usually resolves.
+	ErrFlightBusy = apperr.Unavailable("flight_busy", "flight is busy, please retry")
+)
+
+// seatGuard holds the seat locks of the flights touched by one operation.
+// Its context is cancelled as soon as any of those locks is lost.
+type seatGuard struct {
+	ctx   context.Context
+	locks map[int]*redis.Lock
+}
+
+// lockFlights takes the seat lock of every given flight in ID order so two
+// operations on the same pair of flights cannot deadlock. If Redis is
+// unavailable the operation continues with the row-level checks in
+// Postgres alone.
+func (r *Repository) lockFlights(ctx context.Context, flightIDs ...int) (*seatGuard, error) {
+	ids := slices.Clone(flightIDs)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	g := &seatGuard{ctx: ctx, locks: make(map[int]*redis.Lock)}
+	for _, id := range ids {
+		acquireCtx, cancel := context.WithTimeout(ctx, seatLockWait)
+		lock, err := r.Locker.Acquire(acquireCtx, fmt.Sprintf("flight:%d:seats", id), seatLockTTL, seatLockRetry)
+		cancel()
+		if errors.Is(err, redis.ErrLockNotAcquired) {
+			g.release()
+			return nil, fmt.Errorf("flight %d is busy: %w", id, err)
+		}
+		if err != nil {
+			log.Printf("Seat lock for flight %d unavailable, relying on database checks: %v", id, err)
+			continue
+		}
+		g.locks[id] = lock
+		g.ctx = lock.KeepAlive(g.ctx)
+	}
+	return g, nil
+}
+
+func (g *seatGuard) release() {
+	for id, lock := range g.locks {
+		if err := lock.Release(context.Background()); err != nil {
+			log.Printf("Failed to release seat lock for flight %d: %v", id, err)
+		}
+	}
+}
+
+// adjustSeats takes seats from a flight, or returns them when seats is
+// negative. Seats can be returned to a cancelled flight but not taken. With
+// a lock held the write carries its fencing token and is rejected if a
+// newer holder has already written.
+func (g *seatGuard) adjustSeats(tx *sqlx.Tx, flightID, seats int) error {
+	var (
+		res sql.Result
+		err error
+	)
+	lock, locked := g.locks[flightID]
+	if locked {
+		res, err = tx.ExecContext(g.ctx, `
+			UPDATE flights SET available_seats = available_seats - $1, seat_fence = $2
+			WHERE id = $3 AND available_seats >= $1 AND seat_fence <= $2
+			AND ($1 <= 0 OR status <> 'cancelled')`, seats, lock.Token, flightID)
+	} else {
+		res, err = tx.ExecContext(g.ctx, `
+			UPDATE flights SET available_seats = available_seats - $1
+			WHERE id = $2 AND available_seats >= $1
+			AND ($1 <= 0 OR status <> 'cancelled')`, seats, flightID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update seats on flight %d: %w", flightID, err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+
+	// Nothing updated: find out why
+	var flight struct {
+		Fence  int64  `db:"seat_fence"`
+		Status string `db:"status"`
+	}
+	err = tx.GetContext(g.ctx, &flight, `SELECT seat_fence, status FROM flights WHERE id = $1`, flightID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrFlightNotFound
+	case err != nil:
+		return fmt.Errorf("failed to check flight %d: %w", flightID, err)
+	case locked && flight.Fence > lock.Token:
+		return redis.ErrLockLost
+	case flight.Status == "cancelled":
+		return ErrFlightCancelled
+	default:
+		return ErrSoldOut
+	}
+}
+
+// withSeatLocks runs fn in a transaction while holding the seat locks of
+// the given flights. The transaction is abandoned if a lock is lost.
+func (r *Repository) withSeatLocks(ctx context.Context, flightIDs []int, fn func(tx *sqlx.Tx, g *seatGuard) error) error {
+	g, err := r.lockFlights(ctx, flightIDs...)
+	if err != nil {
+		return err
+	}
+	defer g.release()
+
+	tx, err := r.DB.BeginTxx(g.ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx, g); err != nil {
+		return err
+	}
+	if g.ctx.Err() != nil {
+		return redis.ErrLockLost
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	return nil
+}
+
+// holdsSeats reports whether a booking in the given status occupies seats.
+func holdsSeats(status string) bool {
+	return status == StatusConfirmed || status == StatusHeld
+}
@@ -0,0 +1,166 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"airline-booking/pkg/redis"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jmoiron/sqlx"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const updateSeats = `UPDATE flights SET available_seats`
+
+// newSeatRepo returns a repository on a mock database whose seat locks
+// live in a fake Redis.
+func newSeatRepo(t *testing.T) (*Repository, sqlmock.Sqlmock, *miniredis.Miniredis) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	t.Cleanup(func() { mockDB.Close() })
+
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	r := &Repository{DB: sqlx.NewDb(mockDB, "sqlmock"), Locker: redis.NewLocker(&redis.RedisClient{Client: client}), Ctx: context.Background()}
+	return r, mock, mr
+}
+
+func TestWithSeatLocks(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, mr *miniredis.Miniredis, tx *sqlx.Tx, g *seatGuard) error
+		// adjusts is set when fn takes seats; commit when the transaction
+		// should be committed
+		adjusts bool
+		commit  bool
+		want    error
+	}{
+		{
+			name: "commits",
+			fn: func(t *testing.T, _ *miniredis.Miniredis, tx *sqlx.Tx, g *seatGuard) error {
+				return g.adjustSeats(tx, 1, 2)
+			},
+			adjusts: true,
+			commit:  true,
+		},
+		{
+			name: "rolls back when fn fails",
+			fn: func(*testing.T, *miniredis.Miniredis, *sqlx.Tx, *seatGuard) error {
+				return ErrSoldOut
+			},
+			want: ErrSoldOut,
+		},
+		{
+			name: "rolls back when the lock is lost mid-operation",
+			fn: func(t *testing.T, mr *miniredis.Miniredis, tx *sqlx.Tx, g *seatGuard) error {
+				if err := g.adjustSeats(tx, 1, 2); err != nil {
+					return err
+				}
+				// The lock expires before the work is done
+				mr.Del("lock:flight:1:seats")
+				select {
+				case <-g.ctx.Done():
+				case <-time.After(time.Second):
+					t.Fatal("guard context not cancelled after the lock was lost")
+				}
+				return nil
+			},
+			adjusts: true,
+			want:    redis.ErrLockLost,
+		},
+	}
+	defer func(ttl time.Duration) { seatLockTTL = ttl }(seatLockTTL)
+	seatLockTTL = 150 * time.Millisecond
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mock, mr := newSeatRepo(t)
+			mock.ExpectBegin()
+			if tt.adjusts {
+				mock.ExpectExec(updateSeats).WithArgs(2, sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			if tt.commit {
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			err := r.withSeatLocks(context.Background(), []int{1}, func(tx *sqlx.Tx, g *seatGuard) error {
+				return tt.fn(t, mr, tx, g)
+			})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("withSeatLocks error = %v, want %v", err, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+			if mr.Exists("lock:flight:1:seats") {
+				t.Error("seat lock not released")
+			}
+		})
+	}
+}
+
+func TestAdjustSeats(t *testing.T) {
+	const token = 100
+	tests := []struct {
+		name     string
+		locked   bool
+		affected int64
+		// fence and status of the flight when nothing was updated; no
+		// status means the flight does not exist
+		fence  int64
+		status string
+		want   error
+	}{
+		{name: "updated under lock", locked: true, affected: 1},
+		{name: "updated without lock", affected: 1},
+		{name: "stale fencing token", locked: true, fence: token + 1, status: "scheduled", want: redis.ErrLockLost},
+		{name: "sold out", locked: true, fence: token, status: "scheduled", want: ErrSoldOut},
+		{name: "sold out without lock", fence: token + 1, status: "scheduled", want: ErrSoldOut},
+		{name: "cancelled flight", locked: true, fence: token, status: "cancelled", want: ErrFlightCancelled},
+		{name: "unknown flight", locked: true, want: ErrFlightNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mock, _ := newSeatRepo(t)
+			mock.ExpectBegin()
+			tx, err := r.DB.Beginx()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tx.Rollback()
+
+			g := &seatGuard{ctx: context.Background(), locks: map[int]*redis.Lock{}}
+			if tt.locked {
+				g.locks[7] = &redis.Lock{Key: "flight:7:seats", Token: token}
+				mock.ExpectExec(`seat_fence = \$2`).WithArgs(3, int64(token), 7).WillReturnResult(sqlmock.NewResult(0, tt.affected))
+			} else {
+				mock.ExpectExec(updateSeats).WithArgs(3, 7).WillReturnResult(sqlmock.NewResult(0, tt.affected))
+			}
+			if tt.affected == 0 {
+				rows := sqlmock.NewRows([]string{"seat_fence", "status"})
+				if tt.status != "" {
+					rows.AddRow(tt.fence, tt.status)
+				}
+				mock.ExpectQuery(`SELECT seat_fence, status FROM flights`).WithArgs(7).WillReturnRows(rows)
+			}
+
+			if err := g.adjustSeats(tx, 7, 3); !errors.Is(err, tt.want) {
+				t.Errorf("adjustSeats error = %v, want %v", err, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package fare
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"airline-booking/internal/aircraft"
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/money"
+	"airline-booking/pkg/validate"
+)
+
+// Fare families
+const (
+	FamilyEconomy  = "economy"
+	FamilyPremium  = "premium"
+	FamilyBusiness = "business"
+)
+
+// cabins maps each fare family to the cabin it is flown in.
+var cabins = map[string]string{
+	FamilyEconomy:  aircraft.ClassEconomy,
+	FamilyPremium:  aircraft.ClassPremiumEconomy,
+	FamilyBusiness: aircraft.ClassBusiness,
+}
+
+var (
+	// ErrClassNotFound is returned when a flight has no booking class with the given code.
+	ErrClassNotFound = apperr.Validation("fare_class_not_found", "flight has no such booking class",
+		apperr.FieldError{Field: "fare_class", Message: "is not offered on this flight"})
+	// ErrClassClosed is returned when a booking class has fewer seats left than requested.
+	ErrClassClosed = apperr.SoldOut("fare_class_closed", "not enough seats left in this booking class")
+	// ErrClassInUse is returned when removing a booking class that has seats sold.
+	ErrClassInUse = apperr.Conflict("fare_class_in_use", "booking class has seats sold")
+)
+
+// Bucket is a booking class of a flight, e.g. Y or M, with its own price
+// and seat allocation.
+type Bucket struct {
+	FlightID int         `db:"flight_id" json:"-"`
+	Code     string      `db:"code" json:"code" validate:"required,max=2,code"`
+	Family   string      `db:"family" json:"family" validate:"required,oneof=economy premium business"`
+	Price    money.Money `db:"price" json:"price" validate:"gt=0"`
+	// Allocation is the nested booking limit: the most seats sold in this
+	// class and the cheaper classes of its family together
+	Allocation int `db:"allocation" json:"allocation" validate:"min=0"`
+	Sold       int `db:"sold" json:"sold"`
+	// Available is derived by Nest
+	Available int `db:"-" json:"available"`
+}
+
+func init() {
+	validate.Register(Bucket{})
+}
+
+// Cabin is the aircraft cabin the class is flown in.
+func (b Bucket) Cabin() string {
+	return cabins[b.Family]
+}
+
+// Nest sorts the buckets of a flight by family and from the most to the
+// least expensive, and works out the seats available in each. Classes are
+// nested: a sale in a class counts against its own limit and that of every
+// dearer class of the family, so the cheapest classes close first. No
+// class has more seats available than free, the seats left on the flight.
+func Nest(buckets []Bucket, free int) []Bucket {
+	slices.SortStableFunc(buckets, func(a, b Bucket) int {
+		return cmp.Or(cmp.Compare(a.Family, b.Family), cmp.Compare(b.Price.Amount, a.Price.Amount), cmp.Compare(a.Code, b.Code))
+	})
+
+	for start := 0; start < len(buckets); {
+		end := start
+		for end < len(buckets) && buckets[end].Family == buckets[start].Family {
+			end++
+		}
+		family := buckets[start:end]
+
+		// soldBelow[i] is what class i and its cheaper classes have sold
+		soldBelow := make([]int, len(family)+1)
+		for i := len(family) - 1; i >= 0; i-- {
+			soldBelow[i] = soldBelow[i+1] + family[i].Sold
+		}
+		for i := range family {
+			avail := min(family[i].Allocation-soldBelow[i], free)
+			if i > 0 {
+				avail = min(avail, family[i-1].Available)
+			}
+			family[i].Available = max(avail, 0)
+		}
+		start = end
+	}
+	return buckets
+}
+
+// LowestFares returns the cheapest fare still available in each cabin of a
+// flight with free seats left.
+func LowestFares(buckets []Bucket, free int) map[string]money.Money {
+	fares := map[string]money.Money{}
+	for _, b := range Nest(buckets, free) {
+		if b.Available == 0 {
+			continue
+		}
+		if price, ok := fares[b.Cabin()]; !ok || b.Price.Amount < price.Amount {
+			fares[b.Cabin()] = b.Price
+		}
+	}
+	return fares
+}
+
+// Buckets is the set of booking classes of a flight as sent by clients.
+type Buckets struct {
+	Buckets []Bucket `json:"buckets"`
+}
+
+// Validate checks every bucket, which the tag rules do not reach, and that
+// no code is used twice and all are priced in one currency.
+func (s Buckets) Validate() []apperr.FieldError {
+	var problems []apperr.FieldError
+	seen := map[string]bool{}
+	for i, b := range s.Buckets {
+		prefix := fmt.Sprintf("buckets[%d].", i)
+		for _, p := range validate.Struct(b) {
+			problems = append(problems, apperr.FieldError{Field: prefix + p.Field, Message: p.Message})
+		}
+		if seen[b.Code] {
+			problems = append(problems, apperr.FieldError{Field: prefix + "code", Message: fmt.Sprintf("%s is listed twice", b.Code)})
+		}
+		seen[b.Code] = true
+		if b.Price.Currency != s.Buckets[0].Price.Currency {
+			problems = append(problems, apperr.FieldError{Field: prefix + "price", Message: "must be in the currency of the other booking classes"})
+		}
+	}
+	return problems
+}
+
+// CheckCabins checks that no class is allocated more seats than its cabin
+// has, given the seats that can be sold in each cabin of the aircraft.
+func (s Buckets) CheckCabins(seats map[string]int) error {
+	var problems []apperr.FieldError
+	for i, b := range s.Buckets {
+		if n := seats[b.Cabin()]; b.Allocation > n {
+			problems = append(problems, apperr.FieldError{
+				Field:   fmt.Sprintf("buckets[%d].allocation", i),
+				Message: fmt.Sprintf("must be at most %d, the seats of the %s cabin", n, b.Cabin()),
+			})
+		}
+	}
+	if len(problems) > 0 {
+		return apperr.Validation("validation_failed", "request has invalid fields", problems...)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package fare
+
+import (
+	"errors"
+	"testing"
+
+	"airline-booking/internal/aircraft"
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/money"
+)
+
+func bucket(code, family string, price int64, allocation, sold int) Bucket {
+	return Bucket{Code: code, Family: family, Price: money.New(price, "INR"), Allocation: allocation, Sold: sold}
+}
+
+// available returns the seats available in each class by code.
+func available(buckets []Bucket) map[string]int {
+	got := map[string]int{}
+	for _, b := range buckets {
+		got[b.Code] = b.Available
+	}
+	return got
+}
+
+func TestNest(t *testing.T) {
+	tests := []struct {
+		name    string
+		buckets []Bucket
+		free    int
+		want    map[string]int
+	}{
+		{
+			name:    "nothing sold",
+			buckets: []Bucket{bucket("Q", FamilyEconomy, 3000, 20, 0), bucket("Y", FamilyEconomy, 9000, 100, 0), bucket("M", FamilyEconomy, 6000, 60, 0)},
+			free:    100,
+			want:    map[string]int{"Y": 100, "M": 60, "Q": 20},
+		},
+		{
+			name:    "sales count against dearer classes",
+			buckets: []Bucket{bucket("Y", FamilyEconomy, 9000, 100, 10), bucket("M", FamilyEconomy, 6000, 60, 30), bucket("Q", FamilyEconomy, 3000, 20, 20)},
+			free:    40,
+			want:    map[string]int{"Y": 40, "M": 10, "Q": 0},
+		},
+		{
+			name:    "a dearer class caps a cheaper one",
+			buckets: []Bucket{bucket("Y", FamilyEconomy, 9000, 10, 8), bucket("M", FamilyEconomy, 6000, 60, 0)},
+			free:    100,
+			want:    map[string]int{"Y": 2, "M": 2},
+		},
+		{
+			name:    "families are nested apart",
+			buckets: []Bucket{bucket("J", FamilyBusiness, 30000, 12, 12), bucket("Y", FamilyEconomy, 9000, 100, 0)},
+			free:    100,
+			want:    map[string]int{"J": 0, "Y": 100},
+		},
+		{
+			name:    "capped by the seats left on the flight",
+			buckets: []Bucket{bucket("J", FamilyBusiness, 30000, 12, 0), bucket("Y", FamilyEconomy, 9000, 100, 0), bucket("Q", FamilyEconomy, 3000, 20, 0)},
+			free:    5,
+			want:    map[string]int{"J": 5, "Y": 5, "Q": 5},
+		},
+		{
+			name:    "flight full",
+			buckets: []Bucket{bucket("Y", FamilyEconomy, 9000, 100, 0)},
+			want:    map[string]int{"Y": 0},
+		},
+		{
+			name:    "oversold allocation",
+			buckets: []Bucket{bucket("Y", FamilyEconomy, 9000, 10, 12)},
+			free:    50,
+			want:    map[string]int{"Y": 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Nest(tt.buckets, tt.free)
+			for code, want := range tt.want {
+				if available(got)[code] != want {
+					t.Errorf("available = %v, want %v", available(got), tt.want)
+					break
+				}
+			}
+			for i := 1; i < len(got); i++ {
+				if got[i].Family == got[i-1].Family && got[i].Price.Amount > got[i-1].Price.Amount {
+					t.Errorf("%s sorted after cheaper %s", got[i].Code, got[i-1].Code)
+				}
+			}
+		})
+	}
+}
+
+func TestLowestFares(t *testing.T) {
+	buckets := []Bucket{
+		bucket("J", FamilyBusiness, 30000, 12, 0),
+		bucket("Y", FamilyEconomy, 9000, 100, 0),
+		bucket("M", FamilyEconomy, 6000, 60, 0),
+		bucket("Q", FamilyEconomy, 3000, 20, 20),
+	}
+	fares := LowestFares(buckets, 50)
+	if len(fares) != 2 || fares[aircraft.ClassEconomy].Amount != 6000 || fares[aircraft.ClassBusiness].Amount != 30000 {
+		t.Errorf("LowestFares() = %v, want economy 6000 and business 30000", fares)
+	}
+	if fares := LowestFares(buckets, 0); len(fares) != 0 {
+		t.Errorf("LowestFares() on a full flight = %v, want none", fares)
+	}
+}
+
+func TestBucketsValidate(t *testing.T) {
+	s := Buckets{Buckets: []Bucket{
+		bucket("Y", FamilyEconomy, 9000, 100, 0),
+		bucket("Y", FamilyEconomy, 6000, 60, 0),
+		{Code: "q", Family: "cargo", Price: money.New(3000, "USD")},
+	}}
+	want := map[string]bool{"buckets[1].code": true, "buckets[2].code": true, "buckets[2].family": true, "buckets[2].price": true}
+	problems := s.Validate()
+	for _, p := range problems {
+		if !want[p.Field] {
+			t.Errorf("unexpected problem %v", p)
+		}
+		delete(want, p.Field)
+	}
+	if len(want) > 0 {
+		t.Errorf("missing problems with %v in %v", want, problems)
+	}
+}
+
+func TestCheckCabins(t *testing.T) {
+	seats := map[string]int{aircraft.ClassEconomy: 150, aircraft.ClassBusiness: 12}
+	tests := []struct {
+		name    string
+		buckets []Bucket
+		fields  []string
+	}{
+		{name: "within the cabins", buckets: []Bucket{bucket("J", FamilyBusiness, 30000, 12, 0), bucket("Y", FamilyEconomy, 9000, 150, 0)}},
+		{name: "more than the cabin", buckets: []Bucket{bucket("J", FamilyBusiness, 30000, 13, 0)}, fields: []string{"buckets[0].allocation"}},
+		{name: "cabin the aircraft lacks", buckets: []Bucket{bucket("Y", FamilyEconomy, 9000, 10, 0), bucket("W", FamilyPremium, 15000, 1, 0)},
+			fields: []string{"buckets[1].allocation"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Buckets{Buckets: tt.buckets}.CheckCabins(seats)
+			if len(tt.fields) == 0 {
+				if err != nil {
+					t.Fatalf("CheckCabins() = %v", err)
+				}
+				return
+			}
+			var e *apperr.Error
+			if !errors.As(err, &e) || e.Kind != apperr.KindValidation || len(e.Fields) != len(tt.fields) || e.Fields[0].Field != tt.fields[0] {
+				t.Fatalf("CheckCabins() = %#v, want problems with %v", err, tt.fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package fare
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"airline-booking/pkg/db"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const bucketColumns = `flight_id, code, family, price AS "price.amount", currency AS "price.currency", allocation, sold`
+
+type Repository struct {
+	DB       *sqlx.DB
+	Replicas *db.Cluster
+}
+
+func NewRepository(cluster *db.Cluster) *Repository {
+	return &Repository{DB: cluster.Primary, Replicas: cluster}
+}
+
+// Buckets returns the booking classes of a flight with their availability.
+func (r *Repository) Buckets(ctx context.Context, flightID int) ([]Bucket, error) {
+	reader := r.Replicas.Reader(ctx)
+	buckets := []Bucket{}
+	err := reader.SelectContext(ctx, &buckets, `SELECT `+bucketColumns+` FROM fare_buckets WHERE flight_id = $1`, flightID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch booking classes of flight %d: %w", flightID, err)
+	}
+	free, err := freeSeats(ctx, reader, flightID)
+	if err != nil {
+		return nil, err
+	}
+	return Nest(buckets, free), nil
+}
+
+// freeSeats returns the seats left on a flight.
+func freeSeats(ctx context.Context, q sqlx.QueryerContext, flightID int) (int, error) {
+	var free int
+	if err := sqlx.GetContext(ctx, q, &free, `SELECT available_seats FROM flights WHERE id = $1`, flightID); err != nil {
+		return 0, fmt.Errorf("failed to fetch seats left on flight %d: %w", flightID, err)
+	}
+	return free, nil
+}
+
+// ForFlights returns the booking classes of several flights by flight ID,
+// in one query.
+func (r *Repository) ForFlights(ctx context.Context, flightIDs []int) (map[int][]Bucket, error) {
+	var buckets []Bucket
+	err := r.Replicas.Reader(ctx).SelectContext(ctx, &buckets, `SELECT `+bucketColumns+` FROM fare_buckets WHERE flight_id = ANY($1)`, flightIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch booking classes: %w", err)
+	}
+	byFlight := map[int][]Bucket{}
+	for _, b := range buckets {
+		byFlight[b.FlightID] = append(byFlight[b.FlightID], b)
+	}
+	return byFlight, nil
+}
+
+// SaveBuckets replaces the booking classes of a flight. Seats already sold
+// in a class are kept; a class with seats sold cannot be removed.
+func (r *Repository) SaveBuckets(ctx context.Context, flightID int, buckets []Bucket) error {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	codes := make([]string, len(buckets))
+	for i, b := range buckets {
+		codes[i] = b.Code
+	}
+	var inUse []string
+	err = tx.SelectContext(ctx, &inUse, `
+		SELECT code FROM fare_buckets WHERE flight_id = $1 AND NOT code = ANY($2) AND sold > 0 FOR UPDATE`, flightID, codes)
+	if err != nil {
+		return fmt.Errorf("failed to check booking classes of flight %d: %w", flightID, err)
+	}
+	if len(inUse) > 0 {
+		return fmt.Errorf("%w: %s", ErrClassInUse, strings.Join(inUse, ", "))
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM fare_buckets WHERE flight_id = $1 AND NOT code = ANY($2)`, flightID, codes); err != nil {
+		return fmt.Errorf("failed to remove booking classes of flight %d: %w", flightID, err)
+	}
+
+	query := `
+		INSERT INTO fare_buckets (flight_id, code, family, price, currency, allocation)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (flight_id, code) DO UPDATE SET family = EXCLUDED.family, price = EXCLUDED.price, currency = EXCLUDED.currency,
+			allocation = EXCLUDED.allocation`
+	for _, b := range buckets {
+		if _, err := tx.ExecContext(ctx, query, flightID, b.Code, b.Family, b.Price.Amount, b.Price.Currency, b.Allocation); err != nil {
+			return fmt.Errorf("failed to save booking class %s of flight %d: %w", b.Code, flightID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	return nil
+}
+
+// Take sells seats in a booking class within tx and returns the class.
+// The caller holds the seat lock of the flight; the rows are locked as
+// well so concurrent sales are counted against the nested limits, and
+// no more seats are sold than the flight has left.
+func Take(ctx context.Context, tx *sqlx.Tx, flightID int, code string, seats int) (Bucket, error) {
+	var buckets []Bucket
+	err := tx.SelectContext(ctx, &buckets, `SELECT `+bucketColumns+` FROM fare_buckets WHERE flight_id = $1 FOR UPDATE`, flightID)
+	if err != nil {
+		return Bucket{}, fmt.Errorf("failed to fetch booking classes of flight %d: %w", flightID, err)
+	}
+	free, err := freeSeats(ctx, tx, flightID)
+	if err != nil {
+		return Bucket{}, err
+	}
+
+	i := slices.IndexFunc(Nest(buckets, free), func(b Bucket) bool { return b.Code == code })
+	if i < 0 {
+		return Bucket{}, ErrClassNotFound
+	}
+	b := buckets[i]
+	if b.Available < seats {
+		return b, ErrClassClosed
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE fare_buckets SET sold = sold + $1 WHERE flight_id = $2 AND code = $3`, seats, flightID, code); err != nil {
+		return b, fmt.Errorf("failed to sell booking class %s of flight %d: %w", code, flightID, err)
+	}
+	b.Sold += seats
+	b.Available -= seats
+	return b, nil
+}
+
+// Release returns seats sold in a booking class within tx. Classes removed
+// since are ignored.
+func Release(ctx context.Context, tx *sqlx.Tx, flightID int, code string, seats int) error {
+	_, err := tx.ExecContext(ctx, `UPDATE fare_buckets SET sold = GREATEST(sold - $1, 0) WHERE flight_id = $2 AND code = $3`, seats, flightID, code)
+	if err != nil {
+		return fmt.Errorf("failed to release booking class %s of flight %d: %w", code, flightID, err)
+	}
+	return nil
+}
+
+// CabinOf returns the cabin a booking class of a flight is flown in.
+func CabinOf(ctx context.Context, tx *sqlx.Tx, flightID int, code string) (string, error) {
+	var family string
+	err := tx.GetContext(ctx, &family, `SELECT family FROM fare_buckets WHERE flight_id = $1 AND code = $2`, flightID, code)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrClassNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch booking class %s of flight %d: %w", code, flightID, err)
+	}
+	return cabins[family], nil
+}
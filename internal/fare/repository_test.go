@@ -0,0 +1,66 @@
+package fare
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+func TestTake(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  string
+		seats int
+		free  int
+		want  error
+	}{
+		{name: "sold", code: "M", seats: 2, free: 100},
+		{name: "class closed", code: "Q", seats: 1, free: 100, want: ErrClassClosed},
+		{name: "more than the flight has left", code: "M", seats: 3, free: 2, want: ErrClassClosed},
+		{name: "unknown class", code: "Z", seats: 1, free: 100, want: ErrClassNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("sqlmock: %v", err)
+			}
+			defer mockDB.Close()
+			db := sqlx.NewDb(mockDB, "sqlmock")
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`FROM fare_buckets WHERE flight_id = $1 FOR UPDATE`)).WithArgs(3).WillReturnRows(
+				sqlmock.NewRows([]string{"flight_id", "code", "family", "price.amount", "price.currency", "allocation", "sold"}).
+					AddRow(3, "Y", FamilyEconomy, 9000, "INR", 100, 10).
+					AddRow(3, "M", FamilyEconomy, 6000, "INR", 60, 10).
+					AddRow(3, "Q", FamilyEconomy, 3000, "INR", 20, 20))
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT available_seats FROM flights WHERE id = $1`)).WithArgs(3).
+				WillReturnRows(sqlmock.NewRows([]string{"available_seats"}).AddRow(tt.free))
+			if tt.want == nil {
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE fare_buckets SET sold = sold + $1`)).WithArgs(tt.seats, 3, tt.code).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			mock.ExpectRollback()
+
+			tx, err := db.Beginx()
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := Take(context.Background(), tx, 3, tt.code, tt.seats)
+			tx.Rollback()
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Take() = %v, want %v", err, tt.want)
+			}
+			if err == nil && (b.Sold != 10+tt.seats || b.Price.Amount != 6000) {
+				t.Errorf("Take() = %+v", b)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package flight
+
+import "airline-booking/pkg/money"
+
+// Display shows the price and lowest fares of a flight in another
+// currency, at Rate.
+type Display struct {
+	Price       money.Money            `json:"price"`
+	LowestFares map[string]money.Money `json:"lowest_fares,omitempty"`
+	Rate        money.Rate             `json:"rate"`
+}
+
+// show fills in f.Display in currency, if given, at the current rates.
+func (h *Handler) show(f *Flight, currency string) error {
+	if currency == "" {
+		return nil
+	}
+	price, rate, err := h.Rates.Convert(f.Price, currency)
+	if err != nil {
+		return err
+	}
+	d := &Display{Price: price, Rate: rate}
+	if len(f.LowestFares) > 0 {
+		d.LowestFares = map[string]money.Money{}
+		for cabin, fare := range f.LowestFares {
+			// Booking classes may be priced in another currency than the flight
+			if d.LowestFares[cabin], _, err = h.Rates.Convert(fare, currency); err != nil {
+				return err
+			}
+		}
+	}
+	f.Display = d
+	return nil
+}
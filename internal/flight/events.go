@@ -0,0 +1,86 @@
+package flight
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Flight event types published to Kafka.
+const (
+	EventCreated     = "flight_created"
+	EventUpdated     = "flight_updated"
+	EventRescheduled = "flight_rescheduled"
+	EventDeleted     = "flight_deleted"
+	EventCancelled   = "flight_cancelled"
+)
+
+// Re-accommodation policies for the passengers of a cancelled flight.
+const (
+	// ReaccommodateAuto moves passengers to the next alternative flight
+	ReaccommodateAuto = "auto"
+	// ReaccommodateOffer only offers the alternative to passengers
+	ReaccommodateOffer = "offer"
+)
+
+// scheduleFields are the fields whose change affects passengers already booked.
+var scheduleFields = []string{"source", "destination", "departure", "arrival"}
+
+// Change is the old and new value of one field.
+type Change struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// UpdateEvent is published when a flight is changed. Changes is keyed by
+// the JSON name of each changed field.
+type UpdateEvent struct {
+	Flight  Flight            `json:"flight"`
+	Changes map[string]Change `json:"changes"`
+}
+
+// CancelEvent is published when a flight is cancelled.
+type CancelEvent struct {
+	Flight          Flight `json:"flight"`
+	Reason          string `json:"reason,omitempty"`
+	Reaccommodation string `json:"reaccommodation"`
+}
+
+// Diff lists the stored fields that differ between two versions of a
+// flight, by JSON name. Times are compared as instants, whatever their zone.
+func Diff(old, next Flight) map[string]Change {
+	changes := map[string]Change{}
+	a, b := reflect.ValueOf(old), reflect.ValueOf(next)
+	t := a.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if !t.Field(i).IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" {
+			name = t.Field(i).Name
+		}
+		if name == "id" || name == "-" || t.Field(i).Tag.Get("db") == "-" {
+			continue
+		}
+		x, y := a.Field(i).Interface(), b.Field(i).Interface()
+		if tx, ok := x.(time.Time); ok && tx.Equal(y.(time.Time)) {
+			continue
+		}
+		if x != y {
+			changes[name] = Change{Old: x, New: y}
+		}
+	}
+	return changes
+}
+
+// EventType is flight_rescheduled when any schedule field changed and
+// flight_updated otherwise.
+func (e UpdateEvent) EventType() string {
+	for _, field := range scheduleFields {
+		if _, ok := e.Changes[field]; ok {
+			return EventRescheduled
+		}
+	}
+	return EventUpdated
+}
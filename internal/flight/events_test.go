@@ -0,0 +1,101 @@
+package flight
+
+import (
+	"testing"
+	"time"
+
+	"airline-booking/internal/aircraft"
+	"airline-booking/pkg/money"
+)
+
+func TestDiff(t *testing.T) {
+	departure := time.Date(2026, 11, 2, 6, 30, 0, 0, time.UTC)
+	old := Flight{
+		ID:          1,
+		Airline:     "AI",
+		Source:      "DEL",
+		Destination: "BOM",
+		Departure:   departure,
+		Arrival:     departure.Add(2 * time.Hour),
+		Price:       money.New(450000, "INR"),
+		Aircraft:    "A320",
+	}
+
+	tests := []struct {
+		name   string
+		change func(f *Flight)
+		want   map[string]Change
+		event  string
+	}{
+		{
+			name:   "same instant in another zone",
+			change: func(f *Flight) { f.Departure = departure.In(time.FixedZone("IST", 5*3600+1800)) },
+			want:   map[string]Change{},
+			event:  EventUpdated,
+		},
+		{
+			name:   "aircraft swapped",
+			change: func(f *Flight) { f.Aircraft = "A321" },
+			want:   map[string]Change{"aircraft": {Old: "A320", New: "A321"}},
+			event:  EventUpdated,
+		},
+		{
+			name:   "retimed",
+			change: func(f *Flight) { f.Arrival = departure.Add(3 * time.Hour) },
+			want:   map[string]Change{"arrival": {Old: departure.Add(2 * time.Hour), New: departure.Add(3 * time.Hour)}},
+			event:  EventRescheduled,
+		},
+		{
+			name: "derived fields ignored",
+			change: func(f *Flight) {
+				f.ID = 2
+				f.BlockMinutes = 180
+				f.Display = &Display{}
+				f.LowestFares = map[string]money.Money{"economy": money.New(300000, "INR")}
+			},
+			want:  map[string]Change{},
+			event: EventUpdated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := old
+			tt.change(&next)
+			got := Diff(old, next)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Diff() = %v, want %v", got, tt.want)
+			}
+			for name, c := range tt.want {
+				g, ok := got[name]
+				if !ok {
+					t.Fatalf("Diff() = %v, want a change of %s", got, name)
+				}
+				if gt, ok := g.Old.(time.Time); ok {
+					if !gt.Equal(c.Old.(time.Time)) || !g.New.(time.Time).Equal(c.New.(time.Time)) {
+						t.Errorf("%s = %+v, want %+v", name, g, c)
+					}
+				} else if g != c {
+					t.Errorf("%s = %+v, want %+v", name, g, c)
+				}
+			}
+			if e := (UpdateEvent{Flight: next, Changes: got}).EventType(); e != tt.event {
+				t.Errorf("EventType() = %s, want %s", e, tt.event)
+			}
+		})
+	}
+}
+
+func TestDiffSkipsUnexportedFields(t *testing.T) {
+	old := Flight{ID: 1, Airline: "AI", AvailableSeats: 10}
+	next := old
+	next.AvailableSeats = 9
+	next.layout = &aircraft.Type{Code: "A320"}
+
+	changes := Diff(old, next)
+	if len(changes) != 1 {
+		t.Fatalf("Diff() = %v, want only available_seats", changes)
+	}
+	if c := changes["available_seats"]; c.Old != 10 || c.New != 9 {
+		t.Errorf("available_seats = %+v, want 10 to 9", c)
+	}
+}
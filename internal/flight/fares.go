@@ -0,0 +1,122 @@
+package flight
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"airline-booking/internal/fare"
+	"airline-booking/internal/pricing"
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/request"
+	"airline-booking/pkg/response"
+)
+
+// GetFares returns the booking classes of the flight given by the id path
+// parameter with the seats available in each.
+func (h *Handler) GetFares(w http.ResponseWriter, r *http.Request) {
+	id, ok := flightID(w, r)
+	if !ok {
+		return
+	}
+	if _, err := h.Repo.GetFlight(r.Context(), id); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	buckets, err := h.Fares.Buckets(r.Context(), id)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, fare.Buckets{Buckets: buckets})
+}
+
+// PutFares replaces the booking classes of the flight given by the id path
+// parameter. On a flight with an aircraft no class may be allocated more
+// seats than its cabin has.
+func (h *Handler) PutFares(w http.ResponseWriter, r *http.Request) {
+	id, ok := flightID(w, r)
+	if !ok {
+		return
+	}
+
+	var req fare.Buckets
+	if err := request.Decode(w, r, &req); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	f, err := h.Repo.GetFlight(r.Context(), id)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	if f.Aircraft != "" && h.Aircraft != nil {
+		t, err := h.Aircraft.Type(r.Context(), f.Aircraft)
+		if err != nil {
+			apperr.Write(w, r, err)
+			return
+		}
+		if err := req.CheckCabins(t.CabinSeats()); err != nil {
+			apperr.Write(w, r, err)
+			return
+		}
+	}
+
+	if err := h.Fares.SaveBuckets(r.Context(), id, req.Buckets); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	buckets, err := h.Fares.Buckets(r.Context(), id)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, fare.Buckets{Buckets: buckets})
+}
+
+// addLowestFares fills in the cheapest available fare per cabin of each
+// flight, at its live price when pricing rules are configured.
+func (h *Handler) addLowestFares(ctx context.Context, flights []Flight) error {
+	if len(flights) == 0 {
+		return nil
+	}
+	ids := make([]int, len(flights))
+	for i, f := range flights {
+		ids[i] = f.ID
+	}
+	byFlight, err := h.Fares.ForFlights(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	var (
+		rules pricing.Rules
+		conds map[int]pricing.Conditions
+	)
+	if h.Pricing != nil {
+		rs, err := h.Pricing.RuleSet(ctx)
+		if err != nil {
+			return err
+		}
+		rules = rs.Rules
+		if conds, err = pricing.FlightConditions(ctx, h.Pricing.Replicas.Reader(ctx), ids, time.Now(), rules.DemandWindow()); err != nil {
+			return err
+		}
+	}
+
+	for i := range flights {
+		buckets := byFlight[flights[i].ID]
+		if len(buckets) == 0 {
+			continue
+		}
+		fares := fare.LowestFares(buckets, flights[i].AvailableSeats)
+		if c, ok := conds[flights[i].ID]; ok {
+			for cabin, base := range fares {
+				fares[cabin], _, _ = rules.Price(base, c)
+			}
+		}
+		flights[i].LowestFares = fares
+	}
+	return nil
+}
@@ -2,10 +2,23 @@ package flight
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 
+	"airline-booking/internal/aircraft"
+	"airline-booking/internal/fare"
+	"airline-booking/internal/pricing"
+	"airline-booking/internal/reference"
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/db"
 	"airline-booking/pkg/kafka"
+	"airline-booking/pkg/money"
+	"airline-booking/pkg/request"
+	"airline-booking/pkg/response"
 )
 
 // Handler holds dependencies for flight HTTP routes.
@@ -13,55 +26,288 @@ type Handler struct {
 	Repo     *Repository
 	Producer *kafka.Producer
 	Topic    string
+	// Reference checks airlines and airports; nil accepts any code
+	Reference *reference.Repository
+	// Aircraft provides the seat layouts flights derive their inventory from
+	Aircraft *aircraft.Repository
+	// Fares holds the booking classes of each flight
+	Fares *fare.Repository
+	// Pricing turns fares into live prices; nil shows them as entered
+	Pricing *pricing.Repository
+	// Rates converts prices for display in other currencies
+	Rates *money.Rates
 }
 
 // NewHandler creates a new flight handler.
-func NewHandler(repo *Repository, producer *kafka.Producer, topic string) *Handler {
+func NewHandler(repo *Repository, producer *kafka.Producer, topic string, ref *reference.Repository, fleet *aircraft.Repository,
+	fares *fare.Repository, prices *pricing.Repository, rates *money.Rates) *Handler {
 	return &Handler{
-		Repo:     repo,
-		Producer: producer,
-		Topic:    topic,
+		Repo:      repo,
+		Producer:  producer,
+		Topic:     topic,
+		Reference: ref,
+		Aircraft:  fleet,
+		Fares:     fares,
+		Pricing:   prices,
+		Rates:     rates,
 	}
 }
 
-// GetFlights returns all available flights.
+// Routes registers the flight endpoints on mux.
+func (h *Handler) Routes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /flights", h.GetFlights)
+	mux.HandleFunc("POST /flights", h.AddFlight)
+	mux.HandleFunc("GET /flights/{id}", h.GetFlight)
+	mux.HandleFunc("PUT /flights/{id}", h.UpdateFlight)
+	mux.HandleFunc("PATCH /flights/{id}", h.PatchFlight)
+	mux.HandleFunc("DELETE /flights/{id}", h.DeleteFlight)
+	mux.HandleFunc("POST /flights/{id}/cancel", h.CancelFlight)
+	mux.HandleFunc("GET /flights/{id}/seats", h.GetSeatMap)
+	mux.HandleFunc("GET /flights/{id}/fares", h.GetFares)
+	mux.HandleFunc("PUT /flights/{id}/fares", h.PutFares)
+}
+
+// GetFlights returns all flights, or searches them when any of the source,
+// destination, date (YYYY-MM-DD, local at the departure airport),
+// departure_after or departure_before (RFC 3339) parameters is given.
+// Search results show the lowest available fare per cabin at its live price.
+// With currency, e.g. USD, prices are also shown converted at current rates.
 func (h *Handler) GetFlights(w http.ResponseWriter, r *http.Request) {
-	flights, err := h.Repo.GetAllFlights()
+	search, err := parseSearch(r)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	currency, err := request.Currency(r)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	var flights []Flight
+	if search.IsZero() {
+		flights, err = h.Repo.GetAllFlights(r.Context())
+	} else {
+		flights, err = h.Repo.SearchFlights(r.Context(), search)
+		if err == nil && h.Fares != nil {
+			err = h.addLowestFares(r.Context(), flights)
+		}
+	}
+	for i := 0; err == nil && i < len(flights); i++ {
+		err = h.show(&flights[i], currency)
+	}
+	if err != nil {
+		apperr.Write(w, r, fmt.Errorf("error fetching flights: %w", err))
+		return
+	}
+
+	response.JSON(w, http.StatusOK, flights)
+}
+
+// GetFlight returns the flight given by the id path parameter, with its
+// price also in the currency parameter if given.
+func (h *Handler) GetFlight(w http.ResponseWriter, r *http.Request) {
+	id, ok := flightID(w, r)
+	if !ok {
+		return
+	}
+	currency, err := request.Currency(r)
 	if err != nil {
-		http.Error(w, "Failed to fetch flights", http.StatusInternalServerError)
-		log.Printf("Error fetching flights: %v", err)
+		apperr.Write(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(flights)
+	f, err := h.Repo.GetFlight(r.Context(), id)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	if err := h.show(&f, currency); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, f)
 }
 
 // AddFlight adds a new flight and publishes an event to Kafka.
 func (h *Handler) AddFlight(w http.ResponseWriter, r *http.Request) {
 	var f Flight
-	if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	if err := request.Decode(w, r, &f); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	if err := h.checkReference(r.Context(), &f); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	if err := h.checkAircraft(r.Context(), &f); err != nil {
+		apperr.Write(w, r, err)
 		return
 	}
 
 	// Insert into Postgres
-	if err := h.Repo.AddFlight(f); err != nil {
-		http.Error(w, "Failed to add flight", http.StatusInternalServerError)
-		log.Printf("DB insert error: %v", err)
+	if err := h.Repo.AddFlight(&f); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	h.publish(EventCreated, f)
+
+	w.Header().Set("Location", fmt.Sprintf("/flights/%d", f.ID))
+	response.JSON(w, http.StatusCreated, f)
+}
+
+// UpdateFlight replaces the flight given by the id path parameter.
+func (h *Handler) UpdateFlight(w http.ResponseWriter, r *http.Request) {
+	id, ok := flightID(w, r)
+	if !ok {
+		return
+	}
+
+	var f Flight
+	if err := request.Decode(w, r, &f); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	f.ID = id
+
+	h.saveFlight(w, r, f)
+}
+
+// PatchFlight changes only the fields present in the request body.
+func (h *Handler) PatchFlight(w http.ResponseWriter, r *http.Request) {
+	id, ok := flightID(w, r)
+	if !ok {
 		return
 	}
 
-	// Publish Kafka event
-	eventData, _ := json.Marshal(f)
-	err := h.Producer.SendMessage(h.Topic, "flight_created", string(eventData))
+	// Read the primary so the patch applies to the latest version
+	f, err := h.Repo.GetFlight(db.WithPrimary(r.Context()), id)
 	if err != nil {
-		log.Printf("Failed to publish Kafka message: %v", err)
+		apperr.Write(w, r, err)
+		return
 	}
+	// Fields missing from the body keep their current values
+	if err := request.Decode(w, r, &f); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	f.ID = id
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Flight added successfully",
-	})
+	h.saveFlight(w, r, f)
+}
+
+// saveFlight stores f and publishes what changed, as flight_rescheduled if
+// the schedule moved so booking-service can follow up with passengers.
+func (h *Handler) saveFlight(w http.ResponseWriter, r *http.Request, f Flight) {
+	if err := h.checkReference(r.Context(), &f); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	if err := h.checkAircraft(r.Context(), &f); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	old, err := h.Repo.UpdateFlight(r.Context(), &f)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	if event := (UpdateEvent{Flight: f, Changes: Diff(old, f)}); len(event.Changes) > 0 {
+		h.publish(event.EventType(), event)
+	}
+	response.JSON(w, http.StatusOK, f)
+}
+
+// CancelFlight cancels the flight given by the id path parameter and
+// publishes flight_cancelled so booking-service moves or refunds its
+// passengers. The optional body gives a reason and the re-accommodation
+// policy, "auto" (default) or "offer".
+func (h *Handler) CancelFlight(w http.ResponseWriter, r *http.Request) {
+	id, ok := flightID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Reason          string `json:"reason"`
+		Reaccommodation string `json:"reaccommodation" validate:"oneof=auto offer"`
+	}
+	if err := request.Decode(w, r, &req); err != nil && !errors.Is(err, request.ErrEmptyBody) {
+		apperr.Write(w, r, err)
+		return
+	}
+	if req.Reaccommodation == "" {
+		req.Reaccommodation = ReaccommodateAuto
+	}
+
+	f, err := h.Repo.CancelFlight(r.Context(), id)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	h.publish(EventCancelled, CancelEvent{Flight: f, Reason: req.Reason, Reaccommodation: req.Reaccommodation})
+	response.JSON(w, http.StatusOK, f)
+}
+
+// DeleteFlight removes the flight given by the id path parameter.
+func (h *Handler) DeleteFlight(w http.ResponseWriter, r *http.Request) {
+	id, ok := flightID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Repo.DeleteFlight(r.Context(), id); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	h.publish(EventDeleted, Flight{ID: id})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func parseSearch(r *http.Request) (Search, error) {
+	q := r.URL.Query()
+	s := Search{Source: q.Get("source"), Destination: q.Get("destination")}
+
+	var problems []apperr.FieldError
+	parse := func(param, layout, hint string) time.Time {
+		if q.Get(param) == "" {
+			return time.Time{}
+		}
+		t, err := time.Parse(layout, q.Get(param))
+		if err != nil {
+			problems = append(problems, apperr.FieldError{Field: param, Message: "must be " + hint})
+		}
+		return t
+	}
+	s.Date = parse("date", time.DateOnly, "a date such as 2025-06-01")
+	s.After = parse("departure_after", time.RFC3339, "an RFC 3339 timestamp")
+	s.Before = parse("departure_before", time.RFC3339, "an RFC 3339 timestamp")
+
+	if len(problems) > 0 {
+		return s, apperr.Validation("invalid_query", "invalid search parameters", problems...)
+	}
+	return s, nil
+}
+
+// flightID parses the id path parameter, answering 400 if it is not a number.
+func flightID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Invalid("invalid flight id %q", r.PathValue("id")))
+		return 0, false
+	}
+	return id, true
+}
+
+// publish sends a flight event to Kafka
+func (h *Handler) publish(eventType string, event any) {
+	eventData, _ := json.Marshal(event)
+	if err := h.Producer.SendMessage(h.Topic, eventType, string(eventData)); err != nil {
+		log.Printf("Failed to publish Kafka message: %v", err)
+	}
 }
@@ -1,13 +1,113 @@
 package flight
 
-// Flight represents the structure of a flight record.
+import (
+	"strings"
+	"time"
+
+	"airline-booking/internal/aircraft"
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/money"
+	"airline-booking/pkg/timezone"
+	"airline-booking/pkg/validate"
+)
+
+// Flight statuses
+const (
+	StatusScheduled = "scheduled"
+	StatusCancelled = "cancelled"
+)
+
+// maxBlockTime is the longest gate-to-gate time accepted for a flight.
+const maxBlockTime = 24 * time.Hour
+
+// Flight represents the structure of a flight record. Departure and Arrival
+// are instants, read and written as RFC 3339 with an offset and shown in
+// the local time of their airport.
 type Flight struct {
-	ID             int     `json:"id"`
-	Airline        string  `json:"airline"`
-	Source         string  `json:"source"`
-	Destination    string  `json:"destination"`
-	Departure      string  `json:"departure"`
-	Arrival        string  `json:"arrival"`
-	Price          float64 `json:"price"`
-	AvailableSeats int     `json:"available_seats"`
+	ID             int         `db:"id" json:"id"`
+	Airline        string      `db:"airline" json:"airline" validate:"required,max=100"`
+	Source         string      `db:"source" json:"source" validate:"required,max=64"`
+	Destination    string      `db:"destination" json:"destination" validate:"required,max=64"`
+	Departure      time.Time   `db:"departure" json:"departure" validate:"required"`
+	Arrival        time.Time   `db:"arrival" json:"arrival" validate:"required"`
+	Price          money.Money `db:"price" json:"price" validate:"gt=0"`
+	AvailableSeats int         `db:"available_seats" json:"available_seats" validate:"min=0"`
+	// SourceTZ and DestinationTZ are the IANA time zones of the airports,
+	// UTC when unset
+	SourceTZ      string `db:"source_tz" json:"source_tz" validate:"timezone"`
+	DestinationTZ string `db:"destination_tz" json:"destination_tz" validate:"timezone"`
+	// Aircraft is the code of the aircraft type flying the flight. With one,
+	// Capacity and AvailableSeats are derived from its seat layout
+	Aircraft string `db:"aircraft" json:"aircraft,omitempty" validate:"max=10"`
+	Capacity int    `db:"capacity" json:"capacity,omitempty"`
+	// Status is managed by the service; it is ignored on create and update
+	Status string `db:"status" json:"status"`
+
+	// BlockMinutes and ArrivalDayOffset are derived from the schedule.
+	// ArrivalDayOffset is how many local calendar days after departure the
+	// flight lands, e.g. 1 overnight or -1 crossing the date line westwards.
+	BlockMinutes     int `db:"-" json:"block_minutes"`
+	ArrivalDayOffset int `db:"-" json:"arrival_day_offset"`
+	// LowestFares is the cheapest fare still available in each cabin, set
+	// in search results for flights with booking classes
+	LowestFares map[string]money.Money `db:"-" json:"lowest_fares,omitempty"`
+	// Display is set in responses shown in another currency
+	Display *Display `db:"-" json:"display,omitempty"`
+
+	// layout is the seating of Aircraft, set by checkAircraft
+	layout *aircraft.Type
+}
+
+func init() {
+	validate.Register(Flight{})
+}
+
+// Validate checks the rules that involve more than one field.
+func (f Flight) Validate() []apperr.FieldError {
+	var problems []apperr.FieldError
+	if f.Source != "" && strings.EqualFold(f.Source, f.Destination) {
+		problems = append(problems, apperr.FieldError{Field: "destination", Message: "must differ from source"})
+	}
+	if !f.Departure.IsZero() && !f.Arrival.IsZero() {
+		switch block := f.Arrival.Sub(f.Departure); {
+		case block <= 0:
+			problems = append(problems, apperr.FieldError{Field: "arrival", Message: "must be after departure"})
+		case block > maxBlockTime:
+			problems = append(problems, apperr.FieldError{Field: "arrival", Message: "must be within 24 hours of departure"})
+		}
+	}
+	return problems
+}
+
+// BlockTime is the scheduled time from departure to arrival.
+func (f *Flight) BlockTime() time.Duration {
+	return f.Arrival.Sub(f.Departure)
+}
+
+// localize shows the schedule in airport local time and fills in the
+// derived fields. Time zones are validated on input, so an unknown zone
+// can only come from old data and falls back to UTC.
+func (f *Flight) localize() {
+	f.Departure = f.Departure.In(timezone.Location(f.SourceTZ))
+	f.Arrival = f.Arrival.In(timezone.Location(f.DestinationTZ))
+	f.BlockMinutes = int(f.BlockTime().Minutes())
+	f.ArrivalDayOffset = daysBetween(f.Departure, f.Arrival)
+}
+
+// defaultZones sets the time zones left unset to UTC, so searches by local
+// date can convert every stored departure.
+func (f *Flight) defaultZones() {
+	if f.SourceTZ == "" {
+		f.SourceTZ = "UTC"
+	}
+	if f.DestinationTZ == "" {
+		f.DestinationTZ = "UTC"
+	}
+}
+
+// daysBetween counts calendar days from the local date of a to that of b.
+func daysBetween(a, b time.Time) int {
+	da := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	db := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(db.Sub(da).Hours() / 24)
 }
@@ -0,0 +1,59 @@
+package flight
+
+import (
+	"context"
+	"errors"
+
+	"airline-booking/internal/reference"
+	"airline-booking/pkg/apperr"
+)
+
+// checkReference resolves the airline and airports of f against the
+// reference data. Codes are normalized to IATA, ICAO codes being accepted
+// too, and the airport time zones are filled in. Unknown codes are
+// reported as field errors. Without reference data any code is accepted.
+func (h *Handler) checkReference(ctx context.Context, f *Flight) error {
+	ref := h.Reference
+	if ref == nil {
+		return nil
+	}
+	var problems []apperr.FieldError
+
+	airline, err := ref.Airline(ctx, f.Airline)
+	switch {
+	case errors.Is(err, reference.ErrAirlineNotFound):
+		problems = append(problems, apperr.FieldError{Field: "airline", Message: "is not a known airline designator"})
+	case err != nil:
+		return err
+	default:
+		f.Airline = airline.IATA
+	}
+
+	airport := func(field string, code, tz *string) error {
+		a, err := ref.Airport(ctx, *code)
+		if errors.Is(err, reference.ErrAirportNotFound) {
+			problems = append(problems, apperr.FieldError{Field: field, Message: "is not a known airport code"})
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		*code, *tz = a.IATA, a.Timezone
+		return nil
+	}
+	if err := airport("source", &f.Source, &f.SourceTZ); err != nil {
+		return err
+	}
+	if err := airport("destination", &f.Destination, &f.DestinationTZ); err != nil {
+		return err
+	}
+	// An IATA and an ICAO code can name the same airport
+	if len(problems) == 0 && f.Source == f.Destination {
+		problems = append(problems, apperr.FieldError{Field: "destination", Message: "must differ from source"})
+	}
+
+	if len(problems) > 0 {
+		return apperr.Validation("unknown_reference", "flight refers to unknown airline or airports", problems...)
+	}
+	return nil
+}
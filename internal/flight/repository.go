@@ -2,81 +2,330 @@ package flight
 
 import (
 	"context"
-	"encoding/json"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"airline-booking/internal/aircraft"
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/cache"
+	"airline-booking/pkg/db"
+
 	"github.com/jmoiron/sqlx"
-	"github.com/redis/go-redis/v9"
 )
 
+const (
+	flightsAllKey = "flights:all"
+	flightsTag    = "flights"
+	flightsTTL    = 10 * time.Minute
+	// notFoundTTL caches lookups of unknown flight IDs
+	notFoundTTL = time.Minute
+)
+
+var (
+	// ErrFlightNotFound is returned when no flight matches the given ID.
+	ErrFlightNotFound = apperr.NotFound("flight_not_found", "flight not found")
+	// ErrFlightHasBookings is returned when deleting a flight that still has active bookings.
+	ErrFlightHasBookings = apperr.Conflict("flight_has_bookings", "flight has active bookings")
+	// ErrFlightCancelled is returned when changing a flight that has been cancelled.
+	ErrFlightCancelled = apperr.Conflict("flight_cancelled", "flight is cancelled")
+	// ErrAircraftTooSmall is returned when the aircraft of a flight has
+	// fewer seats than are already booked.
+	ErrAircraftTooSmall = apperr.Conflict("aircraft_too_small", "aircraft has fewer seats than are booked")
+)
+
+const flightColumns = `id, airline, source, destination, departure, arrival, price AS "price.amount", currency AS "price.currency", available_seats, source_tz, destination_tz, aircraft, capacity, status`
+
+func flightKey(id int) string { return fmt.Sprintf("flight:%d", id) }
+
 type Repository struct {
-	DB    *sqlx.DB
-	Cache *redis.Client
+	DB       *sqlx.DB
+	Replicas *db.Cluster
+	Cache    *cache.Cache
+
+	ttl atomic.Int64
 }
 
-func NewRepository(db *sqlx.DB, cache *redis.Client) *Repository {
-	return &Repository{DB: db, Cache: cache}
+func NewRepository(cluster *db.Cluster, c *cache.Cache) *Repository {
+	return &Repository{DB: cluster.Primary, Replicas: cluster, Cache: c}
 }
 
-// GetAllFlights fetches all flights from the database.
-func (r *Repository) GetAllFlights() ([]Flight, error) {
-	ctx := context.Background()
-	cacheKey := "flights:all"
+// SetCacheTTL changes how long flight listings stay cached.
+func (r *Repository) SetCacheTTL(ttl time.Duration) {
+	r.ttl.Store(int64(ttl))
+}
 
-	// Check redis cache first
-	val, err := r.Cache.Get(ctx, cacheKey).Result()
-	if err == nil {
-		var cachedFlights []Flight
-		if jsonErr := json.Unmarshal([]byte(val), &cachedFlights); jsonErr == nil {
-			log.Printf("Flights fetched from cache")
-			return cachedFlights, nil
-		}
+func (r *Repository) cacheTTL() time.Duration {
+	if ttl := time.Duration(r.ttl.Load()); ttl > 0 {
+		return ttl
 	}
+	return flightsTTL
+}
 
-	// If not in cache, fetch from database
-	query := `SELECT id, airline, source, destination, departure, arrival, price, available_seats FROM flights`
-	rows, err := r.DB.Query(query)
-	if err != nil {
+// GetAllFlights fetches all flights, served from cache or a replica when possible.
+func (r *Repository) GetAllFlights(ctx context.Context) ([]Flight, error) {
+	opts := cache.Options{TTL: r.cacheTTL(), Tags: []string{flightsTag}, Refresh: db.PrimaryForced(ctx)}
+	return cache.GetOrLoad(ctx, r.Cache, flightsAllKey, opts, r.queryAllFlights)
+}
+
+// queryAllFlights loads all flights from the database.
+func (r *Repository) queryAllFlights(ctx context.Context) ([]Flight, error) {
+	var flights []Flight
+	if err := r.Replicas.Reader(ctx).SelectContext(ctx, &flights, `SELECT `+flightColumns+` FROM flights`); err != nil {
 		return nil, fmt.Errorf("failed to query flights: %v", err)
 	}
-	defer rows.Close()
+	for i := range flights {
+		flights[i].localize()
+	}
 
-	var flights []Flight
-	for rows.Next() {
+	slog.Debug("Flights fetched from Db")
+	return flights, nil
+}
+
+// Search filters flights. Date is a calendar day at the departure airport,
+// so a flight leaving at 23:30 local time is found on that day whatever its
+// UTC date, and one landing the next day or across the date line is still
+// found by the day it leaves.
+type Search struct {
+	Source      string
+	Destination string
+	Date        time.Time
+	After       time.Time
+	Before      time.Time
+}
+
+// IsZero reports whether the search has no filters.
+func (s Search) IsZero() bool {
+	return s == Search{}
+}
+
+// SearchFlights returns the scheduled flights matching s, earliest
+// departure first, read from a replica when possible.
+func (r *Repository) SearchFlights(ctx context.Context, s Search) ([]Flight, error) {
+	conds := []string{"status <> 'cancelled'"}
+	var args []any
+	where := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if s.Source != "" {
+		where("source = $%d", s.Source)
+	}
+	if s.Destination != "" {
+		where("destination = $%d", s.Destination)
+	}
+	if !s.Date.IsZero() {
+		where("(departure AT TIME ZONE source_tz)::date = $%d", s.Date.Format(time.DateOnly))
+	}
+	if !s.After.IsZero() {
+		where("departure >= $%d", s.After)
+	}
+	if !s.Before.IsZero() {
+		where("departure < $%d", s.Before)
+	}
+
+	query := `SELECT ` + flightColumns + ` FROM flights WHERE ` + strings.Join(conds, " AND ") + ` ORDER BY departure, id`
+	flights := []Flight{}
+	if err := r.Replicas.Reader(ctx).SelectContext(ctx, &flights, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to search flights: %w", err)
+	}
+	for i := range flights {
+		flights[i].localize()
+	}
+	return flights, nil
+}
+
+// GetFlight fetches a single flight, served from cache or a replica when possible.
+func (r *Repository) GetFlight(ctx context.Context, id int) (Flight, error) {
+	opts := cache.Options{TTL: r.cacheTTL(), NegativeTTL: notFoundTTL, Tags: []string{flightsTag}, Refresh: db.PrimaryForced(ctx)}
+	f, err := cache.GetOrLoad(ctx, r.Cache, flightKey(id), opts, func(ctx context.Context) (Flight, error) {
 		var f Flight
-		if err := rows.Scan(&f.ID, &f.Airline, &f.Source, &f.Destination, &f.Departure, &f.Arrival, &f.Price, &f.AvailableSeats); err != nil {
-			return nil, fmt.Errorf("failed to scan flight row: %v", err)
+		err := r.Replicas.Reader(ctx).GetContext(ctx, &f, `SELECT `+flightColumns+` FROM flights WHERE id = $1`, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return f, cache.ErrNotFound
 		}
-		flights = append(flights, f)
+		if err != nil {
+			return f, fmt.Errorf("failed to fetch flight %d: %w", id, err)
+		}
+		f.localize()
+		return f, nil
+	})
+	if errors.Is(err, cache.ErrNotFound) {
+		return f, ErrFlightNotFound
 	}
+	return f, err
+}
 
-	// Store the result in cache
-	data, _ := json.Marshal(flights)
-	if err := r.Cache.Set(ctx, cacheKey, data, 10*time.Minute).Err(); err != nil {
-		log.Printf("Redis cache set failed: %v", err)
+// AddFlight inserts a new flight into the database and sets its ID and status.
+func (r *Repository) AddFlight(f *Flight) error {
+	f.defaultZones()
+	query := `
+		INSERT INTO flights (airline, source, destination, departure, arrival, price, currency, available_seats, source_tz, destination_tz, aircraft, capacity)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, status`
+	err := r.DB.QueryRow(query, f.Airline, f.Source, f.Destination, f.Departure, f.Arrival, f.Price.Amount, f.Price.Currency, f.AvailableSeats,
+		f.SourceTZ, f.DestinationTZ, f.Aircraft, f.Capacity).Scan(&f.ID, &f.Status)
+	if err != nil {
+		return fmt.Errorf("failed to insert flight: %v", err)
 	}
+	f.localize()
 
-	log.Println("Flights fetched from Db and cached")
-	return flights, nil
+	r.invalidate("insert")
+	return nil
 }
 
-// AddFlight inserts a new flight into the database.
-func (r *Repository) AddFlight(f Flight) error {
+// UpdateFlight replaces every field of an existing flight except its status,
+// which f is given, and returns the flight as it was before. With an
+// aircraft, the seats left are its capacity less those booked; without
+// one they are kept as they are, since only bookings change them. The
+// flight row stays locked until commit, so bookings taking seats wait for
+// the count. Cancelled flights cannot be changed.
+func (r *Repository) UpdateFlight(ctx context.Context, f *Flight) (Flight, error) {
+	var old Flight
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return old, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.GetContext(ctx, &old, `SELECT `+flightColumns+` FROM flights WHERE id = $1 FOR UPDATE`, f.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return old, ErrFlightNotFound
+	}
+	if err != nil {
+		return old, fmt.Errorf("failed to fetch flight %d: %w", f.ID, err)
+	}
+	old.localize()
+	if old.Status == StatusCancelled {
+		return old, ErrFlightCancelled
+	}
+	f.Status = old.Status
+	f.defaultZones()
+	f.localize()
+
+	if err := checkSeats(ctx, tx, old, f); err != nil {
+		return old, err
+	}
+
+	if f.Capacity > 0 {
+		var booked int
+		err := tx.GetContext(ctx, &booked, `
+			SELECT COALESCE(SUM(seats), 0) FROM bookings WHERE flight_id = $1 AND status IN ('confirmed', 'held')`, f.ID)
+		if err != nil {
+			return old, fmt.Errorf("failed to count booked seats on flight %d: %w", f.ID, err)
+		}
+		if booked > f.Capacity {
+			return old, ErrAircraftTooSmall
+		}
+		f.AvailableSeats = f.Capacity - booked
+	} else {
+		f.AvailableSeats = old.AvailableSeats
+	}
+
 	query := `
-		INSERT INTO flights (airline, source, destination, departure, arrival, price, available_seats)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)`
-	_, err := r.DB.Exec(query, f.Airline, f.Source, f.Destination, f.Departure, f.Arrival, f.Price, f.AvailableSeats)
+		UPDATE flights SET airline = $1, source = $2, destination = $3, departure = $4, arrival = $5, price = $6, currency = $7,
+			available_seats = $8, source_tz = $9, destination_tz = $10, aircraft = $11, capacity = $12
+		WHERE id = $13`
+	_, err = tx.ExecContext(ctx, query, f.Airline, f.Source, f.Destination, f.Departure, f.Arrival, f.Price.Amount, f.Price.Currency,
+		f.AvailableSeats, f.SourceTZ, f.DestinationTZ, f.Aircraft, f.Capacity, f.ID)
 	if err != nil {
-		return fmt.Errorf("failed to insert flight: %v", err)
+		return old, fmt.Errorf("failed to update flight %d: %w", f.ID, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return old, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	r.invalidate("update")
+	return old, nil
+}
+
+// checkSeats rejects an aircraft change that leaves passengers of the
+// flight in seats its new aircraft, or the lack of one, does not have.
+func checkSeats(ctx context.Context, tx *sqlx.Tx, old Flight, f *Flight) error {
+	if old.Aircraft == "" || (f.Aircraft == old.Aircraft && f.layout == nil) {
+		return nil
+	}
+	var assigned []string
+	if err := tx.SelectContext(ctx, &assigned, `SELECT seat FROM seat_assignments WHERE flight_id = $1`, f.ID); err != nil {
+		return fmt.Errorf("failed to fetch seats of flight %d: %w", f.ID, err)
+	}
+	stranded := assigned
+	if f.layout != nil {
+		stranded = f.layout.Stranded(assigned)
+	}
+	if len(stranded) > 0 {
+		return fmt.Errorf("%w: %s", aircraft.ErrSeatsStranded, strings.Join(stranded, ", "))
 	}
+	return nil
+}
 
-	// Invalidate cache after insert
-	ctx := context.Background()
-	r.Cache.Del(ctx, "flights:all")
+// CancelFlight marks a flight as cancelled and returns it. Its bookings are
+// left for booking-service to re-accommodate.
+func (r *Repository) CancelFlight(ctx context.Context, id int) (Flight, error) {
+	var f Flight
+	query := `UPDATE flights SET status = $1 WHERE id = $2 AND status <> $1 RETURNING ` + flightColumns
+	err := r.DB.GetContext(ctx, &f, query, StatusCancelled, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		var exists bool
+		if err := r.DB.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM flights WHERE id = $1)`, id); err != nil {
+			return f, fmt.Errorf("failed to check flight %d: %w", id, err)
+		}
+		if exists {
+			return f, ErrFlightCancelled
+		}
+		return f, ErrFlightNotFound
+	}
+	if err != nil {
+		return f, fmt.Errorf("failed to cancel flight %d: %w", id, err)
+	}
+	f.localize()
+
+	r.invalidate("cancellation")
+	return f, nil
+}
 
-	log.Println("Redis cache invalidated after flight insert")
+// DeleteFlight removes a flight that has no confirmed or held bookings.
+func (r *Repository) DeleteFlight(ctx context.Context, id int) error {
+	query := `
+		DELETE FROM flights WHERE id = $1
+		AND NOT EXISTS (SELECT 1 FROM bookings WHERE flight_id = $1 AND status IN ('confirmed', 'held'))`
+	res, err := r.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete flight %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var exists bool
+		if err := r.DB.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM flights WHERE id = $1)`, id); err != nil {
+			return fmt.Errorf("failed to check flight %d: %w", id, err)
+		}
+		if exists {
+			return ErrFlightHasBookings
+		}
+		return ErrFlightNotFound
+	}
 
+	r.invalidate("delete")
 	return nil
 }
+
+// invalidate evicts every cached flight view after a write.
+func (r *Repository) invalidate(op string) {
+	if err := r.Cache.InvalidateTags(context.Background(), flightsTag); err == nil {
+		log.Printf("Redis cache invalidated after flight %s", op)
+	}
+}
+
+// OccupiedSeats returns the numbers of the seats taken on a flight.
+func (r *Repository) OccupiedSeats(ctx context.Context, flightID int) ([]string, error) {
+	seats := []string{}
+	err := r.Replicas.Reader(ctx).SelectContext(ctx, &seats, `SELECT seat FROM seat_assignments WHERE flight_id = $1`, flightID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch seats of flight %d: %w", flightID, err)
+	}
+	return seats, nil
+}
@@ -0,0 +1,169 @@
+package flight
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"airline-booking/internal/aircraft"
+	"airline-booking/pkg/cache"
+	"airline-booking/pkg/money"
+	"airline-booking/pkg/redis"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jmoiron/sqlx"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// newMockRepo returns a repository on a mock database, caching in a fake
+// Redis.
+func newMockRepo(t *testing.T) (*Repository, sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	t.Cleanup(func() { mockDB.Close() })
+
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return &Repository{DB: sqlx.NewDb(mockDB, "sqlmock"), Cache: cache.New(&redis.RedisClient{Client: client})}, mock
+}
+
+// flightRows returns f as a row of flightColumns.
+func flightRows(f Flight) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "airline", "source", "destination", "departure", "arrival", "price.amount", "price.currency",
+		"available_seats", "source_tz", "destination_tz", "aircraft", "capacity", "status"}).
+		AddRow(f.ID, f.Airline, f.Source, f.Destination, f.Departure, f.Arrival, f.Price.Amount, f.Price.Currency,
+			f.AvailableSeats, f.SourceTZ, f.DestinationTZ, f.Aircraft, f.Capacity, f.Status)
+}
+
+func TestUpdateFlightAvailableSeats(t *testing.T) {
+	departure := time.Date(2026, 11, 1, 8, 0, 0, 0, time.UTC)
+	stored := Flight{ID: 7, Airline: "Air", Source: "DEL", Destination: "BOM", Departure: departure, Arrival: departure.Add(2 * time.Hour),
+		Price: money.New(500000, "INR"), AvailableSeats: 40, SourceTZ: "UTC", DestinationTZ: "UTC", Status: StatusScheduled}
+
+	tests := []struct {
+		name     string
+		aircraft string
+		capacity int
+		// booked is counted only for flights with an aircraft
+		booked int
+		want   int
+		err    error
+	}{
+		{name: "without aircraft keeps the stored seats", want: 40},
+		{name: "with aircraft subtracts booked seats", aircraft: "A320", capacity: 180, booked: 30, want: 150},
+		{name: "aircraft smaller than bookings", aircraft: "ATR72", capacity: 70, booked: 71, err: ErrAircraftTooSmall},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mock := newMockRepo(t)
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT .* FROM flights WHERE id = \$1 FOR UPDATE`).WithArgs(stored.ID).WillReturnRows(flightRows(stored))
+			if tt.capacity > 0 {
+				mock.ExpectQuery(`SELECT COALESCE\(SUM\(seats\), 0\) FROM bookings`).WithArgs(stored.ID).
+					WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(tt.booked))
+			}
+			if tt.err == nil {
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE flights SET`)).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+						tt.want, sqlmock.AnyArg(), sqlmock.AnyArg(), tt.aircraft, tt.capacity, stored.ID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			f := stored
+			f.AvailableSeats = 500 // sent by the client, never written
+			f.Aircraft, f.Capacity = tt.aircraft, tt.capacity
+			_, err := r.UpdateFlight(context.Background(), &f)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("UpdateFlight() error = %v, want %v", err, tt.err)
+			}
+			if err == nil && f.AvailableSeats != tt.want {
+				t.Errorf("AvailableSeats = %d, want %d", f.AvailableSeats, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestAddFlightDefaultsTimeZones(t *testing.T) {
+	r, mock := newMockRepo(t)
+	departure := time.Date(2026, 11, 1, 8, 0, 0, 0, time.UTC)
+	f := Flight{Airline: "Air", Source: "DEL", Destination: "BOM", Departure: departure, Arrival: departure.Add(2 * time.Hour),
+		Price: money.New(500000, "INR"), AvailableSeats: 40, DestinationTZ: "Asia/Kolkata"}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO flights`)).
+		WithArgs(f.Airline, f.Source, f.Destination, f.Departure, f.Arrival, f.Price.Amount, f.Price.Currency, f.AvailableSeats,
+			"UTC", "Asia/Kolkata", "", 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(1, StatusScheduled))
+	if err := r.AddFlight(&f); err != nil {
+		t.Fatalf("AddFlight() = %v", err)
+	}
+	if f.SourceTZ != "UTC" || f.Arrival.Location().String() != "Asia/Kolkata" {
+		t.Errorf("zones = %s, %s", f.SourceTZ, f.Arrival.Location())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUpdateFlightStrandedSeats(t *testing.T) {
+	departure := time.Date(2026, 11, 1, 8, 0, 0, 0, time.UTC)
+	stored := Flight{ID: 7, Airline: "Air", Source: "DEL", Destination: "BOM", Departure: departure, Arrival: departure.Add(2 * time.Hour),
+		Price: money.New(500000, "INR"), AvailableSeats: 178, SourceTZ: "UTC", DestinationTZ: "UTC", Aircraft: "A320", Capacity: 180,
+		Status: StatusScheduled}
+	layout := func(lastRow int, blocked ...string) *aircraft.Type {
+		return &aircraft.Type{Code: "A321", Cabins: aircraft.Cabins{{Class: aircraft.ClassEconomy, FirstRow: 1, LastRow: lastRow, Letters: "ABC-DEF", Blocked: blocked}}}
+	}
+
+	tests := []struct {
+		name   string
+		layout *aircraft.Type
+		want   error
+	}{
+		{name: "new layout has the seats", layout: layout(30)},
+		{name: "new layout is shorter", layout: layout(20), want: aircraft.ErrSeatsStranded},
+		{name: "new layout blocks a seat", layout: layout(30, "1A"), want: aircraft.ErrSeatsStranded},
+		{name: "aircraft removed", want: aircraft.ErrSeatsStranded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, mock := newMockRepo(t)
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT .* FROM flights WHERE id = \$1 FOR UPDATE`).WithArgs(stored.ID).WillReturnRows(flightRows(stored))
+			mock.ExpectQuery(`SELECT seat FROM seat_assignments WHERE flight_id = \$1`).WithArgs(stored.ID).
+				WillReturnRows(sqlmock.NewRows([]string{"seat"}).AddRow("1A").AddRow("25F"))
+			if tt.want == nil {
+				mock.ExpectQuery(`SELECT COALESCE\(SUM\(seats\), 0\) FROM bookings`).WithArgs(stored.ID).
+					WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(2))
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE flights SET`)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			f := stored
+			f.Aircraft, f.Capacity, f.layout = "", 0, tt.layout
+			if tt.layout != nil {
+				f.Aircraft, f.Capacity = tt.layout.Code, tt.layout.Capacity()
+			}
+			if _, err := r.UpdateFlight(context.Background(), &f); !errors.Is(err, tt.want) {
+				t.Fatalf("UpdateFlight() error = %v, want %v", err, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
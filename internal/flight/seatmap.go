@@ -0,0 +1,80 @@
+package flight
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"airline-booking/internal/aircraft"
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/response"
+)
+
+// ErrNoSeatMap is returned for the seat map of a flight without an aircraft.
+var ErrNoSeatMap = apperr.NotFound("seat_map_not_found", "flight has no aircraft assigned")
+
+// SeatMap shows the seats of a flight and which of them are free.
+type SeatMap struct {
+	FlightID int            `json:"flight_id"`
+	Aircraft string         `json:"aircraft"`
+	Free     int            `json:"free"`
+	Rows     []aircraft.Row `json:"rows"`
+}
+
+// checkAircraft resolves the aircraft type of f and derives its capacity
+// from the seat layout. A new flight starts with every seat available;
+// on update the repository subtracts the seats already booked.
+func (h *Handler) checkAircraft(ctx context.Context, f *Flight) error {
+	if f.Aircraft == "" || h.Aircraft == nil {
+		f.Capacity = 0
+		return nil
+	}
+
+	t, err := h.Aircraft.Type(ctx, f.Aircraft)
+	if errors.Is(err, aircraft.ErrTypeNotFound) {
+		return apperr.Validation("unknown_reference", "flight refers to an unknown aircraft type",
+			apperr.FieldError{Field: "aircraft", Message: "is not a known aircraft type"})
+	}
+	if err != nil {
+		return err
+	}
+	f.Aircraft = t.Code
+	f.Capacity = t.Capacity()
+	f.layout = &t
+	f.AvailableSeats = f.Capacity
+	return nil
+}
+
+// GetSeatMap returns the seat map of the flight given by the id path
+// parameter.
+func (h *Handler) GetSeatMap(w http.ResponseWriter, r *http.Request) {
+	id, ok := flightID(w, r)
+	if !ok {
+		return
+	}
+
+	f, err := h.Repo.GetFlight(r.Context(), id)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	if f.Aircraft == "" || h.Aircraft == nil {
+		apperr.Write(w, r, ErrNoSeatMap)
+		return
+	}
+
+	t, err := h.Aircraft.Type(r.Context(), f.Aircraft)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	occupied, err := h.Repo.OccupiedSeats(r.Context(), id)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	m := SeatMap{FlightID: id, Aircraft: t.Code}
+	m.Rows, m.Free = t.Map(occupied)
+	response.JSON(w, http.StatusOK, m)
+}
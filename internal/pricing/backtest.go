@@ -0,0 +1,169 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"airline-booking/pkg/money"
+	"airline-booking/pkg/timezone"
+)
+
+// maxBacktestSamples bounds the bookings listed in a back-test result.
+const maxBacktestSamples = 100
+
+// BacktestBooking is one past booking priced under the candidate rules.
+type BacktestBooking struct {
+	BookingID  int                `json:"booking_id"`
+	FlightID   int                `json:"flight_id"`
+	BookedAt   time.Time          `json:"booked_at"`
+	Seats      int                `json:"seats"`
+	BaseFare   money.Money        `json:"base_fare"`
+	Paid       money.Money        `json:"paid"`
+	Price      money.Money        `json:"price"`
+	Multiplier float64            `json:"multiplier"`
+	Factors    map[string]float64 `json:"factors"`
+	Conditions Conditions         `json:"conditions"`
+}
+
+// BacktestResult compares what past bookings paid with what they would
+// have paid under the candidate rules and under the rules in force.
+// Only bookings in Currency are counted.
+type BacktestResult struct {
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Currency string    `json:"currency"`
+	Bookings int       `json:"bookings"`
+	Seats    int       `json:"seats"`
+	// Revenue actually paid, and under the current and candidate rules
+	ActualRevenue    money.Money `json:"actual_revenue"`
+	CurrentRevenue   money.Money `json:"current_revenue"`
+	CandidateRevenue money.Money `json:"candidate_revenue"`
+	// Change is the candidate revenue against the actual, in percent
+	Change            float64           `json:"change_percent"`
+	AverageMultiplier float64           `json:"average_multiplier"`
+	Samples           []BacktestBooking `json:"samples,omitempty"`
+}
+
+// Backtest prices the bookings in currency made between from and to under
+// candidate, rebuilding the conditions of each flight at the time of
+// booking from the bookings that still hold seats. It assumes the same
+// bookings would have been made at the new prices. Bookings start from the base fare of their recorded
+// quote, or what they paid per seat when there is none. Holds that never
+// became sales are left out.
+func (r *Repository) Backtest(ctx context.Context, candidate Rules, currency string, from, to time.Time) (BacktestResult, error) {
+	res := BacktestResult{
+		From: from, To: to, Currency: currency,
+		ActualRevenue:    money.New(0, currency),
+		CurrentRevenue:   money.New(0, currency),
+		CandidateRevenue: money.New(0, currency),
+	}
+	current, err := r.RuleSet(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	// The seats sold before each booking are summed over the history of its
+	// flight in one pass, counting the bookings that still hold seats
+	query := `
+		WITH history AS (
+			SELECT id, flight_id,
+				COALESCE(SUM(seats) FILTER (WHERE status IN ('confirmed', 'held')) OVER (
+					PARTITION BY flight_id ORDER BY booked_at RANGE BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW EXCLUDE GROUP), 0) AS sold_before,
+				COALESCE(SUM(seats) FILTER (WHERE status IN ('confirmed', 'held')) OVER (PARTITION BY flight_id), 0) AS booked
+			FROM bookings
+			WHERE flight_id IN (SELECT flight_id FROM bookings WHERE booked_at >= $1 AND booked_at < $2)
+		)
+		SELECT b.id, b.flight_id, b.seats, b.total_price, b.booked_at, f.departure, f.source_tz,
+			COALESCE(NULLIF(f.capacity, 0), f.available_seats + h.booked) AS capacity, h.sold_before,
+			COALESCE((SELECT q.base_fare FROM price_quotes q WHERE q.booking_id = b.id ORDER BY q.quoted_at DESC LIMIT 1),
+				ROUND(b.total_price::NUMERIC / b.seats)::BIGINT) AS base_fare
+		FROM bookings b JOIN flights f ON f.id = b.flight_id JOIN history h ON h.id = b.id
+		WHERE b.booked_at >= $1 AND b.booked_at < $2 AND b.seats > 0 AND b.status NOT IN ('held', 'expired') AND b.currency = $3
+		ORDER BY b.booked_at, b.id`
+	var rows []struct {
+		ID         int       `db:"id"`
+		FlightID   int       `db:"flight_id"`
+		Seats      int       `db:"seats"`
+		TotalPrice int64     `db:"total_price"`
+		BookedAt   time.Time `db:"booked_at"`
+		Departure  time.Time `db:"departure"`
+		SourceTZ   string    `db:"source_tz"`
+		Capacity   int       `db:"capacity"`
+		SoldBefore int       `db:"sold_before"`
+		BaseFare   int64     `db:"base_fare"`
+	}
+	if err := r.Replicas.Reader(ctx).SelectContext(ctx, &rows, query, from, to, currency); err != nil {
+		return res, fmt.Errorf("failed to read booking history: %w", err)
+	}
+
+	// Recent bookings per flight depend on each rule set's demand window
+	recent, err := r.bookingTimes(ctx, from.Add(-max(candidate.DemandWindow(), current.Rules.DemandWindow())), to)
+	if err != nil {
+		return res, err
+	}
+	countRecent := func(flightID int, at time.Time, window time.Duration) int {
+		n := 0
+		for _, t := range recent[flightID] {
+			if !t.Before(at.Add(-window)) && t.Before(at) {
+				n++
+			}
+		}
+		return n
+	}
+
+	var multipliers float64
+	for _, b := range rows {
+		base, paid := money.New(b.BaseFare, currency), money.New(b.TotalPrice, currency)
+		departure := b.Departure.In(timezone.Location(b.SourceTZ))
+		c := conditions(departure, b.BookedAt, b.SoldBefore, b.Capacity, countRecent(b.FlightID, b.BookedAt, candidate.DemandWindow()))
+		price, m, factors := candidate.Price(base, c)
+
+		cc := conditions(departure, b.BookedAt, b.SoldBefore, b.Capacity, countRecent(b.FlightID, b.BookedAt, current.Rules.DemandWindow()))
+		currentPrice, _, _ := current.Rules.Price(base, cc)
+
+		res.Bookings++
+		res.Seats += b.Seats
+		res.ActualRevenue.Amount += paid.Amount
+		res.CurrentRevenue.Amount += currentPrice.Times(b.Seats).Amount
+		res.CandidateRevenue.Amount += price.Times(b.Seats).Amount
+		multipliers += m
+		if len(res.Samples) < maxBacktestSamples {
+			res.Samples = append(res.Samples, BacktestBooking{
+				BookingID: b.ID, FlightID: b.FlightID, BookedAt: b.BookedAt, Seats: b.Seats, BaseFare: base,
+				Paid: paid, Price: price, Multiplier: m, Factors: factors, Conditions: c,
+			})
+		}
+	}
+
+	if res.Bookings > 0 {
+		res.AverageMultiplier = round(multipliers / float64(res.Bookings))
+	}
+	if res.ActualRevenue.Amount > 0 {
+		res.Change = round(float64(res.CandidateRevenue.Amount-res.ActualRevenue.Amount) / float64(res.ActualRevenue.Amount) * 100)
+	}
+	return res, nil
+}
+
+// bookingTimes returns when each booking between from and to was made, by flight.
+func (r *Repository) bookingTimes(ctx context.Context, from, to time.Time) (map[int][]time.Time, error) {
+	var rows []struct {
+		FlightID int       `db:"flight_id"`
+		BookedAt time.Time `db:"booked_at"`
+	}
+	err := r.Replicas.Reader(ctx).SelectContext(ctx, &rows, `
+		SELECT flight_id, booked_at FROM bookings WHERE booked_at >= $1 AND booked_at < $2`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read booking history: %w", err)
+	}
+	times := map[int][]time.Time{}
+	for _, row := range rows {
+		times[row.FlightID] = append(times[row.FlightID], row.BookedAt)
+	}
+	return times, nil
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,168 @@
+package pricing
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/money"
+	"airline-booking/pkg/request"
+	"airline-booking/pkg/response"
+)
+
+// maxQuotesListed bounds GET /pricing/quotes.
+const maxQuotesListed = 100
+
+// Handler serves the pricing rules, quotes and back-tests.
+type Handler struct {
+	Repo *Repository
+	// Rates converts quotes asked for in another currency
+	Rates *money.Rates
+}
+
+// NewHandler creates a new pricing handler.
+func NewHandler(repo *Repository, rates *money.Rates) *Handler {
+	return &Handler{Repo: repo, Rates: rates}
+}
+
+// Routes registers the pricing endpoints on mux.
+func (h *Handler) Routes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /pricing/rules", h.GetRules)
+	mux.HandleFunc("PUT /pricing/rules", h.PutRules)
+	mux.HandleFunc("GET /pricing/quotes", h.ListQuotes)
+	mux.HandleFunc("POST /pricing/quotes", h.AddQuote)
+	mux.HandleFunc("POST /pricing/backtest", h.Backtest)
+}
+
+// GetRules returns the rules in force.
+func (h *Handler) GetRules(w http.ResponseWriter, r *http.Request) {
+	rs, err := h.Repo.RuleSet(r.Context())
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, rs)
+}
+
+// PutRules puts a new version of the rules in force.
+func (h *Handler) PutRules(w http.ResponseWriter, r *http.Request) {
+	var rules Rules
+	if err := request.Decode(w, r, &rules); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	rs, err := h.Repo.SaveRules(r.Context(), rules)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, rs)
+}
+
+// AddQuote prices seats on a flight under the rules in force and records
+// the quote. With the currency parameter, e.g. USD, the quote is converted
+// at the current rate, which is recorded with it.
+func (h *Handler) AddQuote(w http.ResponseWriter, r *http.Request) {
+	currency, err := request.Currency(r)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	var req struct {
+		FlightID  int    `json:"flight_id" validate:"required,min=1"`
+		FareClass string `json:"fare_class" validate:"max=2,code"`
+		Seats     int    `json:"seats" validate:"min=1,max=9"`
+	}
+	if err := request.Decode(w, r, &req); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	reader := h.Repo.Replicas.Reader(ctx)
+	base, err := BaseFare(ctx, reader, req.FlightID, req.FareClass)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	quote, err := h.Repo.Quote(ctx, reader, req.FlightID, req.FareClass, req.Seats, base)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	if currency != "" {
+		rate, err := h.Rates.Rate(quote.Price.Currency, currency)
+		if err == nil {
+			err = quote.Convert(rate)
+		}
+		if err != nil {
+			apperr.Write(w, r, err)
+			return
+		}
+	}
+	if err := Record(ctx, h.Repo.DB, &quote); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusCreated, quote)
+}
+
+// ListQuotes returns the latest quotes of the flight given by the
+// flight_id query parameter.
+func (h *Handler) ListQuotes(w http.ResponseWriter, r *http.Request) {
+	flightID, err := strconv.Atoi(r.URL.Query().Get("flight_id"))
+	if err != nil {
+		apperr.Write(w, r, apperr.Validation("invalid_query", "invalid flight_id",
+			apperr.FieldError{Field: "flight_id", Message: "is required and must be a number"}))
+		return
+	}
+
+	quotes, err := h.Repo.Quotes(r.Context(), flightID, maxQuotesListed)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, quotes)
+}
+
+// Backtest prices the bookings of a past period under candidate rules.
+func (h *Handler) Backtest(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Rules Rules     `json:"rules"`
+		From  time.Time `json:"from" validate:"required"`
+		To    time.Time `json:"to" validate:"required"`
+		// Currency selects the bookings counted, by default those in
+		// money.DefaultCurrency
+		Currency string `json:"currency" validate:"len=3"`
+	}
+	if err := request.Decode(w, r, &req); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	problems := req.Rules.Validate()
+	for i := range problems {
+		problems[i].Field = "rules." + problems[i].Field
+	}
+	if !req.To.After(req.From) {
+		problems = append(problems, apperr.FieldError{Field: "to", Message: "must be after from"})
+	}
+	if req.Currency == "" {
+		req.Currency = money.DefaultCurrency
+	} else if !money.Valid(req.Currency) {
+		problems = append(problems, apperr.FieldError{Field: "currency", Message: "must be an ISO 4217 currency code, e.g. INR"})
+	}
+	if len(problems) > 0 {
+		apperr.Write(w, r, apperr.Validation("validation_failed", "request has invalid fields", problems...))
+		return
+	}
+
+	res, err := h.Repo.Backtest(r.Context(), req.Rules, req.Currency, req.From, req.To)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,230 @@
+// Package pricing computes live fares by applying configurable rules to a
+// base fare, records every price quoted and back-tests rule changes
+// against past bookings.
+package pricing
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/jsonb"
+	"airline-booking/pkg/money"
+	"airline-booking/pkg/validate"
+)
+
+// Rule names, used as keys of Quote.Factors
+const (
+	FactorLoad    = "load_factor"
+	FactorDays    = "days_to_departure"
+	FactorWeekday = "day_of_week"
+	FactorDemand  = "demand"
+	FactorBounds  = "bounds"
+)
+
+// defaultDemandWindow is the period recent bookings are counted over
+// unless the rules say otherwise.
+const defaultDemandWindow = 24 * time.Hour
+
+// Step applies Multiplier from From upwards, until the next step.
+type Step struct {
+	From       float64 `json:"from"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// Rules turn the conditions of a flight into a multiplier of the base fare.
+// Every rule is optional; with none the base fare is charged.
+type Rules struct {
+	// LoadFactor steps by the share of seats sold, 0 to 1
+	LoadFactor []Step `json:"load_factor,omitempty"`
+	// DaysToDeparture steps by the days left before departure
+	DaysToDeparture []Step `json:"days_to_departure,omitempty"`
+	// DayOfWeek multiplies by the local weekday of departure, e.g. "friday": 1.1
+	DayOfWeek map[string]float64 `json:"day_of_week,omitempty"`
+	// Demand steps by the bookings made on the flight in the last
+	// DemandWindowHours, 24 by default
+	Demand            []Step `json:"demand,omitempty"`
+	DemandWindowHours int    `json:"demand_window_hours,omitempty" validate:"min=0,max=720"`
+	// MinMultiplier and MaxMultiplier bound the combined multiplier; 0 is
+	// no bound
+	MinMultiplier float64 `json:"min_multiplier,omitempty" validate:"min=0"`
+	MaxMultiplier float64 `json:"max_multiplier,omitempty" validate:"min=0"`
+}
+
+func init() {
+	validate.Register(Rules{})
+}
+
+// DemandWindow is the period recent bookings are counted over.
+func (r Rules) DemandWindow() time.Duration {
+	if r.DemandWindowHours == 0 {
+		return defaultDemandWindow
+	}
+	return time.Duration(r.DemandWindowHours) * time.Hour
+}
+
+// Value implements driver.Valuer.
+func (r Rules) Value() (driver.Value, error) {
+	return jsonb.Value(r)
+}
+
+// Scan implements sql.Scanner.
+func (r *Rules) Scan(src any) error {
+	return jsonb.Scan(src, r)
+}
+
+// Conditions describe a flight at the moment a price is quoted.
+type Conditions struct {
+	LoadFactor      float64      `json:"load_factor"`
+	DaysToDeparture float64      `json:"days_to_departure"`
+	Weekday         time.Weekday `json:"-"`
+	RecentBookings  int          `json:"recent_bookings"`
+}
+
+// Multiplier combines the rules that apply to c and returns the multiplier
+// of each, by rule name.
+func (r Rules) Multiplier(c Conditions) (float64, map[string]float64) {
+	factors := map[string]float64{}
+	if m, ok := step(r.LoadFactor, c.LoadFactor); ok {
+		factors[FactorLoad] = m
+	}
+	if m, ok := step(r.DaysToDeparture, c.DaysToDeparture); ok {
+		factors[FactorDays] = m
+	}
+	if m, ok := r.DayOfWeek[strings.ToLower(c.Weekday.String())]; ok {
+		factors[FactorWeekday] = m
+	}
+	if m, ok := step(r.Demand, float64(c.RecentBookings)); ok {
+		factors[FactorDemand] = m
+	}
+
+	total := 1.0
+	for _, m := range factors {
+		total *= m
+	}
+	bounded := total
+	if r.MinMultiplier > 0 {
+		bounded = max(bounded, r.MinMultiplier)
+	}
+	if r.MaxMultiplier > 0 {
+		bounded = min(bounded, r.MaxMultiplier)
+	}
+	if bounded != total {
+		factors[FactorBounds] = bounded / total
+	}
+	return bounded, factors
+}
+
+// step returns the multiplier of the last step starting at or below v.
+func step(steps []Step, v float64) (float64, bool) {
+	i := len(steps) - 1
+	for i >= 0 && steps[i].From > v {
+		i--
+	}
+	if i < 0 {
+		return 0, false
+	}
+	return steps[i].Multiplier, true
+}
+
+// Validate checks the steps and multipliers, which the tag rules do not reach.
+func (r Rules) Validate() []apperr.FieldError {
+	var problems []apperr.FieldError
+	checkSteps := func(field string, steps []Step) {
+		for i, s := range steps {
+			switch {
+			case s.Multiplier <= 0:
+				problems = append(problems, apperr.FieldError{Field: fmt.Sprintf("%s[%d].multiplier", field, i), Message: "must be greater than 0"})
+			case i > 0 && s.From <= steps[i-1].From:
+				problems = append(problems, apperr.FieldError{Field: fmt.Sprintf("%s[%d].from", field, i), Message: "must be greater than the previous step"})
+			}
+		}
+	}
+	checkSteps(FactorLoad, r.LoadFactor)
+	checkSteps(FactorDays, r.DaysToDeparture)
+	checkSteps(FactorDemand, r.Demand)
+	if n := len(r.LoadFactor); n > 0 && (r.LoadFactor[0].From < 0 || r.LoadFactor[n-1].From > 1) {
+		problems = append(problems, apperr.FieldError{Field: FactorLoad, Message: "steps must start between 0 and 1"})
+	}
+
+	weekdays := make([]string, 7)
+	for d := range weekdays {
+		weekdays[d] = strings.ToLower(time.Weekday(d).String())
+	}
+	for day, m := range r.DayOfWeek {
+		if !slices.Contains(weekdays, day) {
+			problems = append(problems, apperr.FieldError{Field: FactorWeekday, Message: fmt.Sprintf("%q is not a weekday, e.g. monday", day)})
+		} else if m <= 0 {
+			problems = append(problems, apperr.FieldError{Field: FactorWeekday + "." + day, Message: "must be greater than 0"})
+		}
+	}
+	if r.MaxMultiplier > 0 && r.MaxMultiplier < r.MinMultiplier {
+		problems = append(problems, apperr.FieldError{Field: "max_multiplier", Message: "must not be below min_multiplier"})
+	}
+	return problems
+}
+
+// Quote is a price offered for a flight.
+type Quote struct {
+	ID         int64       `db:"id" json:"id,omitempty"`
+	FlightID   int         `db:"flight_id" json:"flight_id"`
+	FareClass  string      `db:"fare_class" json:"fare_class,omitempty"`
+	Seats      int         `db:"seats" json:"seats"`
+	BaseFare   money.Money `db:"base_fare" json:"base_fare"`
+	Multiplier float64     `db:"multiplier" json:"multiplier"`
+	// Price is per seat; Total is for every seat
+	Price      money.Money `db:"price" json:"price"`
+	Total      money.Money `db:"-" json:"total"`
+	Factors    Factors     `db:"factors" json:"factors"`
+	Conditions Conditions  `db:"-" json:"conditions"`
+	BookingID  *int        `db:"booking_id" json:"booking_id,omitempty"`
+	QuotedAt   time.Time   `db:"quoted_at" json:"quoted_at"`
+	// ExchangeRate is the rate the quote was converted at when asked for
+	// in another currency, and Display the quote at that rate
+	ExchangeRate *money.Rate `db:"exchange_rate" json:"exchange_rate,omitempty"`
+	Display      *Display    `db:"-" json:"display,omitempty"`
+}
+
+// Display shows the price of a quote in another currency, at Rate.
+type Display struct {
+	Price money.Money `json:"price"`
+	Total money.Money `json:"total"`
+	Rate  money.Rate  `json:"rate"`
+}
+
+// Convert locks rate into the quote and shows the quote at it.
+func (q *Quote) Convert(rate money.Rate) error {
+	d := Display{Rate: rate}
+	var err error
+	if d.Price, err = rate.Convert(q.Price); err != nil {
+		return err
+	}
+	if d.Total, err = rate.Convert(q.Total); err != nil {
+		return err
+	}
+	q.ExchangeRate, q.Display = &rate, &d
+	return nil
+}
+
+// Factors are the multipliers of the rules that applied, stored as JSON.
+type Factors map[string]float64
+
+// Value implements driver.Valuer.
+func (f Factors) Value() (driver.Value, error) {
+	return jsonb.Value(f)
+}
+
+// Scan implements sql.Scanner.
+func (f *Factors) Scan(src any) error {
+	return jsonb.Scan(src, f)
+}
+
+// Price applies the rules to a base fare under the given conditions,
+// rounding half to even to the minor unit of its currency.
+func (r Rules) Price(base money.Money, c Conditions) (price money.Money, multiplier float64, factors map[string]float64) {
+	multiplier, factors = r.Multiplier(c)
+	return base.Mul(multiplier), multiplier, factors
+}
@@ -0,0 +1,155 @@
+package pricing
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"airline-booking/pkg/money"
+)
+
+func TestStep(t *testing.T) {
+	steps := []Step{{From: 0, Multiplier: 1}, {From: 0.5, Multiplier: 1.2}, {From: 0.8, Multiplier: 1.5}}
+	tests := []struct {
+		v    float64
+		want float64
+		ok   bool
+	}{
+		{v: 0, want: 1, ok: true},
+		{v: 0.49, want: 1, ok: true},
+		{v: 0.5, want: 1.2, ok: true},
+		{v: 0.99, want: 1.5, ok: true},
+		{v: -1},
+	}
+	for _, tt := range tests {
+		got, ok := step(steps, tt.v)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("step(%v) = %v, %v, want %v, %v", tt.v, got, ok, tt.want, tt.ok)
+		}
+	}
+	if _, ok := step(nil, 1); ok {
+		t.Error("step with no steps applied")
+	}
+}
+
+func TestMultiplier(t *testing.T) {
+	rules := Rules{
+		LoadFactor:      []Step{{From: 0, Multiplier: 1}, {From: 0.5, Multiplier: 1.2}, {From: 0.8, Multiplier: 1.5}},
+		DaysToDeparture: []Step{{From: 0, Multiplier: 1.3}, {From: 7, Multiplier: 1.1}, {From: 30, Multiplier: 0.9}},
+		DayOfWeek:       map[string]float64{"friday": 1.1},
+		Demand:          []Step{{From: 10, Multiplier: 1.05}},
+	}
+	tests := []struct {
+		name    string
+		rules   Rules
+		c       Conditions
+		want    float64
+		factors map[string]float64
+	}{
+		{
+			name:    "no rules",
+			c:       Conditions{LoadFactor: 0.9},
+			want:    1,
+			factors: map[string]float64{},
+		},
+		{
+			name:    "every rule applies",
+			rules:   rules,
+			c:       Conditions{LoadFactor: 0.85, DaysToDeparture: 10, Weekday: time.Friday, RecentBookings: 12},
+			want:    1.5 * 1.1 * 1.1 * 1.05,
+			factors: map[string]float64{FactorLoad: 1.5, FactorDays: 1.1, FactorWeekday: 1.1, FactorDemand: 1.05},
+		},
+		{
+			name:    "below the first demand step",
+			rules:   rules,
+			c:       Conditions{LoadFactor: 0.1, DaysToDeparture: 45, Weekday: time.Monday, RecentBookings: 3},
+			want:    0.9,
+			factors: map[string]float64{FactorLoad: 1, FactorDays: 0.9},
+		},
+		{
+			name:    "capped",
+			rules:   Rules{LoadFactor: rules.LoadFactor, DaysToDeparture: rules.DaysToDeparture, MaxMultiplier: 1.6},
+			c:       Conditions{LoadFactor: 0.85, DaysToDeparture: 2},
+			want:    1.6,
+			factors: map[string]float64{FactorLoad: 1.5, FactorDays: 1.3, FactorBounds: 1.6 / (1.5 * 1.3)},
+		},
+		{
+			name:    "floored",
+			rules:   Rules{DaysToDeparture: rules.DaysToDeparture, MinMultiplier: 0.95},
+			c:       Conditions{DaysToDeparture: 60},
+			want:    0.95,
+			factors: map[string]float64{FactorDays: 0.9, FactorBounds: 0.95 / 0.9},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, factors := tt.rules.Multiplier(tt.c)
+			if !near(got, tt.want) {
+				t.Errorf("Multiplier() = %v, want %v", got, tt.want)
+			}
+			if len(factors) != len(tt.factors) {
+				t.Fatalf("factors = %v, want %v", factors, tt.factors)
+			}
+			for name, m := range tt.factors {
+				if !near(factors[name], m) {
+					t.Errorf("factors[%s] = %v, want %v", name, factors[name], m)
+				}
+			}
+		})
+	}
+}
+
+func TestPriceRoundsHalfToEven(t *testing.T) {
+	rules := Rules{LoadFactor: []Step{{From: 0, Multiplier: 1.5}}}
+	tests := []struct {
+		base, want int64
+	}{
+		{100001, 150002},
+		{100003, 150004},
+		{100000, 150000},
+	}
+	for _, tt := range tests {
+		price, _, _ := rules.Price(money.New(tt.base, "INR"), Conditions{})
+		if price != money.New(tt.want, "INR") {
+			t.Errorf("Price(%d) = %v, want %d", tt.base, price, tt.want)
+		}
+	}
+}
+
+func TestRulesValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules Rules
+		want  []string
+	}{
+		{name: "valid", rules: Rules{
+			LoadFactor: []Step{{From: 0, Multiplier: 1}, {From: 0.8, Multiplier: 1.4}},
+			DayOfWeek:  map[string]float64{"sunday": 1.2},
+		}},
+		{name: "steps out of order", rules: Rules{DaysToDeparture: []Step{{From: 7, Multiplier: 1}, {From: 7, Multiplier: 1.2}}},
+			want: []string{"days_to_departure[1].from"}},
+		{name: "multiplier not positive", rules: Rules{Demand: []Step{{From: 1, Multiplier: 0}}},
+			want: []string{"demand[0].multiplier"}},
+		{name: "load factor above 1", rules: Rules{LoadFactor: []Step{{From: 0, Multiplier: 1}, {From: 80, Multiplier: 1.4}}},
+			want: []string{FactorLoad}},
+		{name: "unknown weekday", rules: Rules{DayOfWeek: map[string]float64{"fri": 1.1}}, want: []string{FactorWeekday}},
+		{name: "bounds reversed", rules: Rules{MinMultiplier: 1.2, MaxMultiplier: 1.1}, want: []string{"max_multiplier"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := tt.rules.Validate()
+			if len(problems) != len(tt.want) {
+				t.Fatalf("Validate() = %v, want fields %v", problems, tt.want)
+			}
+			for i, p := range problems {
+				if p.Field != tt.want[i] {
+					t.Errorf("problems[%d].Field = %q, want %q", i, p.Field, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
@@ -0,0 +1,211 @@
+package pricing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/cache"
+	"airline-booking/pkg/db"
+	"airline-booking/pkg/money"
+	"airline-booking/pkg/ruleset"
+	"airline-booking/pkg/timezone"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	rulesKey = "pricing:rules"
+	// quoteValidity is how long the exchange rate of a quote can be booked at
+	quoteValidity = 30 * time.Minute
+)
+
+var (
+	// ErrFlightNotFound is returned when pricing an unknown flight.
+	ErrFlightNotFound = apperr.NotFound("flight_not_found", "flight not found")
+	// ErrClassNotFound is returned when pricing a booking class the flight does not offer.
+	ErrClassNotFound = apperr.Validation("fare_class_not_found", "flight has no such booking class",
+		apperr.FieldError{Field: "fare_class", Message: "is not offered on this flight"})
+	// ErrQuoteNotFound is returned when booking from a quote that is not a
+	// recent one of the flight.
+	ErrQuoteNotFound = apperr.Validation("quote_not_found", "quote not found or expired",
+		apperr.FieldError{Field: "quote_id", Message: "is not a recent quote of this flight"})
+)
+
+// RuleSet is a saved version of the rules. The latest version is in force.
+type RuleSet = ruleset.Set[Rules]
+
+type Repository struct {
+	DB       *sqlx.DB
+	Replicas *db.Cluster
+	Cache    *cache.Cache
+}
+
+func NewRepository(cluster *db.Cluster, c *cache.Cache) *Repository {
+	return &Repository{DB: cluster.Primary, Replicas: cluster, Cache: c}
+}
+
+// RuleSet returns the rules in force. Before any are saved the base fare
+// is charged as it is.
+func (r *Repository) RuleSet(ctx context.Context) (RuleSet, error) {
+	return r.ruleStore().Latest(ctx)
+}
+
+// SaveRules puts a new version of the rules in force.
+func (r *Repository) SaveRules(ctx context.Context, rules Rules) (RuleSet, error) {
+	return r.ruleStore().Save(ctx, rules)
+}
+
+func (r *Repository) ruleStore() ruleset.Store[Rules] {
+	return ruleset.Store[Rules]{Cluster: r.Replicas, Cache: r.Cache, Table: "pricing_rules", Key: rulesKey}
+}
+
+// FlightConditions reads the state of the given flights at time at, in one query.
+// Flights that do not exist are left out.
+func FlightConditions(ctx context.Context, q sqlx.QueryerContext, flightIDs []int, at time.Time, window time.Duration) (map[int]Conditions, error) {
+	query := `
+		SELECT f.id, f.departure, f.source_tz, f.available_seats, f.capacity,
+			COALESCE(SUM(b.seats) FILTER (WHERE b.status IN ('confirmed', 'held')), 0) AS booked,
+			COUNT(b.id) FILTER (WHERE b.booked_at >= $2) AS recent
+		FROM flights f LEFT JOIN bookings b ON b.flight_id = f.id
+		WHERE f.id = ANY($1)
+		GROUP BY f.id`
+	var rows []struct {
+		ID        int       `db:"id"`
+		Departure time.Time `db:"departure"`
+		SourceTZ  string    `db:"source_tz"`
+		Available int       `db:"available_seats"`
+		Capacity  int       `db:"capacity"`
+		Booked    int       `db:"booked"`
+		Recent    int       `db:"recent"`
+	}
+	if err := sqlx.SelectContext(ctx, q, &rows, query, flightIDs, at.Add(-window)); err != nil {
+		return nil, fmt.Errorf("failed to read flight conditions: %w", err)
+	}
+
+	conds := make(map[int]Conditions, len(rows))
+	for _, f := range rows {
+		capacity := f.Capacity
+		if capacity == 0 {
+			capacity = f.Booked + f.Available
+		}
+		conds[f.ID] = conditions(f.Departure.In(timezone.Location(f.SourceTZ)), at, capacity-f.Available, capacity, f.Recent)
+	}
+	return conds, nil
+}
+
+// conditions derives the pricing conditions of a flight departing at
+// departure, in local time, with sold of its capacity seats sold.
+func conditions(departure, at time.Time, sold, capacity, recent int) Conditions {
+	c := Conditions{
+		DaysToDeparture: max(departure.Sub(at).Hours()/24, 0),
+		Weekday:         departure.Weekday(),
+		RecentBookings:  recent,
+	}
+	if capacity > 0 {
+		c.LoadFactor = math.Min(max(float64(sold)/float64(capacity), 0), 1)
+	}
+	return c
+}
+
+// BaseFare returns the fare a quote starts from: the price of the booking
+// class, or the flight's price without one.
+func BaseFare(ctx context.Context, q sqlx.QueryerContext, flightID int, fareClass string) (money.Money, error) {
+	var (
+		base  money.Money
+		err   error
+		notOK = ErrFlightNotFound
+	)
+	if fareClass == "" {
+		err = sqlx.GetContext(ctx, q, &base, `SELECT price AS amount, currency FROM flights WHERE id = $1`, flightID)
+	} else {
+		err = sqlx.GetContext(ctx, q, &base, `SELECT price AS amount, currency FROM fare_buckets WHERE flight_id = $1 AND code = $2`, flightID, fareClass)
+		notOK = ErrClassNotFound
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return base, notOK
+	}
+	if err != nil {
+		return base, fmt.Errorf("failed to fetch base fare of flight %d: %w", flightID, err)
+	}
+	return base, nil
+}
+
+// Quote prices seats on a flight from base under the rules in force. The
+// conditions are read through q so a booking prices the flight as its
+// transaction sees it. The quote is not recorded.
+func (r *Repository) Quote(ctx context.Context, q sqlx.QueryerContext, flightID int, fareClass string, seats int, base money.Money) (Quote, error) {
+	rs, err := r.RuleSet(ctx)
+	if err != nil {
+		return Quote{}, err
+	}
+	conds, err := FlightConditions(ctx, q, []int{flightID}, time.Now(), rs.Rules.DemandWindow())
+	if err != nil {
+		return Quote{}, err
+	}
+	c, ok := conds[flightID]
+	if !ok {
+		return Quote{}, ErrFlightNotFound
+	}
+
+	quote := Quote{FlightID: flightID, FareClass: fareClass, Seats: seats, BaseFare: base, Conditions: c, QuotedAt: time.Now()}
+	var factors map[string]float64
+	quote.Price, quote.Multiplier, factors = rs.Rules.Price(base, c)
+	quote.Factors = factors
+	quote.Total = quote.Price.Times(seats)
+	return quote, nil
+}
+
+// Record stores a quote through q, setting its ID.
+func Record(ctx context.Context, q sqlx.QueryerContext, quote *Quote) error {
+	query := `
+		INSERT INTO price_quotes (flight_id, fare_class, seats, base_fare, multiplier, price, currency, factors, booking_id, quoted_at, exchange_rate)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id`
+	err := sqlx.GetContext(ctx, q, &quote.ID, query, quote.FlightID, quote.FareClass, quote.Seats, quote.BaseFare.Amount, quote.Multiplier,
+		quote.Price.Amount, quote.Price.Currency, quote.Factors, quote.BookingID, quote.QuotedAt, quote.ExchangeRate)
+	if err != nil {
+		return fmt.Errorf("failed to record quote: %w", err)
+	}
+	return nil
+}
+
+// QuotedRate returns the exchange rate of a quote of a flight made in the
+// last 30 minutes, or nil if it was not converted.
+func QuotedRate(ctx context.Context, q sqlx.QueryerContext, quoteID int64, flightID int) (*money.Rate, error) {
+	var rate *money.Rate
+	query := `SELECT exchange_rate FROM price_quotes WHERE id = $1 AND flight_id = $2 AND quoted_at >= $3`
+	err := sqlx.GetContext(ctx, q, &rate, query, quoteID, flightID, time.Now().Add(-quoteValidity))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrQuoteNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch quote %d: %w", quoteID, err)
+	}
+	return rate, nil
+}
+
+// Quotes lists the latest quotes of a flight, newest first.
+func (r *Repository) Quotes(ctx context.Context, flightID, limit int) ([]Quote, error) {
+	quotes := []Quote{}
+	query := `
+		SELECT id, flight_id, fare_class, seats, base_fare AS "base_fare.amount", currency AS "base_fare.currency", multiplier,
+			price AS "price.amount", currency AS "price.currency", factors, booking_id, quoted_at, exchange_rate
+		FROM price_quotes WHERE flight_id = $1 ORDER BY quoted_at DESC, id DESC LIMIT $2`
+	if err := r.Replicas.Reader(ctx).SelectContext(ctx, &quotes, query, flightID, limit); err != nil {
+		return nil, fmt.Errorf("failed to fetch quotes of flight %d: %w", flightID, err)
+	}
+	for i := range quotes {
+		quotes[i].Total = quotes[i].Price.Times(quotes[i].Seats)
+		if rate := quotes[i].ExchangeRate; rate != nil {
+			if err := quotes[i].Convert(*rate); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return quotes, nil
+}
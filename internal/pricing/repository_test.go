@@ -0,0 +1,45 @@
+package pricing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConditions(t *testing.T) {
+	at := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		departure time.Time
+		sold      int
+		capacity  int
+		want      Conditions
+	}{
+		{
+			name:      "half full",
+			departure: at.Add(36 * time.Hour),
+			sold:      90,
+			capacity:  180,
+			want:      Conditions{LoadFactor: 0.5, DaysToDeparture: 1.5, Weekday: time.Wednesday, RecentBookings: 4},
+		},
+		{
+			name:      "departed and overbooked",
+			departure: at.Add(-time.Hour),
+			sold:      200,
+			capacity:  180,
+			want:      Conditions{LoadFactor: 1, Weekday: time.Monday, RecentBookings: 4},
+		},
+		{
+			name:      "no capacity",
+			departure: at.Add(24 * time.Hour),
+			sold:      10,
+			want:      Conditions{DaysToDeparture: 1, Weekday: time.Tuesday, RecentBookings: 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := conditions(tt.departure, at, tt.sold, tt.capacity, 4); got != tt.want {
+				t.Errorf("conditions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package reference
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/request"
+	"airline-booking/pkg/response"
+)
+
+// maxImportBytes bounds the size of a CSV upload.
+const maxImportBytes = 32 << 20
+
+// Handler serves the airport and airline reference data.
+type Handler struct {
+	Repo *Repository
+}
+
+// NewHandler creates a new reference data handler.
+func NewHandler(repo *Repository) *Handler {
+	return &Handler{Repo: repo}
+}
+
+// Routes registers the reference data endpoints on mux.
+func (h *Handler) Routes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /airports", h.ListAirports)
+	mux.HandleFunc("GET /airports/{code}", h.GetAirport)
+	mux.HandleFunc("PUT /airports/{code}", h.PutAirport)
+	mux.HandleFunc("POST /airports/import", h.importCSV(h.Repo.ImportAirports))
+	mux.HandleFunc("GET /airlines", h.ListAirlines)
+	mux.HandleFunc("GET /airlines/{code}", h.GetAirline)
+	mux.HandleFunc("PUT /airlines/{code}", h.PutAirline)
+	mux.HandleFunc("POST /airlines/import", h.importCSV(h.Repo.ImportAirlines))
+}
+
+// ListAirports returns all airports, filtered by the country query parameter if given.
+func (h *Handler) ListAirports(w http.ResponseWriter, r *http.Request) {
+	airports, err := h.Repo.ListAirports(r.Context(), r.URL.Query().Get("country"))
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, airports)
+}
+
+// GetAirport returns the airport given by its IATA or ICAO code.
+func (h *Handler) GetAirport(w http.ResponseWriter, r *http.Request) {
+	a, err := h.Repo.Airport(r.Context(), r.PathValue("code"))
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, a)
+}
+
+// PutAirport creates or replaces the airport given by its IATA code.
+func (h *Handler) PutAirport(w http.ResponseWriter, r *http.Request) {
+	a := Airport{IATA: strings.ToUpper(r.PathValue("code"))}
+	if err := request.Decode(w, r, &a); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	a.IATA = strings.ToUpper(r.PathValue("code"))
+
+	if err := h.Repo.SaveAirports(r.Context(), []Airport{a}); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, a)
+}
+
+// ListAirlines returns all airlines.
+func (h *Handler) ListAirlines(w http.ResponseWriter, r *http.Request) {
+	airlines, err := h.Repo.ListAirlines(r.Context())
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, airlines)
+}
+
+// GetAirline returns the airline given by its IATA or ICAO designator.
+func (h *Handler) GetAirline(w http.ResponseWriter, r *http.Request) {
+	a, err := h.Repo.Airline(r.Context(), r.PathValue("code"))
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, a)
+}
+
+// PutAirline creates or replaces the airline given by its IATA designator.
+func (h *Handler) PutAirline(w http.ResponseWriter, r *http.Request) {
+	a := Airline{IATA: strings.ToUpper(r.PathValue("code"))}
+	if err := request.Decode(w, r, &a); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	a.IATA = strings.ToUpper(r.PathValue("code"))
+
+	if err := h.Repo.SaveAirlines(r.Context(), []Airline{a}); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, a)
+}
+
+// importCSV serves a bulk import of a CSV request body and answers with
+// the rows imported and skipped.
+func (h *Handler) importCSV(run func(context.Context, io.Reader) (ImportResult, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res, err := run(r.Context(), http.MaxBytesReader(w, r.Body, maxImportBytes))
+		if err != nil {
+			apperr.Write(w, r, err)
+			return
+		}
+		response.JSON(w, http.StatusOK, res)
+	}
+}
@@ -0,0 +1,166 @@
+package reference
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/validate"
+)
+
+// RowError is a CSV row that was skipped.
+type RowError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// ImportResult reports what a bulk import did.
+type ImportResult struct {
+	Imported int        `json:"imported"`
+	Skipped  []RowError `json:"skipped,omitempty"`
+}
+
+// ImportAirports reads airports from CSV with a header row of
+// iata,icao,name,city,country,timezone,latitude,longitude in any order
+// (icao and the coordinates are optional) and saves every valid row;
+// invalid rows are skipped and reported.
+func (r *Repository) ImportAirports(ctx context.Context, in io.Reader) (ImportResult, error) {
+	var airports []Airport
+	res, err := readCSV(in, []string{"iata", "name", "city", "country", "timezone"}, func(row map[string]string) (err error) {
+		a := Airport{
+			IATA:     strings.ToUpper(row["iata"]),
+			ICAO:     strings.ToUpper(row["icao"]),
+			Name:     row["name"],
+			City:     row["city"],
+			Country:  strings.ToUpper(row["country"]),
+			Timezone: row["timezone"],
+		}
+		if a.Latitude, err = parseFloat(row, "latitude"); err != nil {
+			return err
+		}
+		if a.Longitude, err = parseFloat(row, "longitude"); err != nil {
+			return err
+		}
+		if err := check(a); err != nil {
+			return err
+		}
+		airports = append(airports, a)
+		return nil
+	})
+	if err != nil {
+		return res, err
+	}
+
+	if err := r.SaveAirports(ctx, airports); err != nil {
+		return res, err
+	}
+	res.Imported = len(airports)
+	return res, nil
+}
+
+// ImportAirlines reads airlines from CSV with a header row of
+// iata,icao,name,country in any order (icao and country are optional) and
+// saves every valid row; invalid rows are skipped and reported.
+func (r *Repository) ImportAirlines(ctx context.Context, in io.Reader) (ImportResult, error) {
+	var airlines []Airline
+	res, err := readCSV(in, []string{"iata", "name"}, func(row map[string]string) error {
+		a := Airline{
+			IATA:    strings.ToUpper(row["iata"]),
+			ICAO:    strings.ToUpper(row["icao"]),
+			Name:    row["name"],
+			Country: strings.ToUpper(row["country"]),
+		}
+		if err := check(a); err != nil {
+			return err
+		}
+		airlines = append(airlines, a)
+		return nil
+	})
+	if err != nil {
+		return res, err
+	}
+
+	if err := r.SaveAirlines(ctx, airlines); err != nil {
+		return res, err
+	}
+	res.Imported = len(airlines)
+	return res, nil
+}
+
+// readCSV calls row for every record keyed by header, collecting the rows
+// that are malformed or rejected. It fails only if the header is unusable
+// or the input cannot be read.
+func readCSV(in io.Reader, required []string, row func(map[string]string) error) (ImportResult, error) {
+	var res ImportResult
+	cr := csv.NewReader(in)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return res, apperr.Invalid("cannot read CSV header: %v", err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+	for _, col := range required {
+		if !slices.Contains(header, col) {
+			return res, apperr.Invalid("CSV header is missing the %s column", col)
+		}
+	}
+
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return res, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			res.Skipped = append(res.Skipped, RowError{Line: parseErr.StartLine, Message: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return res, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+
+		values := make(map[string]string, len(header))
+		for i, col := range header {
+			if i < len(record) {
+				values[col] = strings.TrimSpace(record[i])
+			}
+		}
+		if err := row(values); err != nil {
+			res.Skipped = append(res.Skipped, RowError{Line: line, Message: err.Error()})
+		}
+	}
+}
+
+// check validates one imported record and describes its problems.
+func check(v any) error {
+	problems := validate.Struct(v)
+	if len(problems) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(problems))
+	for i, p := range problems {
+		msgs[i] = p.Field + " " + p.Message
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
+
+func parseFloat(row map[string]string, col string) (float64, error) {
+	if row[col] == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(row[col], 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number, got %q", col, row[col])
+	}
+	return f, nil
+}
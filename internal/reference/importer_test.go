@@ -0,0 +1,65 @@
+package reference
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"airline-booking/pkg/apperr"
+)
+
+func TestReadCSV(t *testing.T) {
+	in := strings.Join([]string{
+		" IATA,Name ,country",
+		"AI, Air India ,IN",
+		"6E,,IN",
+		"UK,Vistara",
+		`QP,"Akasa "Air",IN`,
+		"SG,SpiceJet,IN",
+	}, "\n")
+
+	var names []string
+	res, err := readCSV(strings.NewReader(in), []string{"iata", "name"}, func(row map[string]string) error {
+		if row["name"] == "" {
+			return errors.New("name is required")
+		}
+		names = append(names, row["iata"]+":"+row["name"])
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"AI:Air India", "SG:SpiceJet"}; !slices.Equal(names, want) {
+		t.Errorf("rows = %v, want %v", names, want)
+	}
+	var lines []int
+	for _, s := range res.Skipped {
+		lines = append(lines, s.Line)
+	}
+	// The empty name, the short row and the stray quote
+	if want := []int{3, 4, 5}; !slices.Equal(lines, want) {
+		t.Errorf("skipped lines = %v, want %v (%+v)", lines, want, res.Skipped)
+	}
+}
+
+func TestReadCSVHeader(t *testing.T) {
+	tests := []struct {
+		name, in string
+	}{
+		{"empty", ""},
+		{"missing column", "iata,country\nAI,IN\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readCSV(strings.NewReader(tt.in), []string{"iata", "name"}, func(map[string]string) error {
+				t.Fatal("row called without a usable header")
+				return nil
+			})
+			var ae *apperr.Error
+			if !errors.As(err, &ae) || ae.Kind != apperr.KindValidation {
+				t.Errorf("readCSV() = %v, want a validation error", err)
+			}
+		})
+	}
+}
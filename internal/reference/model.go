@@ -0,0 +1,36 @@
+package reference
+
+import (
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/validate"
+)
+
+// Airport is an airport known by its IATA code, e.g. DEL.
+type Airport struct {
+	IATA      string  `db:"iata" json:"iata" validate:"required,len=3,code"`
+	ICAO      string  `db:"icao" json:"icao" validate:"len=4,code"`
+	Name      string  `db:"name" json:"name" validate:"required,max=200"`
+	City      string  `db:"city" json:"city" validate:"required,max=100"`
+	Country   string  `db:"country" json:"country" validate:"required,len=2,code"`
+	Timezone  string  `db:"timezone" json:"timezone" validate:"required,timezone"`
+	Latitude  float64 `db:"latitude" json:"latitude" validate:"min=-90,max=90"`
+	Longitude float64 `db:"longitude" json:"longitude" validate:"min=-180,max=180"`
+}
+
+// Airline is an airline known by its IATA designator, e.g. AI.
+type Airline struct {
+	IATA    string `db:"iata" json:"iata" validate:"required,len=2,code"`
+	ICAO    string `db:"icao" json:"icao" validate:"len=3,code"`
+	Name    string `db:"name" json:"name" validate:"required,max=200"`
+	Country string `db:"country" json:"country" validate:"len=2,code"`
+}
+
+func init() {
+	validate.Register(Airport{}, Airline{})
+}
+
+// Errors returned by lookups
+var (
+	ErrAirportNotFound = apperr.NotFound("airport_not_found", "airport not found")
+	ErrAirlineNotFound = apperr.NotFound("airline_not_found", "airline not found")
+)
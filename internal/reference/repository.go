@@ -0,0 +1,152 @@
+package reference
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"airline-booking/pkg/cache"
+	"airline-booking/pkg/db"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// referenceTag groups every cached reference entry; imports evict it
+	referenceTag = "reference"
+	referenceTTL = time.Hour
+	notFoundTTL  = time.Minute
+)
+
+const (
+	airportColumns = `iata, COALESCE(icao, '') AS icao, name, city, country, timezone, latitude, longitude`
+	airlineColumns = `iata, COALESCE(icao, '') AS icao, name, country`
+)
+
+type Repository struct {
+	DB       *sqlx.DB
+	Replicas *db.Cluster
+	Cache    *cache.Cache
+}
+
+func NewRepository(cluster *db.Cluster, c *cache.Cache) *Repository {
+	return &Repository{DB: cluster.Primary, Replicas: cluster, Cache: c}
+}
+
+// Airport looks up an airport by IATA or ICAO code, case-insensitively.
+func (r *Repository) Airport(ctx context.Context, code string) (Airport, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	column := "iata"
+	if len(code) == 4 {
+		column = "icao"
+	}
+	query := `SELECT ` + airportColumns + ` FROM airports WHERE ` + column + ` = $1`
+	a, err := lookup[Airport](ctx, r, "airport:"+code, query, code)
+	if errors.Is(err, cache.ErrNotFound) {
+		return a, ErrAirportNotFound
+	}
+	return a, err
+}
+
+// Airline looks up an airline by IATA or ICAO designator, case-insensitively.
+func (r *Repository) Airline(ctx context.Context, code string) (Airline, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	column := "iata"
+	if len(code) == 3 {
+		column = "icao"
+	}
+	query := `SELECT ` + airlineColumns + ` FROM airlines WHERE ` + column + ` = $1`
+	a, err := lookup[Airline](ctx, r, "airline:"+code, query, code)
+	if errors.Is(err, cache.ErrNotFound) {
+		return a, ErrAirlineNotFound
+	}
+	return a, err
+}
+
+// lookup loads a single row through the cache, remembering misses briefly.
+func lookup[T any](ctx context.Context, r *Repository, key, query string, args ...any) (T, error) {
+	opts := cache.Options{TTL: referenceTTL, NegativeTTL: notFoundTTL, Tags: []string{referenceTag}}
+	return cache.GetOrLoad(ctx, r.Cache, key, opts, func(ctx context.Context) (T, error) {
+		var v T
+		err := r.Replicas.Reader(ctx).GetContext(ctx, &v, query, args...)
+		if errors.Is(err, sql.ErrNoRows) {
+			return v, cache.ErrNotFound
+		}
+		if err != nil {
+			return v, fmt.Errorf("failed to look up %s: %w", key, err)
+		}
+		return v, nil
+	})
+}
+
+// ListAirports returns all airports, or those of one country, by IATA code.
+func (r *Repository) ListAirports(ctx context.Context, country string) ([]Airport, error) {
+	query := `SELECT ` + airportColumns + ` FROM airports WHERE $1 = '' OR country = $1 ORDER BY iata`
+	airports := []Airport{}
+	if err := r.Replicas.Reader(ctx).SelectContext(ctx, &airports, query, strings.ToUpper(country)); err != nil {
+		return nil, fmt.Errorf("failed to list airports: %w", err)
+	}
+	return airports, nil
+}
+
+// ListAirlines returns all airlines by IATA designator.
+func (r *Repository) ListAirlines(ctx context.Context) ([]Airline, error) {
+	airlines := []Airline{}
+	if err := r.Replicas.Reader(ctx).SelectContext(ctx, &airlines, `SELECT `+airlineColumns+` FROM airlines ORDER BY iata`); err != nil {
+		return nil, fmt.Errorf("failed to list airlines: %w", err)
+	}
+	return airlines, nil
+}
+
+// SaveAirports inserts or replaces airports by IATA code in one transaction.
+func (r *Repository) SaveAirports(ctx context.Context, airports []Airport) error {
+	query := `
+		INSERT INTO airports (iata, icao, name, city, country, timezone, latitude, longitude)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (iata) DO UPDATE SET icao = EXCLUDED.icao, name = EXCLUDED.name, city = EXCLUDED.city,
+			country = EXCLUDED.country, timezone = EXCLUDED.timezone, latitude = EXCLUDED.latitude, longitude = EXCLUDED.longitude`
+	return r.save(ctx, "airports", len(airports), func(tx *sqlx.Tx, i int) error {
+		a := airports[i]
+		_, err := tx.ExecContext(ctx, query, a.IATA, a.ICAO, a.Name, a.City, a.Country, a.Timezone, a.Latitude, a.Longitude)
+		return err
+	})
+}
+
+// SaveAirlines inserts or replaces airlines by IATA designator in one transaction.
+func (r *Repository) SaveAirlines(ctx context.Context, airlines []Airline) error {
+	query := `
+		INSERT INTO airlines (iata, icao, name, country)
+		VALUES ($1, NULLIF($2, ''), $3, $4)
+		ON CONFLICT (iata) DO UPDATE SET icao = EXCLUDED.icao, name = EXCLUDED.name, country = EXCLUDED.country`
+	return r.save(ctx, "airlines", len(airlines), func(tx *sqlx.Tx, i int) error {
+		a := airlines[i]
+		_, err := tx.ExecContext(ctx, query, a.IATA, a.ICAO, a.Name, a.Country)
+		return err
+	})
+}
+
+func (r *Repository) save(ctx context.Context, what string, n int, exec func(tx *sqlx.Tx, i int) error) error {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for i := 0; i < n; i++ {
+		if err := exec(tx, i); err != nil {
+			return fmt.Errorf("failed to save %s: %w", what, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+
+	if err := r.Cache.InvalidateTags(ctx, referenceTag); err != nil {
+		log.Printf("Failed to invalidate reference cache: %v", err)
+	}
+	return nil
+}
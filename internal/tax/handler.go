@@ -0,0 +1,59 @@
+package tax
+
+import (
+	"net/http"
+
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/money"
+	"airline-booking/pkg/request"
+	"airline-booking/pkg/response"
+)
+
+// Handler serves the tax and fee rules.
+type Handler struct {
+	Repo *Repository
+	// Rates must convert the fixed amounts of the rules saved
+	Rates *money.Rates
+}
+
+// NewHandler creates a new tax handler.
+func NewHandler(repo *Repository, rates *money.Rates) *Handler {
+	return &Handler{Repo: repo, Rates: rates}
+}
+
+// Routes registers the tax endpoints on mux.
+func (h *Handler) Routes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /taxes/rules", h.GetRules)
+	mux.HandleFunc("PUT /taxes/rules", h.PutRules)
+}
+
+// GetRules returns the taxes and fees in force.
+func (h *Handler) GetRules(w http.ResponseWriter, r *http.Request) {
+	rs, err := h.Repo.RuleSet(r.Context())
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, rs)
+}
+
+// PutRules puts a new version of the taxes and fees in force. Fixed
+// amounts must be in a currency the exchange rates convert.
+func (h *Handler) PutRules(w http.ResponseWriter, r *http.Request) {
+	var rules Rules
+	if err := request.Decode(w, r, &rules); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	if err := rules.CheckRates(h.Rates); err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+
+	rs, err := h.Repo.SaveRules(r.Context(), rules)
+	if err != nil {
+		apperr.Write(w, r, err)
+		return
+	}
+	response.JSON(w, http.StatusOK, rs)
+}
@@ -0,0 +1,348 @@
+// Package tax applies configurable airport taxes, fuel surcharges, service
+// fees and VAT to bookings by route and passenger type, and itemizes what
+// each booking is charged.
+package tax
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"slices"
+	"strings"
+
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/jsonb"
+	"airline-booking/pkg/money"
+	"airline-booking/pkg/ruleset"
+	"airline-booking/pkg/validate"
+)
+
+// Charge kinds. Fare and seat are the lines of a breakdown that are not
+// taxes or fees.
+const (
+	KindAirportTax    = "airport_tax"
+	KindFuelSurcharge = "fuel_surcharge"
+	KindServiceFee    = "service_fee"
+	KindVAT           = "vat"
+	KindFare          = "fare"
+	KindSeat          = "seat"
+)
+
+// Passenger types, in the order breakdowns list them
+const (
+	PassengerAdult  = "adult"
+	PassengerChild  = "child"
+	PassengerInfant = "infant"
+)
+
+// PassengerTypes lists every passenger type.
+var PassengerTypes = []string{PassengerAdult, PassengerChild, PassengerInfant}
+
+// Charge is one tax or fee. It is either a fixed Amount or a Percent of
+// the fare; VAT is always a percent, of the fare, seat charges, fuel
+// surcharges and service fees it applies to.
+type Charge struct {
+	// Code is shown on invoices, e.g. UDF or YQ
+	Code string `json:"code" validate:"required,max=10,code"`
+	Name string `json:"name" validate:"required,max=100"`
+	Kind string `json:"kind" validate:"required,oneof=airport_tax fuel_surcharge service_fee vat"`
+	// Origin and Destination limit the charge to a route, by airport code;
+	// empty matches any airport
+	Origin      string `json:"origin,omitempty" validate:"max=64"`
+	Destination string `json:"destination,omitempty" validate:"max=64"`
+	// PassengerTypes limit the charge to some passenger types; empty
+	// charges every passenger
+	PassengerTypes []string `json:"passenger_types,omitempty"`
+	// Amount is charged per passenger, or once per booking with PerBooking.
+	// It is converted at the current rate on bookings in another currency.
+	Amount     money.Money `json:"amount,omitzero" validate:"min=0"`
+	Percent    float64     `json:"percent,omitempty" validate:"min=0,max=100"`
+	PerBooking bool        `json:"per_booking,omitempty"`
+}
+
+func init() {
+	validate.Register(Charge{})
+}
+
+// Rules are the taxes and fees in force. With none only the fare and seat
+// charges are due.
+type Rules struct {
+	Charges []Charge `json:"charges"`
+}
+
+// Value implements driver.Valuer.
+func (r Rules) Value() (driver.Value, error) {
+	return jsonb.Value(r)
+}
+
+// Scan implements sql.Scanner.
+func (r *Rules) Scan(src any) error {
+	return jsonb.Scan(src, r)
+}
+
+// Validate checks every charge, which the tag rules do not reach, and that
+// no code is used twice.
+func (r Rules) Validate() []apperr.FieldError {
+	var problems []apperr.FieldError
+	seen := map[string]bool{}
+	for i, c := range r.Charges {
+		prefix := fmt.Sprintf("charges[%d].", i)
+		add := func(field, message string) {
+			problems = append(problems, apperr.FieldError{Field: prefix + field, Message: message})
+		}
+		for _, p := range validate.Struct(c) {
+			add(p.Field, p.Message)
+		}
+		if seen[c.Code] {
+			add("code", fmt.Sprintf("%s is listed twice", c.Code))
+		}
+		seen[c.Code] = true
+		switch {
+		case c.Amount.IsZero() == (c.Percent == 0):
+			add("amount", "exactly one of amount and percent must be given")
+		case c.Kind == KindVAT && c.Percent == 0:
+			add("percent", "is required for vat")
+		case c.PerBooking && c.Percent != 0:
+			add("per_booking", "applies to fixed amounts only")
+		}
+		for _, t := range c.PassengerTypes {
+			if !slices.Contains(PassengerTypes, t) {
+				add("passenger_types", fmt.Sprintf("%q is not one of %s", t, strings.Join(PassengerTypes, ", ")))
+			}
+		}
+	}
+	return problems
+}
+
+// CheckRates returns a validation error listing the charges whose fixed
+// amounts rates cannot convert, so that bookings in other currencies are
+// not refused over them. Without rates loaded only amounts in the default
+// currency are accepted.
+func (r Rules) CheckRates(rates *money.Rates) error {
+	base := money.DefaultCurrency
+	if rates != nil && rates.Table().Base != "" {
+		base = rates.Table().Base
+	}
+	var problems []apperr.FieldError
+	for i, c := range r.Charges {
+		if c.Amount.IsZero() {
+			continue
+		}
+		if _, err := rates.Rate(c.Amount.Currency, base); err != nil {
+			problems = append(problems, apperr.FieldError{
+				Field:   fmt.Sprintf("charges[%d].amount", i),
+				Message: fmt.Sprintf("has no exchange rate from %s", c.Amount.Currency),
+			})
+		}
+	}
+	if len(problems) > 0 {
+		return apperr.Validation("validation_failed", "request has invalid fields", problems...)
+	}
+	return nil
+}
+
+// RuleSet is a saved version of the rules. The latest version is in force.
+type RuleSet = ruleset.Set[Rules]
+
+// Passengers counts the travellers of a booking by passenger type, stored
+// as JSON.
+type Passengers map[string]int
+
+// Total is the number of travellers.
+func (p Passengers) Total() int {
+	n := 0
+	for _, count := range p {
+		n += count
+	}
+	return n
+}
+
+// Validate checks the passenger types and their counts.
+func (p Passengers) Validate(field string) []apperr.FieldError {
+	var problems []apperr.FieldError
+	for t, n := range p {
+		switch {
+		case !slices.Contains(PassengerTypes, t):
+			problems = append(problems, apperr.FieldError{Field: field, Message: fmt.Sprintf("%q is not one of %s", t, strings.Join(PassengerTypes, ", "))})
+		case n < 1:
+			problems = append(problems, apperr.FieldError{Field: field + "." + t, Message: "must be at least 1"})
+		}
+	}
+	return problems
+}
+
+// Value implements driver.Valuer.
+func (p Passengers) Value() (driver.Value, error) {
+	if p == nil {
+		return nil, nil
+	}
+	return jsonb.Value(p)
+}
+
+// Scan implements sql.Scanner.
+func (p *Passengers) Scan(src any) error {
+	return jsonb.Scan(src, p)
+}
+
+// Trip is what a booking is charged for: its route, the fare and seat
+// charges for the whole party and who travels.
+type Trip struct {
+	Origin      string
+	Destination string
+	Fare        money.Money
+	SeatCharge  money.Money
+	Passengers  Passengers
+}
+
+// Item is one line of a breakdown, for Quantity passengers of
+// PassengerType or, without one, for the booking.
+type Item struct {
+	Code          string      `json:"code,omitempty"`
+	Name          string      `json:"name"`
+	Kind          string      `json:"kind"`
+	PassengerType string      `json:"passenger_type,omitempty"`
+	Quantity      int         `json:"quantity"`
+	Amount        money.Money `json:"amount"`
+}
+
+// Breakdown itemizes what a booking is charged, stored as JSON.
+type Breakdown struct {
+	Items []Item `json:"items"`
+	// Fare is the fare and seat charges; Taxes every tax, fee and surcharge
+	Fare  money.Money `json:"fare"`
+	Taxes money.Money `json:"taxes"`
+	Total money.Money `json:"total"`
+}
+
+// Value implements driver.Valuer.
+func (b Breakdown) Value() (driver.Value, error) {
+	return jsonb.Value(b)
+}
+
+// Scan implements sql.Scanner.
+func (b *Breakdown) Scan(src any) error {
+	return jsonb.Scan(src, b)
+}
+
+// Convert turns a fixed amount into the currency of a trip.
+type Convert func(m money.Money, currency string) (money.Money, error)
+
+// Apply works out the charges of t under the rules. The fare is shared out
+// evenly between passengers; fixed amounts in other currencies go through
+// convert, and percentages round half to even to the minor unit.
+func (r Rules) Apply(t Trip, convert Convert) (Breakdown, error) {
+	currency := t.Fare.Currency
+	b := Breakdown{Fare: money.New(0, currency), Taxes: money.New(0, currency)}
+
+	// The fare of each passenger type, which percentages are taken of
+	fares := map[string]money.Money{}
+	parts := t.Fare.Split(max(t.Passengers.Total(), 1))
+	for _, typ := range PassengerTypes {
+		n := t.Passengers[typ]
+		if n == 0 {
+			continue
+		}
+		fare, _ := money.Sum(parts[:n]...)
+		parts = parts[n:]
+		fares[typ] = fare
+		b.Items = append(b.Items, Item{Name: "Fare", Kind: KindFare, PassengerType: typ, Quantity: n, Amount: fare})
+	}
+	if !t.SeatCharge.IsZero() {
+		b.Items = append(b.Items, Item{Name: "Seat selection", Kind: KindSeat, Quantity: 1, Amount: t.SeatCharge})
+	}
+
+	var vat []Charge
+	for _, c := range r.Charges {
+		if !c.matches(t.Origin, t.Destination) {
+			continue
+		}
+		if c.Kind == KindVAT {
+			vat = append(vat, c)
+			continue
+		}
+		if c.PerBooking {
+			if !slices.ContainsFunc(PassengerTypes, func(typ string) bool { return t.Passengers[typ] > 0 && c.charges(typ) }) {
+				continue
+			}
+			amount, err := c.fixed(currency, convert)
+			if err != nil {
+				return b, err
+			}
+			b.Items = append(b.Items, c.item("", 1, amount))
+			continue
+		}
+		for _, typ := range PassengerTypes {
+			n := t.Passengers[typ]
+			if n == 0 || !c.charges(typ) {
+				continue
+			}
+			amount := fares[typ].Mul(c.Percent / 100)
+			if c.Percent == 0 {
+				each, err := c.fixed(currency, convert)
+				if err != nil {
+					return b, err
+				}
+				amount = each.Times(n)
+			}
+			b.Items = append(b.Items, c.item(typ, n, amount))
+		}
+	}
+
+	// VAT goes on the lines before it that it applies to
+	items := b.Items
+	for _, c := range vat {
+		var base int64
+		for _, it := range items {
+			if taxable(it.Kind) && (it.PassengerType == "" || c.charges(it.PassengerType)) {
+				base += it.Amount.Amount
+			}
+		}
+		if base != 0 {
+			b.Items = append(b.Items, c.item("", 1, money.New(base, currency).Mul(c.Percent/100)))
+		}
+	}
+
+	for _, it := range b.Items {
+		total := &b.Taxes
+		if it.Kind == KindFare || it.Kind == KindSeat {
+			total = &b.Fare
+		}
+		var err error
+		if *total, err = total.Add(it.Amount); err != nil {
+			return b, err
+		}
+	}
+	b.Total = money.New(b.Fare.Amount+b.Taxes.Amount, currency)
+	return b, nil
+}
+
+// matches reports whether c applies on the route from origin to destination.
+func (c Charge) matches(origin, destination string) bool {
+	return (c.Origin == "" || strings.EqualFold(c.Origin, origin)) &&
+		(c.Destination == "" || strings.EqualFold(c.Destination, destination))
+}
+
+// charges reports whether c applies to passengers of type typ.
+func (c Charge) charges(typ string) bool {
+	return len(c.PassengerTypes) == 0 || slices.Contains(c.PassengerTypes, typ)
+}
+
+// fixed returns the amount of c in currency.
+func (c Charge) fixed(currency string, convert Convert) (money.Money, error) {
+	if c.Amount.Currency == currency {
+		return c.Amount, nil
+	}
+	m, err := convert(c.Amount, currency)
+	if err != nil {
+		return m, fmt.Errorf("%w: charge %s", err, c.Code)
+	}
+	return m, nil
+}
+
+func (c Charge) item(typ string, n int, amount money.Money) Item {
+	return Item{Code: c.Code, Name: c.Name, Kind: c.Kind, PassengerType: typ, Quantity: n, Amount: amount}
+}
+
+// taxable reports whether VAT is charged on lines of kind.
+func taxable(kind string) bool {
+	return kind == KindFare || kind == KindSeat || kind == KindFuelSurcharge || kind == KindServiceFee
+}
@@ -0,0 +1,119 @@
+package tax
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/money"
+)
+
+func TestRulesCheckRates(t *testing.T) {
+	rates := money.NewRates()
+	rates.Set(money.RateTable{Base: "INR", AsOf: time.Now(), Rates: map[string]float64{"USD": 0.012}})
+	rules := Rules{Charges: []Charge{
+		{Code: "UDF", Kind: KindAirportTax, Amount: money.New(50000, "INR")},
+		{Code: "YQ", Kind: KindFuelSurcharge, Amount: money.New(1500, "USD")},
+		{Code: "XF", Kind: KindServiceFee, Amount: money.New(1000, "EUR")},
+		{Code: "GST", Kind: KindVAT, Percent: 5},
+	}}
+
+	tests := []struct {
+		name  string
+		rates *money.Rates
+		rules Rules
+		want  []string
+	}{
+		{name: "convertible", rates: rates, rules: Rules{Charges: rules.Charges[:2]}},
+		{name: "no rate", rates: rates, rules: rules, want: []string{"charges[2].amount"}},
+		{name: "no rates loaded", rules: rules, want: []string{"charges[1].amount", "charges[2].amount"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rules.CheckRates(tt.rates)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("CheckRates() = %v, want nil", err)
+				}
+				return
+			}
+			var ae *apperr.Error
+			if !errors.As(err, &ae) || ae.Kind != apperr.KindValidation {
+				t.Fatalf("CheckRates() = %v, want a validation error", err)
+			}
+			var fields []string
+			for _, p := range ae.Fields {
+				fields = append(fields, p.Field)
+			}
+			if len(fields) != len(tt.want) {
+				t.Fatalf("fields = %v, want %v", fields, tt.want)
+			}
+			for i := range fields {
+				if fields[i] != tt.want[i] {
+					t.Errorf("fields = %v, want %v", fields, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRulesApply(t *testing.T) {
+	rules := Rules{Charges: []Charge{
+		{Code: "UDF", Name: "User development fee", Kind: KindAirportTax, Origin: "DEL", Amount: money.New(50000, "INR"),
+			PassengerTypes: []string{PassengerAdult, PassengerChild}},
+		{Code: "BLR", Name: "Bengaluru fee", Kind: KindAirportTax, Origin: "BLR", Amount: money.New(20000, "INR")},
+		{Code: "INF", Name: "Infant fee", Kind: KindServiceFee, Amount: money.New(1000, "INR"), PassengerTypes: []string{PassengerInfant}},
+		{Code: "YQ", Name: "Fuel surcharge", Kind: KindFuelSurcharge, Percent: 10},
+		{Code: "XF", Name: "Booking fee", Kind: KindServiceFee, Amount: money.New(1000, "USD"), PerBooking: true},
+		{Code: "GST", Name: "GST", Kind: KindVAT, Percent: 5},
+	}}
+	trip := Trip{
+		Origin:      "del",
+		Destination: "BOM",
+		Fare:        money.New(300001, "INR"),
+		SeatCharge:  money.New(50000, "INR"),
+		Passengers:  Passengers{PassengerAdult: 2, PassengerChild: 1},
+	}
+	convert := func(m money.Money, currency string) (money.Money, error) {
+		return money.New(m.Amount*83, currency), nil
+	}
+
+	got, err := rules.Apply(trip, convert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inr := func(n int64) money.Money { return money.New(n, "INR") }
+	want := []Item{
+		// The odd minor unit of the fare goes to the first passenger
+		{Name: "Fare", Kind: KindFare, PassengerType: PassengerAdult, Quantity: 2, Amount: inr(200001)},
+		{Name: "Fare", Kind: KindFare, PassengerType: PassengerChild, Quantity: 1, Amount: inr(100000)},
+		{Name: "Seat selection", Kind: KindSeat, Quantity: 1, Amount: inr(50000)},
+		{Code: "UDF", Name: "User development fee", Kind: KindAirportTax, PassengerType: PassengerAdult, Quantity: 2, Amount: inr(100000)},
+		{Code: "UDF", Name: "User development fee", Kind: KindAirportTax, PassengerType: PassengerChild, Quantity: 1, Amount: inr(50000)},
+		{Code: "YQ", Name: "Fuel surcharge", Kind: KindFuelSurcharge, PassengerType: PassengerAdult, Quantity: 2, Amount: inr(20000)},
+		{Code: "YQ", Name: "Fuel surcharge", Kind: KindFuelSurcharge, PassengerType: PassengerChild, Quantity: 1, Amount: inr(10000)},
+		{Code: "XF", Name: "Booking fee", Kind: KindServiceFee, Quantity: 1, Amount: inr(83000)},
+		// 5% of 463001, the fare, seat, fuel surcharge and service fee
+		{Code: "GST", Name: "GST", Kind: KindVAT, Quantity: 1, Amount: inr(23150)},
+	}
+	if !slices.Equal(got.Items, want) {
+		t.Errorf("Items =\n%+v\nwant\n%+v", got.Items, want)
+	}
+	if got.Fare != inr(350001) || got.Taxes != inr(286150) || got.Total != inr(636151) {
+		t.Errorf("Fare, Taxes, Total = %v, %v, %v", got.Fare, got.Taxes, got.Total)
+	}
+}
+
+func TestRulesApplyConvertFails(t *testing.T) {
+	rules := Rules{Charges: []Charge{{Code: "XF", Kind: KindServiceFee, Amount: money.New(1000, "USD")}}}
+	trip := Trip{Fare: money.New(100000, "INR"), Passengers: Passengers{PassengerAdult: 1}}
+	errNoRate := errors.New("no rate")
+	_, err := rules.Apply(trip, func(money.Money, string) (money.Money, error) {
+		return money.Money{}, errNoRate
+	})
+	if !errors.Is(err, errNoRate) {
+		t.Errorf("Apply() = %v, want %v", err, errNoRate)
+	}
+}
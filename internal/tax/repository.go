@@ -0,0 +1,39 @@
+package tax
+
+import (
+	"context"
+
+	"airline-booking/pkg/cache"
+	"airline-booking/pkg/db"
+	"airline-booking/pkg/ruleset"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const rulesKey = "tax:rules"
+
+type Repository struct {
+	DB       *sqlx.DB
+	Replicas *db.Cluster
+	Cache    *cache.Cache
+}
+
+func NewRepository(cluster *db.Cluster, c *cache.Cache) *Repository {
+	return &Repository{DB: cluster.Primary, Replicas: cluster, Cache: c}
+}
+
+// RuleSet returns the taxes and fees in force. Before any are saved
+// bookings are charged their fare alone.
+func (r *Repository) RuleSet(ctx context.Context) (RuleSet, error) {
+	return r.ruleStore().Latest(ctx)
+}
+
+// SaveRules puts a new version of the taxes and fees in force. Bookings
+// made before keep the charges they were made with.
+func (r *Repository) SaveRules(ctx context.Context, rules Rules) (RuleSet, error) {
+	return r.ruleStore().Save(ctx, rules)
+}
+
+func (r *Repository) ruleStore() ruleset.Store[Rules] {
+	return ruleset.Store[Rules]{Cluster: r.Replicas, Cache: r.Cache, Table: "tax_rules", Key: rulesKey}
+}
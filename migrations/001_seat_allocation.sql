@@ -0,0 +1,6 @@
+-- Fencing token of the last seat allocation applied to each flight, so a
+-- writer whose distributed lock expired cannot overwrite newer allocations.
+ALTER TABLE flights ADD COLUMN IF NOT EXISTS seat_fence BIGINT NOT NULL DEFAULT 0;
+
+-- Seats of a booking in the "held" status are released once this passes.
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS held_until TIMESTAMPTZ;
@@ -0,0 +1,3 @@
+-- Set on bookings whose flight was rescheduled until the passenger is moved
+-- to another flight.
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS needs_reaccommodation BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,2 @@
+-- Flights are cancelled rather than deleted once passengers have booked them.
+ALTER TABLE flights ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'scheduled';
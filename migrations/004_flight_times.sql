@@ -0,0 +1,14 @@
+-- Departure and arrival become instants. Existing values without an offset
+-- are taken as UTC.
+SET TIME ZONE 'UTC';
+
+ALTER TABLE flights
+    ALTER COLUMN departure TYPE TIMESTAMPTZ USING departure::text::timestamptz,
+    ALTER COLUMN arrival TYPE TIMESTAMPTZ USING arrival::text::timestamptz;
+
+-- IANA time zones of the departure and arrival airports, used to show local
+-- times and to search by local departure date.
+ALTER TABLE flights ADD COLUMN IF NOT EXISTS source_tz TEXT NOT NULL DEFAULT 'UTC';
+ALTER TABLE flights ADD COLUMN IF NOT EXISTS destination_tz TEXT NOT NULL DEFAULT 'UTC';
+
+CREATE INDEX IF NOT EXISTS flights_route_departure_idx ON flights (source, destination, departure);
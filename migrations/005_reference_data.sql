@@ -0,0 +1,21 @@
+-- Airports and airlines that flights are checked against. Flights refer to
+-- them by IATA code; ICAO codes are optional but unique when present.
+CREATE TABLE IF NOT EXISTS airports (
+    iata      CHAR(3) PRIMARY KEY,
+    icao      CHAR(4) UNIQUE,
+    name      TEXT NOT NULL,
+    city      TEXT NOT NULL,
+    country   CHAR(2) NOT NULL,
+    timezone  TEXT NOT NULL,
+    latitude  DOUBLE PRECISION NOT NULL DEFAULT 0,
+    longitude DOUBLE PRECISION NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS airports_country_idx ON airports (country);
+
+CREATE TABLE IF NOT EXISTS airlines (
+    iata    CHAR(2) PRIMARY KEY,
+    icao    CHAR(3) UNIQUE,
+    name    TEXT NOT NULL,
+    country TEXT NOT NULL DEFAULT ''
+);
@@ -0,0 +1,23 @@
+-- Aircraft types and their cabin layouts, stored as JSON:
+-- [{"class": "economy", "first_row": 10, "last_row": 30, "letters": "ABC-DEF",
+--   "exit_rows": [12, 13], "blocked": ["30C"]}]
+CREATE TABLE IF NOT EXISTS aircraft_types (
+    code   TEXT PRIMARY KEY,
+    name   TEXT NOT NULL,
+    cabins JSONB NOT NULL
+);
+
+-- The aircraft flying each flight and the seats its layout can sell.
+-- Flights without one keep a plain seat count.
+ALTER TABLE flights ADD COLUMN IF NOT EXISTS aircraft TEXT NOT NULL DEFAULT '';
+ALTER TABLE flights ADD COLUMN IF NOT EXISTS capacity INT NOT NULL DEFAULT 0;
+
+-- Seats taken on each flight, e.g. ('12A', booking 42).
+CREATE TABLE IF NOT EXISTS seat_assignments (
+    flight_id  INT NOT NULL REFERENCES flights (id) ON DELETE CASCADE,
+    seat       TEXT NOT NULL,
+    booking_id INT NOT NULL REFERENCES bookings (id) ON DELETE CASCADE,
+    PRIMARY KEY (flight_id, seat)
+);
+
+CREATE INDEX IF NOT EXISTS seat_assignments_booking_idx ON seat_assignments (booking_id);
@@ -0,0 +1,5 @@
+-- Which traveller of the party sits in each seat, in booking order.
+ALTER TABLE seat_assignments ADD COLUMN IF NOT EXISTS traveller INT NOT NULL DEFAULT 0;
+
+-- Charge for the seats a passenger chose, on top of the fare.
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS seat_charge NUMERIC(10, 2) NOT NULL DEFAULT 0;
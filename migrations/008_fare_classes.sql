@@ -0,0 +1,14 @@
+-- Booking classes of each flight. allocation is a nested limit: the most
+-- seats sold in the class and the cheaper classes of its family together.
+CREATE TABLE IF NOT EXISTS fare_buckets (
+    flight_id  INT NOT NULL REFERENCES flights (id) ON DELETE CASCADE,
+    code       TEXT NOT NULL,
+    family     TEXT NOT NULL,
+    price      NUMERIC(10, 2) NOT NULL,
+    allocation INT NOT NULL DEFAULT 0,
+    sold       INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (flight_id, code)
+);
+
+-- The booking class a booking was sold in, empty for bookings priced by hand.
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS fare_class TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,29 @@
+-- When each booking was made, for demand signals and back-testing. Rows
+-- from before this migration stay NULL and are left out of back-tests.
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS booked_at TIMESTAMPTZ;
+ALTER TABLE bookings ALTER COLUMN booked_at SET DEFAULT now();
+CREATE INDEX IF NOT EXISTS bookings_flight_booked_at_idx ON bookings (flight_id, booked_at);
+
+-- Versions of the pricing rules; the latest is in force.
+CREATE TABLE IF NOT EXISTS pricing_rules (
+    version    SERIAL PRIMARY KEY,
+    rules      JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Every price quoted, and the booking it was sold to if any.
+CREATE TABLE IF NOT EXISTS price_quotes (
+    id         BIGSERIAL PRIMARY KEY,
+    flight_id  INT NOT NULL REFERENCES flights (id) ON DELETE CASCADE,
+    fare_class TEXT NOT NULL DEFAULT '',
+    seats      INT NOT NULL,
+    base_fare  NUMERIC(10, 2) NOT NULL,
+    multiplier DOUBLE PRECISION NOT NULL,
+    price      NUMERIC(10, 2) NOT NULL,
+    factors    JSONB NOT NULL DEFAULT '{}',
+    booking_id INT REFERENCES bookings (id) ON DELETE SET NULL,
+    quoted_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS price_quotes_flight_idx ON price_quotes (flight_id, quoted_at);
+CREATE INDEX IF NOT EXISTS price_quotes_booking_idx ON price_quotes (booking_id);
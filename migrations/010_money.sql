@@ -0,0 +1,41 @@
+-- Amounts are stored as BIGINT minor units (paise for INR) next to the
+-- ISO 4217 currency of the row. Existing rows were priced in INR. Amounts
+-- are rounded half to even, as the services round them.
+CREATE OR REPLACE FUNCTION pg_temp.to_minor(v NUMERIC) RETURNS BIGINT AS $$
+    SELECT CASE WHEN abs(v * 100 - trunc(v * 100)) = 0.5 THEN 2 * round(v * 100 / 2) ELSE round(v * 100) END::BIGINT
+$$ LANGUAGE sql IMMUTABLE;
+
+DO $$
+DECLARE
+    col RECORD;
+BEGIN
+    FOR col IN
+        SELECT table_name, column_name FROM information_schema.columns
+        WHERE table_schema = current_schema() AND data_type <> 'bigint' AND (table_name, column_name) IN (
+            ('flights', 'price'), ('bookings', 'total_price'), ('bookings', 'seat_charge'),
+            ('fare_buckets', 'price'), ('price_quotes', 'base_fare'), ('price_quotes', 'price'))
+    LOOP
+        EXECUTE format('ALTER TABLE %I ALTER COLUMN %I DROP DEFAULT', col.table_name, col.column_name);
+        EXECUTE format('ALTER TABLE %I ALTER COLUMN %I TYPE BIGINT USING pg_temp.to_minor(%I::NUMERIC)',
+            col.table_name, col.column_name, col.column_name);
+    END LOOP;
+END $$;
+
+ALTER TABLE bookings ALTER COLUMN seat_charge SET DEFAULT 0;
+
+ALTER TABLE flights ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'INR';
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'INR';
+ALTER TABLE fare_buckets ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'INR';
+ALTER TABLE price_quotes ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'INR';
+
+-- Seat prices in cabin layouts become {"amount": 50000, "currency": "INR"}
+UPDATE aircraft_types SET cabins = (
+    SELECT jsonb_agg(
+        (SELECT jsonb_object_agg(key, CASE
+            WHEN key IN ('seat_price', 'exit_row_price') AND jsonb_typeof(value) = 'number'
+            THEN jsonb_build_object('amount', pg_temp.to_minor(value::TEXT::NUMERIC), 'currency', 'INR')
+            ELSE value END)
+         FROM jsonb_each(cabin))
+        ORDER BY n)
+    FROM jsonb_array_elements(cabins) WITH ORDINALITY AS c (cabin, n))
+WHERE jsonb_typeof(cabins) = 'array' AND jsonb_array_length(cabins) > 0;
@@ -0,0 +1,7 @@
+-- Exchange rate locked when a booking is shown in another currency, e.g.
+-- {"from": "INR", "to": "USD", "rate": 0.0119, "as_of": "2026-10-19T00:00:00Z"}.
+-- NULL for bookings made in their own currency only.
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS exchange_rate JSONB;
+
+-- Rate a quote was converted at, when one was asked for in another currency.
+ALTER TABLE price_quotes ADD COLUMN IF NOT EXISTS exchange_rate JSONB;
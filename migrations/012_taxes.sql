@@ -0,0 +1,15 @@
+-- Versions of the tax and fee rules; the latest is in force.
+CREATE TABLE IF NOT EXISTS tax_rules (
+    version    SERIAL PRIMARY KEY,
+    rules      JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Travellers by passenger type, e.g. {"adult": 2, "child": 1}. NULL for
+-- bookings of adults only.
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS passenger_types JSONB;
+
+-- Itemized fare, taxes, fees and surcharges the booking was charged.
+-- NULL for bookings made before this migration, which were charged their
+-- total price and seat charge alone.
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS charges JSONB;
@@ -0,0 +1,28 @@
+-- A passenger holds seats on a flight under one booking at most. The Redis
+-- check in booking-service only answers duplicates fast; this enforces it.
+
+-- Duplicates made before the index existed would fail it: keep the first
+-- booking of each passenger on a flight, cancel the others and give their
+-- seats back to the flight, its booking classes and the seat map.
+WITH ranked AS (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY passenger, flight_id ORDER BY id) AS n
+    FROM bookings
+    WHERE status IN ('confirmed', 'held')
+), dropped AS (
+    UPDATE bookings b SET status = 'cancelled'
+    FROM ranked r
+    WHERE b.id = r.id AND r.n > 1
+    RETURNING b.id, b.flight_id, b.seats, b.fare_class
+), flight_seats AS (
+    UPDATE flights f SET available_seats = f.available_seats + d.seats
+    FROM (SELECT flight_id, SUM(seats) AS seats FROM dropped GROUP BY flight_id) d
+    WHERE f.id = d.flight_id
+), class_seats AS (
+    UPDATE fare_buckets fb SET sold = GREATEST(fb.sold - d.seats, 0)
+    FROM (SELECT flight_id, fare_class, SUM(seats) AS seats FROM dropped WHERE fare_class <> '' GROUP BY flight_id, fare_class) d
+    WHERE fb.flight_id = d.flight_id AND fb.code = d.fare_class
+)
+DELETE FROM seat_assignments WHERE booking_id IN (SELECT id FROM dropped);
+
+CREATE UNIQUE INDEX IF NOT EXISTS bookings_passenger_flight_key
+    ON bookings (passenger, flight_id) WHERE status IN ('confirmed', 'held');
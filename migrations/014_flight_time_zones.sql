@@ -0,0 +1,4 @@
+-- Flights saved without airport time zones were stored with empty ones,
+-- which AT TIME ZONE rejects; they are UTC.
+UPDATE flights SET source_tz = 'UTC' WHERE source_tz = '';
+UPDATE flights SET destination_tz = 'UTC' WHERE destination_tz = '';
@@ -0,0 +1,83 @@
+// Package apperr defines the domain errors shared by the services and
+// writes them as RFC 7807 problem details.
+package apperr
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Kind classifies an error and decides its HTTP status.
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindNotFound
+	KindConflict
+	KindValidation
+	KindSoldOut
+	KindDuplicate
+	KindUnavailable
+	KindTooLarge
+)
+
+// Status returns the HTTP status code for errors of this kind.
+func (k Kind) Status() int {
+	switch k {
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict, KindSoldOut, KindDuplicate:
+		return http.StatusConflict
+	case KindValidation:
+		return http.StatusBadRequest
+	case KindUnavailable:
+		return http.StatusServiceUnavailable
+	case KindTooLarge:
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// FieldError is a problem with one field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is a domain error with a machine-readable code, e.g.
+// "booking_not_found". Declare them as package variables and compare with
+// errors.Is; wrap them with fmt.Errorf and %w to add context.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// New creates an error of the given kind.
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func NotFound(code, message string) *Error    { return New(KindNotFound, code, message) }
+func Conflict(code, message string) *Error    { return New(KindConflict, code, message) }
+func SoldOut(code, message string) *Error     { return New(KindSoldOut, code, message) }
+func Duplicate(code, message string) *Error   { return New(KindDuplicate, code, message) }
+func Unavailable(code, message string) *Error { return New(KindUnavailable, code, message) }
+
+// Validation creates a validation error, optionally listing the fields at fault.
+func Validation(code, message string, fields ...FieldError) *Error {
+	e := New(KindValidation, code, message)
+	e.Fields = fields
+	return e
+}
+
+// Invalid is a validation error for a request that could not be read at all.
+func Invalid(format string, args ...any) *Error {
+	return Validation("invalid_request", fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,95 @@
+package apperr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// ContentType is the media type of problem details responses.
+const ContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details body. Code is the machine-readable
+// error code clients should switch on; the type is always about:blank.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// Write answers err as problem details. Errors that are not an *Error are
+// logged and answered with a generic 500 so internals don't leak.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	var e *Error
+	if !errors.As(err, &e) {
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+		WriteProblem(w, r, http.StatusInternalServerError, "internal_error", "An unexpected error occurred")
+		return
+	}
+
+	p := newProblem(r, e.Kind.Status(), e.Code, err.Error())
+	p.Errors = e.Fields
+	writeProblem(w, p)
+}
+
+// WriteProblem answers with a problem that has no domain error behind it,
+// such as rate limiting.
+func WriteProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	writeProblem(w, newProblem(r, status, code, detail))
+}
+
+func newProblem(r *http.Request, status int, code, detail string) Problem {
+	return Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Code:     code,
+	}
+}
+
+func writeProblem(w http.ResponseWriter, p Problem) {
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Fallback serves requests with mux, answering those no route matches with
+// problem details rather than the plain text the mux writes.
+func Fallback(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		// The mux answers 404, or 405 with the methods allowed
+		rec := &recorder{header: http.Header{}, status: http.StatusOK}
+		h.ServeHTTP(rec, r)
+		if rec.status == http.StatusMethodNotAllowed {
+			w.Header().Set("Allow", rec.header.Get("Allow"))
+			WriteProblem(w, r, rec.status, "method_not_allowed", fmt.Sprintf("%s is not allowed on %s", r.Method, r.URL.Path))
+			return
+		}
+		WriteProblem(w, r, http.StatusNotFound, "route_not_found", fmt.Sprintf("no route matches %s", r.URL.Path))
+	})
+}
+
+// recorder keeps the status and headers of a response and drops its body.
+type recorder struct {
+	header http.Header
+	status int
+}
+
+func (rec *recorder) Header() http.Header         { return rec.header }
+func (rec *recorder) Write(b []byte) (int, error) { return len(b), nil }
+func (rec *recorder) WriteHeader(status int)      { rec.status = status }
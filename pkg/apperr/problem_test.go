@@ -0,0 +1,54 @@
+package apperr
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFallback(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /flights/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	h := Fallback(mux)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		code   string
+		allow  string
+	}{
+		{name: "matched route", method: http.MethodGet, path: "/flights/1", status: http.StatusOK},
+		{name: "unknown path", method: http.MethodGet, path: "/planes", status: http.StatusNotFound, code: "route_not_found"},
+		{name: "wrong method", method: http.MethodDelete, path: "/flights/1", status: http.StatusMethodNotAllowed, code: "method_not_allowed", allow: "GET, HEAD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if tt.code == "" {
+				return
+			}
+			if ct := w.Header().Get("Content-Type"); ct != ContentType {
+				t.Errorf("Content-Type = %q, want %q", ct, ContentType)
+			}
+			if allow := w.Header().Get("Allow"); allow != tt.allow {
+				t.Errorf("Allow = %q, want %q", allow, tt.allow)
+			}
+			var p Problem
+			if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+				t.Fatal(err)
+			}
+			if p.Code != tt.code || p.Status != tt.status {
+				t.Errorf("problem = %+v", p)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"airline-booking/pkg/redis"
+
+	goredis "github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrNotFound is returned by loaders when the value does not exist. With a
+// NegativeTTL set the miss itself is cached so repeated lookups skip the DB.
+var ErrNotFound = errors.New("cache: not found")
+
+// negativeMarker is stored in place of a value to remember a miss.
+const negativeMarker = "\x00notfound"
+
+// tagTTL bounds how long a tag index lives without new members.
+const tagTTL = 24 * time.Hour
+
+// loadTimeout bounds a shared load, which no single caller can cancel.
+const loadTimeout = 10 * time.Second
+
+// Options control how a single entry is stored.
+type Options struct {
+	TTL         time.Duration
+	NegativeTTL time.Duration
+	Tags        []string
+	// Refresh skips the cached value and reloads it, e.g. right after a write
+	Refresh bool
+}
+
+// Cache is a cache-aside layer on top of Redis. Every Redis failure is
+// treated as a miss so callers always fall back to their loader.
+type Cache struct {
+	client *goredis.Client
+	group  singleflight.Group
+	// Jitter spreads expiries by up to this fraction of the TTL
+	Jitter float64
+}
+
+// New creates a cache backed by the given Redis client.
+func New(client *redis.RedisClient) *Cache {
+	return &Cache{client: client.GetClient(), Jitter: 0.1}
+}
+
+// GetOrLoad returns the cached value for key or calls load on a miss and
+// caches the result. Concurrent misses for the same key share one load,
+// which runs detached from the caller that started it so its cancellation
+// does not fail the others; each caller still stops waiting when its own
+// ctx is done.
+func GetOrLoad[T any](ctx context.Context, c *Cache, key string, opts Options, load func(context.Context) (T, error)) (T, error) {
+	var zero T
+
+	groupKey := key
+	if opts.Refresh {
+		// Don't join a load that may have started before the caller's write
+		groupKey = key + "\x00refresh"
+	} else if val, found, err := Get[T](ctx, c, key); found {
+		return val, err
+	}
+
+	ch := c.group.DoChan(groupKey, func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), loadTimeout)
+		defer cancel()
+
+		val, err := load(ctx)
+		if errors.Is(err, ErrNotFound) {
+			if opts.NegativeTTL > 0 {
+				if err := c.setRaw(ctx, key, negativeMarker, c.jitter(opts.NegativeTTL), opts.Tags); err != nil {
+					logError("set", key, err)
+				}
+			}
+			return nil, err
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := c.Set(ctx, key, val, opts); err != nil {
+			logError("set", key, err)
+		}
+		return val, nil
+	})
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		return res.Val.(T), nil
+	}
+}
+
+// Get reads a typed value. found is false on a miss or when Redis is
+// unavailable; a cached negative entry is found with ErrNotFound.
+func Get[T any](ctx context.Context, c *Cache, key string) (val T, found bool, err error) {
+	data, err := c.client.Get(ctx, key).Result()
+	if err != nil {
+		if !errors.Is(err, goredis.Nil) {
+			logError("get", key, err)
+		}
+		return val, false, nil
+	}
+	if data == negativeMarker {
+		return val, true, ErrNotFound
+	}
+	if err := json.Unmarshal([]byte(data), &val); err != nil {
+		log.Printf("Cache entry %s is corrupt, ignoring: %v", key, err)
+		return val, false, nil
+	}
+	return val, true, nil
+}
+
+// Set stores value as JSON under key with a jittered TTL and registers it
+// under the given tags.
+func (c *Cache) Set(ctx context.Context, key string, value any, opts Options) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return c.setRaw(ctx, key, string(data), c.jitter(opts.TTL), opts.Tags)
+}
+
+func (c *Cache) setRaw(ctx context.Context, key, data string, ttl time.Duration, tags []string) error {
+	pipe := c.client.TxPipeline()
+	pipe.Set(ctx, key, data, ttl)
+	for _, tag := range tags {
+		pipe.SAdd(ctx, tagKey(tag), key)
+		pipe.Expire(ctx, tagKey(tag), tagTTL)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Exists reports whether key is present. Redis errors count as absent.
+func (c *Cache) Exists(ctx context.Context, key string) bool {
+	n, err := c.client.Exists(ctx, key).Result()
+	if err != nil {
+		logError("exists", key, err)
+		return false
+	}
+	return n > 0
+}
+
+// Delete removes the given keys.
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return c.client.Del(ctx, keys...).Err()
+}
+
+// invalidateScript deletes every key registered under a tag and the tag itself.
+var invalidateScript = goredis.NewScript(`
+local keys = redis.call('SMEMBERS', KEYS[1])
+for i = 1, #keys, 500 do
+	redis.call('DEL', unpack(keys, i, math.min(i + 499, #keys)))
+end
+redis.call('DEL', KEYS[1])
+return #keys
+`)
+
+// InvalidateTags removes every entry stored with any of the given tags.
+func (c *Cache) InvalidateTags(ctx context.Context, tags ...string) error {
+	var firstErr error
+	for _, tag := range tags {
+		if err := invalidateScript.Run(ctx, c.client, []string{tagKey(tag)}).Err(); err != nil {
+			logError("invalidate", tagKey(tag), err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+func (c *Cache) jitter(ttl time.Duration) time.Duration {
+	if ttl <= 0 || c.Jitter <= 0 {
+		return ttl
+	}
+	spread := float64(ttl) * c.Jitter
+	return ttl + time.Duration(spread*(2*rand.Float64()-1))
+}
+
+func tagKey(tag string) string {
+	return "tag:" + tag
+}
+
+// logError logs cache failures, except while the breaker is open to avoid
+// flooding the log during a Redis outage.
+func logError(op, key string, err error) {
+	if errors.Is(err, redis.ErrCircuitOpen) {
+		return
+	}
+	log.Printf("Cache %s failed for %s: %v", op, key, err)
+}
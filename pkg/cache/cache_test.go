@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func newTestCache(t *testing.T) *Cache {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return &Cache{client: client}
+}
+
+func TestGetOrLoadCachesResult(t *testing.T) {
+	c := newTestCache(t)
+	ctx := context.Background()
+	calls := 0
+	load := func(context.Context) (int, error) {
+		calls++
+		return 42, nil
+	}
+
+	for range 2 {
+		v, err := GetOrLoad(ctx, c, "answer", Options{TTL: time.Minute}, load)
+		if err != nil || v != 42 {
+			t.Fatalf("GetOrLoad = %d, %v; want 42, nil", v, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("loader ran %d times, want 1", calls)
+	}
+}
+
+func TestGetOrLoadSurvivesFirstCallerCancelling(t *testing.T) {
+	c := newTestCache(t)
+	started, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	load := func(ctx context.Context) (string, error) {
+		once.Do(func() { close(started) })
+		select {
+		case <-release:
+			return "loaded", ctx.Err()
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	firstCtx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := GetOrLoad(firstCtx, c, "shared", Options{TTL: time.Minute}, load)
+		firstErr <- err
+	}()
+	<-started
+
+	waiter := make(chan error, 1)
+	go func() {
+		v, err := GetOrLoad(context.Background(), c, "shared", Options{TTL: time.Minute}, load)
+		if err == nil && v != "loaded" {
+			err = errors.New("unexpected value " + v)
+		}
+		waiter <- err
+	}()
+
+	// Give the waiter time to join the load in flight
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled caller got %v, want context.Canceled", err)
+	}
+	close(release)
+	if err := <-waiter; err != nil {
+		t.Errorf("waiter failed after the first caller was cancelled: %v", err)
+	}
+}
+
+func TestGetOrLoadNegativeCache(t *testing.T) {
+	c := newTestCache(t)
+	ctx := context.Background()
+	calls := 0
+	load := func(context.Context) (int, error) {
+		calls++
+		return 0, ErrNotFound
+	}
+
+	opts := Options{TTL: time.Minute, NegativeTTL: time.Minute}
+	for range 2 {
+		if _, err := GetOrLoad(ctx, c, "missing", opts, load); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("GetOrLoad error = %v, want ErrNotFound", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("loader ran %d times, want 1", calls)
+	}
+}
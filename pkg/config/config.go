@@ -1,10 +1,12 @@
 package config
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -13,6 +15,16 @@ type Config struct {
 	Postgres PostgresConfig
 	Kafka    KafkaConfig
 	Redis    RedisConfig
+	Secrets  SecretsConfig
+	Runtime  RuntimeConfig
+	// Services holds one block per service role, keyed by name
+	Services map[string]ServiceConfig
+
+	// SecretStore holds the values of settings given as secret references
+	SecretStore *SecretStore `mapstructure:"-"`
+
+	// source remembers where the config was read from so it can be reloaded
+	source *Options
 }
 
 /*---------------Postgres-----------------*/
@@ -23,6 +35,46 @@ type PostgresConfig struct {
 	Password string
 	DBName   string
 	SSLMode  string
+	// TLS certificate paths, used with sslmode verify-ca / verify-full
+	SSLRootCert string `mapstructure:"sslrootcert"`
+	SSLCert     string `mapstructure:"sslcert"`
+	SSLKey      string `mapstructure:"sslkey"`
+
+	ApplicationName  string        `mapstructure:"applicationName"`
+	SearchPath       string        `mapstructure:"searchPath"`
+	StatementTimeout time.Duration `mapstructure:"statementTimeout"`
+	ConnectTimeout   time.Duration `mapstructure:"connectTimeout"`
+
+	// Pool settings default to 20 open and 5 idle connections of at most an
+	// hour each; an explicit 0 means no limit, as in database/sql
+	Pool struct {
+		MaxOpenConns    int           `mapstructure:"maxOpenConns"`
+		MaxIdleConns    int           `mapstructure:"maxIdleConns"`
+		ConnMaxLifetime time.Duration `mapstructure:"connMaxLifetime"`
+		ConnMaxIdleTime time.Duration `mapstructure:"connMaxIdleTime"`
+	}
+
+	// Replicas serve read-only queries; they share the primary's credentials
+	Replicas []ReplicaConfig
+	// MaxReplicaLag is how far behind a replica may be before reads go to the primary
+	MaxReplicaLag time.Duration `mapstructure:"maxReplicaLag"`
+	// ReadYourWrites is how long a client reads from the primary after its own write
+	ReadYourWrites time.Duration `mapstructure:"readYourWrites"`
+
+	passwordFunc func() string
+}
+
+// CurrentPassword returns the password, following rotation when it is a secret reference.
+func (c *PostgresConfig) CurrentPassword() string {
+	if c.passwordFunc != nil {
+		return c.passwordFunc()
+	}
+	return c.Password
+}
+
+type ReplicaConfig struct {
+	Host string
+	Port int
 }
 
 /*-------------------- Kafka --------------------*/
@@ -51,61 +103,218 @@ type RedisConfig struct {
 	ReadTimeout  string
 	WriteTimeout string
 	DialTimeout  string
+	// ReconnectInterval is how often Redis is probed in the background
+	ReconnectInterval time.Duration `mapstructure:"reconnectInterval"`
+	Breaker           struct {
+		FailureThreshold int           `mapstructure:"failureThreshold"`
+		OpenTimeout      time.Duration `mapstructure:"openTimeout"`
+	}
+
+	passwordFunc func() string
 }
 
-func LoadConfig() (*Config, error) {
-	absHome := os.Getenv("ABS_HOME")
-	if absHome == "" {
-		return nil, fmt.Errorf("ABS_HOME environment variable is not set")
+// CurrentPassword returns the password, following rotation when it is a secret reference.
+func (c *RedisConfig) CurrentPassword() string {
+	if c.passwordFunc != nil {
+		return c.passwordFunc()
 	}
+	return c.Password
+}
+
+/*-------------------- Secrets --------------------*/
+type SecretsConfig struct {
+	// RefreshInterval is how often secret references are resolved again
+	RefreshInterval time.Duration `mapstructure:"refreshInterval"`
+	// LocalFile backs ${local:name}; defaults to secrets.local.yml next to the config
+	LocalFile string `mapstructure:"localFile"`
+}
 
-	configDir := filepath.Join(absHome, "configs")
+/*-------------------- Services --------------------*/
+type ServiceConfig struct {
+	// Listen is the HTTP listen address, e.g. ":8080"
+	Listen string
+	TLS    struct {
+		CertFile string `mapstructure:"certFile"`
+		KeyFile  string `mapstructure:"keyFile"`
+	} `mapstructure:"tls"`
+	// Topics default to kafka.topic when unset
+	Topics struct {
+		Produce string
+		Consume []string
+	}
+	// ConsumerGroup defaults to kafka.groupId when unset
+	ConsumerGroup string `mapstructure:"consumerGroup"`
+	// Features switches parts of the service on or off; unlisted features are on
+	Features map[string]bool
+}
 
-	//var cfg Config {}
-	cfg := &Config{}
+// Enabled reports whether a feature is switched on for the service. Feature
+// names are case-insensitive, since viper lowercases map keys.
+func (s *ServiceConfig) Enabled(feature string) bool {
+	on, ok := s.Features[strings.ToLower(feature)]
+	return !ok || on
+}
 
-	// --- Postgres ---
-	dbViper := viper.New()
-	dbViper.SetConfigName("dbconfig")
-	dbViper.SetConfigType("yaml")
-	dbViper.AddConfigPath(configDir)
-	if err := dbViper.ReadInConfig(); err != nil {
-		log.Fatalf("Error reading dbconfig.yml: %v", err)
+// Service returns the settings of the role this process runs as: the one
+// named by --service if given, else name. Unset topics and consumer group
+// fall back to the kafka section.
+func (c *Config) Service(name string) (*ServiceConfig, error) {
+	if c.source != nil && c.source.Service != "" {
+		name = c.source.Service
 	}
-	if err := dbViper.UnmarshalKey("postgres", &cfg.Postgres); err != nil {
-		log.Fatalf("Error decoding Postgres config: %v", err)
+	svc, ok := c.Services[name]
+	if !ok {
+		return nil, fmt.Errorf("no configuration for service %q", name)
 	}
 
-	// --- Kafka ---
-	kafkaViper := viper.New()
-	kafkaViper.SetConfigName("kafkaconfig")
-	kafkaViper.SetConfigType("yaml")
-	kafkaViper.AddConfigPath(configDir)
-	if err := kafkaViper.ReadInConfig(); err != nil {
-		log.Fatalf("Error reading kafkaconfig.yml: %v", err)
+	if svc.Topics.Produce == "" {
+		svc.Topics.Produce = c.Kafka.Topic
 	}
-	if err := kafkaViper.UnmarshalKey("kafka", &cfg.Kafka); err != nil {
-		log.Fatalf("Error decoding Kafka config: %v", err)
+	if len(svc.Topics.Consume) == 0 {
+		svc.Topics.Consume = []string{c.Kafka.Topic}
 	}
+	if svc.ConsumerGroup == "" {
+		svc.ConsumerGroup = c.Kafka.GroupID
+	}
+	return &svc, nil
+}
 
-	// Inside LoadConfig() function, after loading Kafka config:
-	if len(cfg.Kafka.Brokers) == 0 {
-		log.Printf("Warning: No Kafka brokers configured")
+/*-------------------- Runtime --------------------*/
+// RuntimeConfig holds the settings that are applied live on reload.
+type RuntimeConfig struct {
+	LogLevel string `mapstructure:"logLevel"`
+	Cache    struct {
+		FlightsTTL  time.Duration `mapstructure:"flightsTTL"`
+		BookingsTTL time.Duration `mapstructure:"bookingsTTL"`
 	}
-	//log.Printf("Loaded Kafka config: brokers=%v, topic=%s", cfg.Kafka.Brokers, cfg.Kafka.Topic)
+	RateLimit RateLimitConfig `mapstructure:"rateLimit"`
+	// ExchangeRates.File is a .csv or .json rate file used to show prices
+	// in other currencies, relative to the config directory; empty
+	// disables conversion
+	ExchangeRates struct {
+		File string
+	} `mapstructure:"exchangeRates"`
+}
 
-	// --- Redis ---
-	redisViper := viper.New()
-	redisViper.SetConfigName("redisconfig")
-	redisViper.SetConfigType("yaml")
-	redisViper.AddConfigPath(configDir)
-	if err := redisViper.ReadInConfig(); err != nil {
-		log.Fatalf("Error reading redisconfig.yml: %v", err)
+type RateLimitConfig struct {
+	// RequestsPerSecond per client IP; 0 disables rate limiting
+	RequestsPerSecond float64 `mapstructure:"requestsPerSecond"`
+	Burst             int
+}
+
+// LoadConfig reads the configuration selected by the command-line flags and
+// environment. With --print-config it prints the effective configuration
+// and exits.
+func LoadConfig() (*Config, error) {
+	opts, err := ParseFlags(os.Args[1:])
+	if err != nil {
+		return nil, err
 	}
-	if err := redisViper.UnmarshalKey("redis", &cfg.Redis); err != nil {
-		log.Fatalf("Error decoding Redis config: %v", err)
+
+	cfg, err := Load(opts)
+	if err != nil {
+		return nil, err
 	}
 
+	if opts.PrintConfig {
+		Print(os.Stdout, cfg)
+		os.Exit(0)
+	}
 	return cfg, nil
+}
+
+// Load builds the configuration from YAML, then environment variables, then
+// --set overrides, each layer taking precedence over the previous one,
+// resolves secret references and validates the result.
+func Load(opts *Options) (*Config, error) {
+	v := viper.New()
+
+	if opts.File != "" {
+		// --- Single file with postgres, kafka and redis sections ---
+		v.SetConfigFile(opts.File)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", opts.File, err)
+		}
+	} else {
+		// --- One file per component ---
+		configDir := opts.configDir()
+		v.SetConfigType("yaml")
+		v.AddConfigPath(configDir)
+		for _, name := range []string{"dbconfig", "kafkaconfig", "redisconfig"} {
+			v.SetConfigName(name)
+			if err := v.MergeInConfig(); err != nil {
+				return nil, fmt.Errorf("error reading %s.yml: %w", name, err)
+			}
+		}
+
+		// These files are optional, the defaults below apply without them
+		for _, name := range []string{"servicesconfig", "runtimeconfig"} {
+			v.SetConfigName(name)
+			if err := v.MergeInConfig(); err != nil {
+				var notFound viper.ConfigFileNotFoundError
+				if !errors.As(err, &notFound) {
+					return nil, fmt.Errorf("error reading %s.yml: %w", name, err)
+				}
+			}
+		}
+	}
+
+	v.SetDefault("postgres.connectTimeout", 3*time.Second)
+	v.SetDefault("postgres.pool.maxOpenConns", 20)
+	v.SetDefault("postgres.pool.maxIdleConns", 5)
+	v.SetDefault("postgres.pool.connMaxLifetime", time.Hour)
+	v.SetDefault("postgres.maxReplicaLag", 2*time.Second)
+	v.SetDefault("postgres.readYourWrites", 5*time.Second)
+	v.SetDefault("secrets.refreshInterval", time.Minute)
+	v.SetDefault("runtime.logLevel", "info")
+	v.SetDefault("runtime.cache.flightsTTL", 10*time.Minute)
+	v.SetDefault("runtime.cache.bookingsTTL", 30*time.Second)
+	v.SetDefault("runtime.rateLimit.requestsPerSecond", 0)
+	v.SetDefault("runtime.rateLimit.burst", 0)
+	v.SetDefault("services.flight-service.listen", ":8080")
+	v.SetDefault("services.booking-service.listen", ":8081")
+	bindEnv(v)
+
+	for _, o := range opts.Overrides {
+		key, value, ok := strings.Cut(o, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --set %q, expected key=value", o)
+		}
+		v.Set(key, value)
+	}
+
+	cfg := &Config{source: opts}
+	if err := v.Unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("error decoding config: %w", err)
+	}
+	if bindListEnv(v, cfg) {
+		cfg = &Config{source: opts}
+		if err := v.Unmarshal(cfg); err != nil {
+			return nil, fmt.Errorf("error decoding config: %w", err)
+		}
+	}
+	if f := cfg.Runtime.ExchangeRates.File; f != "" && !filepath.IsAbs(f) {
+		cfg.Runtime.ExchangeRates.File = filepath.Join(opts.baseDir(), f)
+	}
+
+	// --- Secret references ---
+	localFile := cfg.Secrets.LocalFile
+	if localFile == "" {
+		localFile = filepath.Join(opts.baseDir(), "secrets.local.yml")
+	}
+	RegisterSecretProvider("local", LocalFileProvider{Path: localFile})
+
+	store, problems := resolveSecrets(cfg)
+	cfg.SecretStore = store
+	cfg.Postgres.passwordFunc = store.getter("postgres.password")
+	cfg.Redis.passwordFunc = store.getter("redis.password")
+
+	if err := cfg.Validate(); err != nil {
+		problems.Problems = append(problems.Problems, err.(*ValidationError).Problems...)
+	}
+	if len(problems.Problems) > 0 {
+		return nil, problems
+	}
 
+	return cfg, nil
 }
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// baseConfig ends in the postgres section so tests can add to it.
+const baseConfig = `
+kafka:
+  brokers: ["127.0.0.1:9092"]
+  topic: flight-events
+  groupId: flight-service-group
+  autoOffsetReset: earliest
+  consumer:
+    initialOffset: newest
+redis:
+  address: "127.0.0.1:6379"
+postgres:
+  host: localhost
+  port: 5432
+  user: airline
+  dbname: airline
+  sslmode: disable
+`
+
+// loadTest loads baseConfig followed by extra postgres settings from a
+// single file.
+func loadTest(t *testing.T, extra string, overrides ...string) *Config {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(file, []byte(baseConfig+extra), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(&Options{File: file, Overrides: overrides})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return cfg
+}
+
+func TestPoolDefaults(t *testing.T) {
+	cfg := loadTest(t, "")
+	pool := cfg.Postgres.Pool
+	if pool.MaxOpenConns != 20 || pool.MaxIdleConns != 5 || pool.ConnMaxLifetime != time.Hour {
+		t.Errorf("pool defaults = %+v", pool)
+	}
+	if cfg.Postgres.ConnectTimeout != 3*time.Second {
+		t.Errorf("connectTimeout = %v, want 3s", cfg.Postgres.ConnectTimeout)
+	}
+}
+
+func TestPoolExplicitZero(t *testing.T) {
+	cfg := loadTest(t, "", "postgres.pool.maxIdleConns=0", "postgres.pool.connMaxLifetime=0s", "postgres.readYourWrites=0s")
+	if cfg.Postgres.Pool.MaxIdleConns != 0 {
+		t.Errorf("maxIdleConns = %d, want the explicit 0", cfg.Postgres.Pool.MaxIdleConns)
+	}
+	if cfg.Postgres.Pool.ConnMaxLifetime != 0 {
+		t.Errorf("connMaxLifetime = %v, want the explicit 0", cfg.Postgres.Pool.ConnMaxLifetime)
+	}
+	if cfg.Postgres.ReadYourWrites != 0 {
+		t.Errorf("readYourWrites = %v, want the explicit 0", cfg.Postgres.ReadYourWrites)
+	}
+	if cfg.Postgres.Pool.MaxOpenConns != 20 {
+		t.Errorf("maxOpenConns = %d, want the default 20", cfg.Postgres.Pool.MaxOpenConns)
+	}
+}
+
+const replicasConfig = `  replicas:
+    - host: replica-a
+    - host: replica-b
+      port: 5433
+`
+
+func TestReplicaEnvOverride(t *testing.T) {
+	t.Setenv("AIRLINE_POSTGRES_REPLICAS_1_HOST", "replica-c")
+	t.Setenv("AIRLINE_POSTGRES_REPLICAS_0_PORT", "6432")
+	t.Setenv("AIRLINE_POSTGRES_REPLICAS_5_HOST", "ignored")
+
+	cfg := loadTest(t, replicasConfig)
+	want := []ReplicaConfig{{Host: "replica-a", Port: 6432}, {Host: "replica-c", Port: 5433}}
+	if len(cfg.Postgres.Replicas) != len(want) {
+		t.Fatalf("replicas = %+v, want %+v", cfg.Postgres.Replicas, want)
+	}
+	for i := range want {
+		if cfg.Postgres.Replicas[i] != want[i] {
+			t.Errorf("replicas[%d] = %+v, want %+v", i, cfg.Postgres.Replicas[i], want[i])
+		}
+	}
+}
+
+func TestKeysOfReplicas(t *testing.T) {
+	cfg := &Config{}
+	cfg.Postgres.Replicas = []ReplicaConfig{{Host: "a"}, {Host: "b", Port: 5433}}
+	var out strings.Builder
+	Print(&out, cfg)
+	for _, line := range []string{"postgres.replicas.0.host", "postgres.replicas.1.port", "AIRLINE_POSTGRES_REPLICAS_1_PORT"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("printed config lacks %s:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestDiffReportsRemovedReplica(t *testing.T) {
+	old, next := &Config{}, &Config{}
+	old.Postgres.Replicas = []ReplicaConfig{{Host: "a", Port: 5432}}
+	changes := diff(old, next)
+	if len(changes) != 2 || !strings.Contains(changes[0], "postgres.replicas.0.host") {
+		t.Errorf("changes = %q", changes)
+	}
+}
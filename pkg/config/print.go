@@ -0,0 +1,29 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Print writes the effective configuration as key = value lines, with
+// secrets masked, alongside the environment variable that overrides each key.
+// Values read from secret references are shown as the reference.
+func Print(w io.Writer, cfg *Config) {
+	walk(reflect.ValueOf(*cfg), "", func(key string, v reflect.Value) {
+		value := fmt.Sprint(v.Interface())
+		if ref, ok := cfg.SecretStore.ref(key); ok {
+			value = ref
+		} else if isSecret(key) && value != "" {
+			value = "******"
+		}
+		fmt.Fprintf(w, "%-40s = %-30s # %s\n", key, value, EnvName(key))
+	})
+}
+
+// isSecret reports whether the value of key must not be printed.
+func isSecret(key string) bool {
+	name := strings.ToLower(key[strings.LastIndex(key, ".")+1:])
+	return strings.Contains(name, "password") || strings.Contains(name, "secret") || strings.Contains(name, "token")
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// reloadDebounce groups the burst of events editors emit for one save.
+const reloadDebounce = 300 * time.Millisecond
+
+// Reloader re-reads the configuration when its files change and hands the
+// runtime section to subscribers. Other sections are only reported, since
+// they need a restart to take effect.
+type Reloader struct {
+	mu   sync.Mutex
+	cfg  *Config
+	subs []func(RuntimeConfig)
+}
+
+// NewReloader watches the sources cfg was loaded from.
+func NewReloader(cfg *Config) *Reloader {
+	return &Reloader{cfg: cfg}
+}
+
+// Subscribe calls fn with the setting picked by get right away and again
+// whenever a reload changes it.
+func Subscribe[T comparable](r *Reloader, get func(RuntimeConfig) T, fn func(T)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	last := get(r.cfg.Runtime)
+	fn(last)
+	r.subs = append(r.subs, func(rt RuntimeConfig) {
+		if v := get(rt); v != last {
+			last = v
+			fn(v)
+		}
+	})
+}
+
+// Reload reads the configuration again. An invalid configuration is
+// rejected and the current one kept.
+func (r *Reloader) Reload() error {
+	next, err := Load(r.cfg.source)
+	if err != nil {
+		log.Printf("Config reload rejected, keeping current config: %v", err)
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changes := diff(r.cfg, next)
+	if len(changes) == 0 {
+		return nil
+	}
+	for _, c := range changes {
+		log.Printf("Config reload: %s", c)
+	}
+
+	r.cfg.Runtime = next.Runtime
+	for _, sub := range r.subs {
+		sub(next.Runtime)
+	}
+	return nil
+}
+
+// Watch reloads the configuration whenever one of its files changes. It
+// blocks until ctx is done.
+func (r *Reloader) Watch(ctx context.Context) {
+	changed := make(chan struct{}, 1)
+	for _, file := range r.cfg.source.files() {
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+		v := viper.New()
+		v.SetConfigFile(file)
+		v.OnConfigChange(func(fsnotify.Event) {
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		})
+		v.WatchConfig()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+			time.Sleep(reloadDebounce)
+			select {
+			case <-changed:
+			default:
+			}
+			r.Reload()
+		}
+	}
+}
+
+// files lists the config files read for these options.
+func (o *Options) files() []string {
+	if o.File != "" {
+		return []string{o.File}
+	}
+	dir := o.configDir()
+	var files []string
+	for _, name := range []string{"dbconfig", "kafkaconfig", "redisconfig", "servicesconfig", "runtimeconfig"} {
+		files = append(files, filepath.Join(dir, name+".yml"))
+	}
+	return files
+}
+
+// diff describes every setting that differs between two configs.
+func diff(old, next *Config) []string {
+	before := map[string]string{}
+	walk(reflect.ValueOf(*old), "", func(key string, v reflect.Value) {
+		before[key] = fmt.Sprint(v.Interface())
+	})
+
+	var changes []string
+	report := func(key, value string) {
+		if before[key] == value {
+			return
+		}
+
+		change := fmt.Sprintf("%s changed from %q to %q", key, before[key], value)
+		if _, ok := next.SecretStore.ref(key); ok || isSecret(key) {
+			change = key + " changed"
+		}
+		if !strings.HasPrefix(key, "runtime.") {
+			change += " (restart required)"
+		}
+		changes = append(changes, change)
+	}
+	after := map[string]bool{}
+	walk(reflect.ValueOf(*next), "", func(key string, v reflect.Value) {
+		after[key] = true
+		report(key, fmt.Sprint(v.Interface()))
+	})
+	// Keys of list entries that were removed
+	for _, key := range slices.Sorted(maps.Keys(before)) {
+		if !after[key] {
+			report(key, "")
+		}
+	}
+	return changes
+}
@@ -0,0 +1,204 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// secretRef matches a config value that refers to a secret, e.g.
+// ${file:/run/secrets/pg_password}, ${env:PG_PASSWORD} or ${local:redis_password}.
+var secretRef = regexp.MustCompile(`^\$\{([a-zA-Z][a-zA-Z0-9_-]*):(.+)\}$`)
+
+// SecretProvider resolves a reference to its current secret value.
+type SecretProvider interface {
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// SecretProviderFunc adapts a function to a SecretProvider.
+type SecretProviderFunc func(ctx context.Context, ref string) (string, error)
+
+func (f SecretProviderFunc) Resolve(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]SecretProvider{
+		"env":  SecretProviderFunc(resolveEnv),
+		"file": SecretProviderFunc(resolveFile),
+	}
+)
+
+// RegisterSecretProvider makes a provider available as ${scheme:ref}.
+// Call it before LoadConfig.
+func RegisterSecretProvider(scheme string, p SecretProvider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[scheme] = p
+}
+
+func provider(scheme string) (SecretProvider, bool) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	p, ok := providers[scheme]
+	return p, ok
+}
+
+func resolveEnv(_ context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// resolveFile reads a secret file such as a Docker or Kubernetes secret mount.
+func resolveFile(_ context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// LocalFileProvider serves secrets from a flat YAML file of name: value
+// pairs. It is meant for development, in place of a real secret store.
+type LocalFileProvider struct {
+	Path string
+}
+
+func (p LocalFileProvider) Resolve(_ context.Context, name string) (string, error) {
+	v := viper.New()
+	v.SetConfigFile(p.Path)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return "", fmt.Errorf("error reading %s: %w", p.Path, err)
+	}
+	if !v.IsSet(name) {
+		return "", fmt.Errorf("secret %s not found in %s", name, p.Path)
+	}
+	return v.GetString(name), nil
+}
+
+// SecretStore remembers which config keys came from secret references and
+// keeps their latest values.
+type SecretStore struct {
+	mu     sync.RWMutex
+	refs   map[string]string
+	values map[string]string
+}
+
+// resolveSecrets replaces every secret reference in cfg with its value and
+// reports every reference that could not be resolved.
+func resolveSecrets(cfg *Config) (*SecretStore, *ValidationError) {
+	store := &SecretStore{refs: map[string]string{}, values: map[string]string{}}
+	problems := &ValidationError{}
+
+	walk(reflect.ValueOf(cfg).Elem(), "", func(key string, v reflect.Value) {
+		if v.Kind() != reflect.String || !secretRef.MatchString(v.String()) {
+			return
+		}
+		if !v.CanSet() {
+			problems.add(key, "secret references are not supported here")
+			return
+		}
+		ref := v.String()
+		value, err := resolve(context.Background(), ref)
+		if err != nil {
+			problems.add(key, "cannot resolve %s: %v", ref, err)
+			return
+		}
+		v.SetString(value)
+		store.refs[key] = ref
+		store.values[key] = value
+	})
+
+	return store, problems
+}
+
+func resolve(ctx context.Context, ref string) (string, error) {
+	m := secretRef.FindStringSubmatch(ref)
+	p, ok := provider(m[1])
+	if !ok {
+		return "", fmt.Errorf("unknown secret provider %q", m[1])
+	}
+	return p.Resolve(ctx, m[2])
+}
+
+// Get returns the latest value of a config key that came from a secret.
+func (s *SecretStore) Get(key string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.values[key]
+	return value, ok
+}
+
+// ref returns the secret reference a config key was read from.
+func (s *SecretStore) ref(key string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	ref, ok := s.refs[key]
+	return ref, ok
+}
+
+// getter returns a function reporting the latest value of key, or nil if
+// key is not a secret reference.
+func (s *SecretStore) getter(key string) func() string {
+	if _, ok := s.refs[key]; !ok {
+		return nil
+	}
+	return func() string {
+		value, _ := s.Get(key)
+		return value
+	}
+}
+
+// Watch resolves all secret references every interval so rotated
+// credentials are picked up by new connections. It blocks until ctx is done.
+func (s *SecretStore) Watch(ctx context.Context, interval time.Duration) {
+	if len(s.refs) == 0 || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.refresh(ctx)
+		}
+	}
+}
+
+func (s *SecretStore) refresh(ctx context.Context) {
+	for key, ref := range s.refs {
+		value, err := resolve(ctx, ref)
+		if err != nil {
+			// Keep the last good value; the old credential may still work
+			log.Printf("Failed to refresh secret %s: %v", key, err)
+			continue
+		}
+
+		s.mu.Lock()
+		changed := s.values[key] != value
+		s.values[key] = value
+		s.mu.Unlock()
+
+		if changed {
+			log.Printf("Secret %s rotated", key)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix is prepended to every environment override, e.g. AIRLINE_POSTGRES_PASSWORD.
+const EnvPrefix = "AIRLINE"
+
+// Options selects where the configuration is read from.
+type Options struct {
+	// Dir holds dbconfig.yml, kafkaconfig.yml and redisconfig.yml
+	Dir string
+	// File is a single YAML file with postgres, kafka and redis sections
+	File string
+	// Service selects the services block this process runs as
+	Service string
+	// Overrides are key=value pairs such as postgres.host=db
+	Overrides   []string
+	PrintConfig bool
+}
+
+// ParseFlags reads the config flags shared by all services.
+func ParseFlags(args []string) (*Options, error) {
+	opts := &Options{}
+	fs := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	fs.StringVar(&opts.Dir, "config-dir", os.Getenv(EnvPrefix+"_CONFIG_DIR"), "directory with dbconfig.yml, kafkaconfig.yml and redisconfig.yml")
+	fs.StringVar(&opts.File, "config-file", os.Getenv(EnvPrefix+"_CONFIG_FILE"), "single YAML file with postgres, kafka and redis sections")
+	fs.StringVar(&opts.Service, "service", os.Getenv(EnvPrefix+"_SERVICE"), "services block to run as, e.g. booking-worker")
+	fs.Func("set", "override a config value, e.g. --set postgres.host=db (repeatable)", func(s string) error {
+		opts.Overrides = append(opts.Overrides, s)
+		return nil
+	})
+	fs.BoolVar(&opts.PrintConfig, "print-config", false, "print the effective configuration with secrets masked and exit")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// configDir resolves the config directory, falling back to $ABS_HOME/configs
+// and then ./configs.
+func (o *Options) configDir() string {
+	if o.Dir != "" {
+		return o.Dir
+	}
+	if absHome := os.Getenv("ABS_HOME"); absHome != "" {
+		return filepath.Join(absHome, "configs")
+	}
+	return "configs"
+}
+
+// baseDir is the directory the configuration is read from.
+func (o *Options) baseDir() string {
+	if o.File != "" {
+		return filepath.Dir(o.File)
+	}
+	return o.configDir()
+}
+
+// bindEnv lets every config field be overridden by an environment variable
+// named after its key, e.g. redis.breaker.openTimeout is
+// AIRLINE_REDIS_BREAKER_OPENTIMEOUT. Fields of the services blocks already
+// present are bound too, e.g. AIRLINE_SERVICES_BOOKING_SERVICE_LISTEN; fields
+// of list entries are applied by bindListEnv.
+func bindEnv(v *viper.Viper) {
+	for _, key := range Keys() {
+		v.BindEnv(key, EnvName(key))
+	}
+
+	for name := range v.GetStringMap("services") {
+		walk(reflect.ValueOf(ServiceConfig{}), "services."+name, func(key string, _ reflect.Value) {
+			v.BindEnv(key, EnvName(key))
+		})
+	}
+}
+
+// bindListEnv applies environment overrides to the fields of list entries
+// present in cfg, e.g. AIRLINE_POSTGRES_REPLICAS_0_HOST, which viper cannot
+// bind by key. It reports whether any was found, in which case the
+// configuration must be decoded again.
+func bindListEnv(v *viper.Viper, cfg *Config) bool {
+	found := false
+	walk(reflect.ValueOf(*cfg), "", func(key string, _ reflect.Value) {
+		value, ok := os.LookupEnv(EnvName(key))
+		if !ok {
+			return
+		}
+		parts := strings.Split(key, ".")
+		for i, part := range parts {
+			index, err := strconv.Atoi(part)
+			if err != nil || i == 0 || i == len(parts)-1 {
+				continue
+			}
+			list := strings.Join(parts[:i], ".")
+			entries, _ := v.Get(list).([]any)
+			if index >= len(entries) {
+				return
+			}
+			entry, ok := entries[index].(map[string]any)
+			if !ok {
+				return
+			}
+			entry[strings.Join(parts[i+1:], ".")] = value
+			v.Set(list, entries)
+			found = true
+			return
+		}
+	})
+	return found
+}
+
+// EnvName returns the environment variable that overrides key.
+func EnvName(key string) string {
+	name := strings.NewReplacer(".", "_", "-", "_").Replace(key)
+	return EnvPrefix + "_" + strings.ToUpper(name)
+}
+
+// Keys lists the dotted key of every settable config field.
+func Keys() []string {
+	var keys []string
+	walk(reflect.ValueOf(Config{}), "", func(key string, _ reflect.Value) {
+		keys = append(keys, key)
+	})
+	return keys
+}
+
+// walk calls fn for every leaf field of a config struct with its dotted key.
+// Scalar lists are leaves, set as comma-separated values. Lists of structs,
+// such as postgres.replicas, are walked per entry by index, e.g.
+// postgres.replicas.0.host, and maps of structs, such as services, per
+// entry in key order.
+func walk(v reflect.Value, prefix string, fn func(key string, v reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("mapstructure")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		if field.Type.Kind() == reflect.Struct && field.Type.PkgPath() != "time" {
+			walk(v.Field(i), key, fn)
+			continue
+		}
+		if field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct {
+			list := v.Field(i)
+			for j := 0; j < list.Len(); j++ {
+				walk(list.Index(j), key+"."+strconv.Itoa(j), fn)
+			}
+			continue
+		}
+		if field.Type.Kind() == reflect.Map && field.Type.Elem().Kind() == reflect.Struct {
+			m := v.Field(i)
+			names := make([]string, 0, m.Len())
+			for _, k := range m.MapKeys() {
+				names = append(names, k.String())
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				walk(m.MapIndex(reflect.ValueOf(name)), key+"."+name, fn)
+			}
+			continue
+		}
+		fn(key, v.Field(i))
+	}
+}
@@ -0,0 +1,199 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FieldError is a single problem with one config key.
+type FieldError struct {
+	Key     string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Key + ": " + e.Message
+}
+
+// ValidationError collects every problem found in a Config.
+type ValidationError struct {
+	Problems []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		lines[i] = "  " + p.Error()
+	}
+	return fmt.Sprintf("invalid configuration (%d problems):\n%s", len(e.Problems), strings.Join(lines, "\n"))
+}
+
+func (e *ValidationError) add(key, format string, args ...any) {
+	e.Problems = append(e.Problems, FieldError{Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+var (
+	sslModes         = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	autoOffsetResets = []string{"earliest", "latest"}
+	initialOffsets   = []string{"oldest", "newest"}
+	logLevels        = []string{"debug", "info", "warn", "error"}
+)
+
+// Validate checks the whole configuration and returns a *ValidationError
+// listing every problem, or nil if the config is usable.
+func (c *Config) Validate() error {
+	v := &ValidationError{}
+
+	// --- Postgres ---
+	pg := c.Postgres
+	v.required("postgres.host", pg.Host)
+	v.port("postgres.port", pg.Port)
+	v.required("postgres.user", pg.User)
+	v.required("postgres.dbname", pg.DBName)
+	v.oneOf("postgres.sslmode", pg.SSLMode, sslModes)
+	if strings.HasPrefix(pg.SSLMode, "verify") && pg.SSLRootCert == "" {
+		v.add("postgres.sslrootcert", "is required with sslmode %s", pg.SSLMode)
+	}
+	if (pg.SSLCert == "") != (pg.SSLKey == "") {
+		v.add("postgres.sslcert", "sslcert and sslkey must be set together")
+	}
+	v.nonNegative("postgres.statementTimeout", pg.StatementTimeout)
+	v.nonNegative("postgres.connectTimeout", pg.ConnectTimeout)
+	if pg.Pool.MaxOpenConns < 0 {
+		v.add("postgres.pool.maxOpenConns", "must not be negative")
+	}
+	if pg.Pool.MaxOpenConns > 0 && pg.Pool.MaxIdleConns > pg.Pool.MaxOpenConns {
+		v.add("postgres.pool.maxIdleConns", "must not exceed maxOpenConns (%d)", pg.Pool.MaxOpenConns)
+	}
+	v.nonNegative("postgres.pool.connMaxLifetime", pg.Pool.ConnMaxLifetime)
+	v.nonNegative("postgres.pool.connMaxIdleTime", pg.Pool.ConnMaxIdleTime)
+	for i, r := range pg.Replicas {
+		v.required(fmt.Sprintf("postgres.replicas[%d].host", i), r.Host)
+		if r.Port != 0 {
+			v.port(fmt.Sprintf("postgres.replicas[%d].port", i), r.Port)
+		}
+	}
+	v.nonNegative("postgres.maxReplicaLag", pg.MaxReplicaLag)
+	v.nonNegative("postgres.readYourWrites", pg.ReadYourWrites)
+
+	// --- Kafka ---
+	k := c.Kafka
+	if len(k.Brokers) == 0 {
+		v.add("kafka.brokers", "at least one broker is required")
+	}
+	for i, b := range k.Brokers {
+		v.hostPort(fmt.Sprintf("kafka.brokers[%d]", i), b)
+	}
+	v.required("kafka.topic", k.Topic)
+	v.required("kafka.groupId", k.GroupID)
+	v.oneOf("kafka.autoOffsetReset", k.AutoOffsetReset, autoOffsetResets)
+	v.oneOf("kafka.consumer.initialOffset", k.Consumer.InitialOffset, initialOffsets)
+	if k.Producer.Retries < 0 {
+		v.add("kafka.producer.retries", "must not be negative")
+	}
+
+	// --- Redis ---
+	r := c.Redis
+	v.hostPort("redis.address", r.Address)
+	if r.DB < 0 || r.DB > 15 {
+		v.add("redis.db", "must be between 0 and 15, got %d", r.DB)
+	}
+	if r.PoolSize < 0 {
+		v.add("redis.poolSize", "must not be negative")
+	}
+	if r.PoolSize > 0 && r.MinIdleConns > r.PoolSize {
+		v.add("redis.minIdleConns", "must not exceed poolSize (%d)", r.PoolSize)
+	}
+	v.duration("redis.readTimeout", r.ReadTimeout)
+	v.duration("redis.writeTimeout", r.WriteTimeout)
+	v.duration("redis.dialTimeout", r.DialTimeout)
+	v.nonNegative("redis.reconnectInterval", r.ReconnectInterval)
+	v.nonNegative("redis.breaker.openTimeout", r.Breaker.OpenTimeout)
+
+	// --- Services ---
+	for name, svc := range c.Services {
+		prefix := "services." + name
+		if svc.Listen == "" {
+			v.add(prefix+".listen", "is required")
+		} else if _, port, err := net.SplitHostPort(svc.Listen); err != nil || port == "" {
+			v.add(prefix+".listen", "must be [host]:port, got %q", svc.Listen)
+		}
+		if (svc.TLS.CertFile == "") != (svc.TLS.KeyFile == "") {
+			v.add(prefix+".tls", "certFile and keyFile must be set together")
+		}
+	}
+
+	// --- Runtime ---
+	rt := c.Runtime
+	v.oneOf("runtime.logLevel", rt.LogLevel, logLevels)
+	if rt.Cache.FlightsTTL <= 0 {
+		v.add("runtime.cache.flightsTTL", "must be positive")
+	}
+	if rt.Cache.BookingsTTL <= 0 {
+		v.add("runtime.cache.bookingsTTL", "must be positive")
+	}
+	if rt.RateLimit.RequestsPerSecond < 0 {
+		v.add("runtime.rateLimit.requestsPerSecond", "must not be negative")
+	}
+	if rt.RateLimit.RequestsPerSecond > 0 && rt.RateLimit.Burst < 1 {
+		v.add("runtime.rateLimit.burst", "must be at least 1 when rate limiting is enabled")
+	}
+	if f := rt.ExchangeRates.File; f != "" {
+		if ext := strings.ToLower(filepath.Ext(f)); ext != ".csv" && ext != ".json" {
+			v.add("runtime.exchangeRates.file", "must be a .csv or .json file, got %q", f)
+		}
+	}
+
+	if len(v.Problems) > 0 {
+		return v
+	}
+	return nil
+}
+
+func (v *ValidationError) required(key, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.add(key, "is required")
+	}
+}
+
+func (v *ValidationError) port(key string, port int) {
+	if port < 1 || port > 65535 {
+		v.add(key, "must be between 1 and 65535, got %d", port)
+	}
+}
+
+func (v *ValidationError) hostPort(key, addr string) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host == "" || port == "" {
+		v.add(key, "must be host:port, got %q", addr)
+	}
+}
+
+func (v *ValidationError) oneOf(key, value string, allowed []string) {
+	if !slices.Contains(allowed, value) {
+		v.add(key, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+	}
+}
+
+func (v *ValidationError) nonNegative(key string, d time.Duration) {
+	if d < 0 {
+		v.add(key, "must not be negative")
+	}
+}
+
+// duration checks an optional duration string such as "2s".
+func (v *ValidationError) duration(key, value string) {
+	if value == "" {
+		return
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		v.add(key, "is not a valid duration: %q", value)
+		return
+	}
+	v.nonNegative(key, d)
+}
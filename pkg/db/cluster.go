@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"airline-booking/pkg/config"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// lagCheckInterval is how often replica lag is measured.
+const lagCheckInterval = 2 * time.Second
+
+// lagQuery returns replication lag in seconds, or 0 when the replica has
+// replayed everything it received (an idle primary is not lag).
+const lagQuery = `
+	SELECT CASE
+		WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+	END`
+
+// Cluster is a primary database plus optional read replicas.
+type Cluster struct {
+	Primary *sqlx.DB
+
+	replicas       []*replica
+	maxLag         time.Duration
+	readYourWrites time.Duration
+	next           atomic.Uint64
+	stop           chan struct{}
+}
+
+type replica struct {
+	name    string
+	db      *sqlx.DB
+	healthy atomic.Bool
+	lag     atomic.Int64
+}
+
+// Connect opens the primary, which must be reachable, and any configured
+// replicas, which are used once their lag has been checked.
+func Connect(cfg *config.PostgresConfig) (*Cluster, error) {
+	primary, err := ConnectPostgres(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cluster{
+		Primary:        primary,
+		maxLag:         cfg.MaxReplicaLag,
+		readYourWrites: cfg.ReadYourWrites,
+		stop:           make(chan struct{}),
+	}
+
+	for _, rc := range cfg.Replicas {
+		replicaCfg := *cfg
+		replicaCfg.Host = rc.Host
+		replicaCfg.Port = orDefault(rc.Port, cfg.Port)
+
+		db, err := open(&replicaCfg)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("error opening replica %s: %w", rc.Host, err)
+		}
+		c.replicas = append(c.replicas, &replica{name: net.JoinHostPort(replicaCfg.Host, strconv.Itoa(replicaCfg.Port)), db: db})
+	}
+
+	if len(c.replicas) > 0 {
+		c.checkReplicas()
+		go c.monitor()
+	}
+	return c, nil
+}
+
+// Reader returns the database to use for a read-only query: a replica that
+// is healthy and within the lag limit, or the primary otherwise or when the
+// request asked for primary reads.
+func (c *Cluster) Reader(ctx context.Context) *sqlx.DB {
+	if len(c.replicas) == 0 || PrimaryForced(ctx) {
+		return c.Primary
+	}
+
+	start := c.next.Add(1)
+	for i := range c.replicas {
+		r := c.replicas[(int(start)+i)%len(c.replicas)]
+		if r.healthy.Load() && time.Duration(r.lag.Load()) <= c.maxLag {
+			return r.db
+		}
+	}
+	return c.Primary
+}
+
+func (c *Cluster) monitor() {
+	ticker := time.NewTicker(lagCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.checkReplicas()
+		}
+	}
+}
+
+func (c *Cluster) checkReplicas() {
+	for _, r := range c.replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), lagCheckInterval)
+		var seconds float64
+		err := r.db.GetContext(ctx, &seconds, lagQuery)
+		cancel()
+
+		wasHealthy := r.healthy.Load()
+		r.healthy.Store(err == nil)
+		if err != nil {
+			if wasHealthy {
+				log.Printf("Replica %s unavailable, reading from primary: %v", r.name, err)
+			}
+			continue
+		}
+		if !wasHealthy {
+			log.Printf("Replica %s available", r.name)
+		}
+		r.lag.Store(int64(seconds * float64(time.Second)))
+	}
+}
+
+// Close closes the primary and all replicas.
+func (c *Cluster) Close() {
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+	for _, r := range c.replicas {
+		r.db.Close()
+	}
+	c.Primary.Close()
+}
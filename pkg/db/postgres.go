@@ -3,11 +3,15 @@ package db
 import (
 	"context"
 	"fmt"
-	"time"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
 
 	"airline-booking/pkg/config"
 
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -15,24 +19,21 @@ var DB *sqlx.DB
 
 // ConnectPostgres initializes the DB connection pool
 func ConnectPostgres(cfg *config.PostgresConfig) (*sqlx.DB, error) {
-	dsn := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s",
-		cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.DBName, cfg.SSLMode)
-
-	db, err := sqlx.Connect("pgx", dsn)
+	db, err := open(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("error connecting to postgres: %w", err)
 	}
 
-	// Connection pool settings
-	db.SetMaxOpenConns(20)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(time.Hour)
-
 	// Check the connection
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
+	ctx := context.Background()
+	if cfg.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.ConnectTimeout)
+		defer cancel()
+	}
 
 	if err := db.PingContext(ctx); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("error pinging postgres: %w", err)
 	}
 
@@ -40,3 +41,67 @@ func ConnectPostgres(cfg *config.PostgresConfig) (*sqlx.DB, error) {
 	return db, nil
 
 }
+
+// open creates a connection pool without contacting the server. Every new
+// connection uses the current password so rotated credentials are picked up.
+func open(cfg *config.PostgresConfig) (*sqlx.DB, error) {
+	connConfig, err := pgx.ParseConfig(BuildDSN(cfg))
+	if err != nil {
+		return nil, err
+	}
+	db := sqlx.NewDb(stdlib.OpenDB(*connConfig, stdlib.OptionBeforeConnect(func(_ context.Context, cc *pgx.ConnConfig) error {
+		cc.Password = cfg.CurrentPassword()
+		return nil
+	})), "pgx")
+
+	// Connection pool settings, defaulted when the config is loaded so an
+	// explicit 0 keeps its database/sql meaning of no limit
+	db.SetMaxOpenConns(cfg.Pool.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Pool.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.Pool.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.Pool.ConnMaxIdleTime)
+	return db, nil
+}
+
+// BuildDSN turns the config into a connection URL. Session settings such as
+// statement_timeout and search_path are sent as startup parameters so every
+// pooled connection gets them.
+func BuildDSN(cfg *config.PostgresConfig) string {
+	params := url.Values{}
+	params.Set("sslmode", cfg.SSLMode)
+	setIfNotEmpty(params, "sslrootcert", cfg.SSLRootCert)
+	setIfNotEmpty(params, "sslcert", cfg.SSLCert)
+	setIfNotEmpty(params, "sslkey", cfg.SSLKey)
+	setIfNotEmpty(params, "application_name", cfg.ApplicationName)
+	setIfNotEmpty(params, "search_path", cfg.SearchPath)
+	if cfg.StatementTimeout > 0 {
+		params.Set("statement_timeout", strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10))
+	}
+	if cfg.ConnectTimeout > 0 {
+		// Whole seconds, rounded up: connect_timeout=0 would mean no timeout
+		params.Set("connect_timeout", strconv.Itoa(int(math.Ceil(cfg.ConnectTimeout.Seconds()))))
+	}
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.User, cfg.Password),
+		Host:     net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		Path:     cfg.DBName,
+		RawQuery: params.Encode(),
+	}
+	return dsn.String()
+}
+
+func setIfNotEmpty(params url.Values, key, value string) {
+	if value != "" {
+		params.Set(key, value)
+	}
+}
+
+func orDefault[T comparable](value, def T) T {
+	var zero T
+	if value == zero {
+		return def
+	}
+	return value
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"airline-booking/pkg/config"
+)
+
+func TestBuildDSNConnectTimeout(t *testing.T) {
+	tests := []struct {
+		timeout time.Duration
+		want    string
+	}{
+		{0, ""},
+		{300 * time.Millisecond, "1"},
+		{time.Second, "1"},
+		{1500 * time.Millisecond, "2"},
+		{3 * time.Second, "3"},
+	}
+	for _, tt := range tests {
+		cfg := &config.PostgresConfig{Host: "db", Port: 5432, SSLMode: "disable", ConnectTimeout: tt.timeout}
+		u, err := url.Parse(BuildDSN(cfg))
+		if err != nil {
+			t.Fatalf("BuildDSN(%v): %v", tt.timeout, err)
+		}
+		if got := u.Query().Get("connect_timeout"); got != tt.want {
+			t.Errorf("connect_timeout for %v = %q, want %q", tt.timeout, got, tt.want)
+		}
+	}
+}
+
+func TestBuildDSNSessionSettings(t *testing.T) {
+	cfg := &config.PostgresConfig{
+		Host: "db", Port: 5432, User: "airline", Password: "p@ss", DBName: "airline", SSLMode: "require",
+		ApplicationName: "booking", StatementTimeout: 5 * time.Second,
+	}
+	u, err := url.Parse(BuildDSN(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pw, _ := u.User.Password(); pw != "p@ss" || u.Host != "db:5432" || u.Path != "/airline" {
+		t.Errorf("DSN = %s", u.Redacted())
+	}
+	q := u.Query()
+	for key, want := range map[string]string{"sslmode": "require", "application_name": "booking", "statement_timeout": "5000"} {
+		if got := q.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if q.Has("search_path") {
+		t.Error("empty search_path was set")
+	}
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// readPrimaryCookie is set after a write so the client's next reads see it
+	readPrimaryCookie = "abs-read-primary"
+	// ReadPrimaryHeader lets a client ask for primary reads explicitly
+	ReadPrimaryHeader = "X-Read-Primary"
+)
+
+type primaryKey struct{}
+
+// WithPrimary marks ctx so reads made with it go to the primary.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// PrimaryForced reports whether reads made with ctx must go to the primary.
+func PrimaryForced(ctx context.Context) bool {
+	forced, _ := ctx.Value(primaryKey{}).(bool)
+	return forced
+}
+
+// ReadYourWrites routes a client's reads to the primary for a short window
+// after it made a successful write, and whenever it sends X-Read-Primary.
+func (c *Cluster) ReadYourWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie(readPrimaryCookie); err == nil || r.Header.Get(ReadPrimaryHeader) != "" {
+			r = r.WithContext(WithPrimary(r.Context()))
+		}
+
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(&writeMarker{ResponseWriter: w, window: c.readYourWrites}, r)
+	})
+}
+
+// writeMarker sets the read-primary cookie on successful write responses.
+type writeMarker struct {
+	http.ResponseWriter
+	window      time.Duration
+	wroteHeader bool
+}
+
+func (m *writeMarker) WriteHeader(code int) {
+	if !m.wroteHeader {
+		m.wroteHeader = true
+		if code < http.StatusBadRequest {
+			http.SetCookie(m.ResponseWriter, &http.Cookie{
+				Name:     readPrimaryCookie,
+				Value:    strconv.FormatInt(time.Now().Unix(), 10),
+				Path:     "/",
+				MaxAge:   max(1, int(m.window.Seconds())),
+				HttpOnly: true,
+			})
+		}
+	}
+	m.ResponseWriter.WriteHeader(code)
+}
+
+func (m *writeMarker) Write(b []byte) (int, error) {
+	if !m.wroteHeader {
+		m.WriteHeader(http.StatusOK)
+	}
+	return m.ResponseWriter.Write(b)
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"airline-booking/pkg/response"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PoolStats is the JSON view of the connection pool state.
+type PoolStats struct {
+	Status            string `json:"status"`
+	Error             string `json:"error,omitempty"`
+	MaxOpenConns      int    `json:"max_open_conns"`
+	OpenConns         int    `json:"open_conns"`
+	InUse             int    `json:"in_use"`
+	Idle              int    `json:"idle"`
+	WaitCount         int64  `json:"wait_count"`
+	WaitDuration      string `json:"wait_duration"`
+	MaxIdleClosed     int64  `json:"max_idle_closed"`
+	MaxIdleTimeClosed int64  `json:"max_idle_time_closed"`
+	MaxLifetimeClosed int64  `json:"max_lifetime_closed"`
+}
+
+// Stats pings the database and returns the current pool statistics.
+func Stats(ctx context.Context, db *sqlx.DB) PoolStats {
+	s := db.Stats()
+	stats := PoolStats{
+		Status:            "up",
+		MaxOpenConns:      s.MaxOpenConnections,
+		OpenConns:         s.OpenConnections,
+		InUse:             s.InUse,
+		Idle:              s.Idle,
+		WaitCount:         s.WaitCount,
+		WaitDuration:      s.WaitDuration.String(),
+		MaxIdleClosed:     s.MaxIdleClosed,
+		MaxIdleTimeClosed: s.MaxIdleTimeClosed,
+		MaxLifetimeClosed: s.MaxLifetimeClosed,
+	}
+	if err := db.PingContext(ctx); err != nil {
+		stats.Status = "down"
+		stats.Error = err.Error()
+	}
+	return stats
+}
+
+// ReplicaStats adds replication state to the pool statistics of a replica.
+type ReplicaStats struct {
+	Name string `json:"name"`
+	Lag  string `json:"lag"`
+	PoolStats
+}
+
+// ClusterStats is the JSON view of the primary and all replicas.
+type ClusterStats struct {
+	Primary  PoolStats      `json:"primary"`
+	Replicas []ReplicaStats `json:"replicas"`
+}
+
+// StatsHandler reports database health and pool statistics. It answers 503
+// only when the primary is down; replicas are optional.
+func StatsHandler(c *Cluster) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+
+		stats := ClusterStats{Primary: Stats(ctx, c.Primary), Replicas: []ReplicaStats{}}
+		for _, rep := range c.replicas {
+			stats.Replicas = append(stats.Replicas, ReplicaStats{
+				Name:      rep.name,
+				Lag:       time.Duration(rep.lag.Load()).String(),
+				PoolStats: Stats(ctx, rep.db),
+			})
+		}
+
+		status := http.StatusOK
+		if stats.Primary.Status != "up" {
+			status = http.StatusServiceUnavailable
+		}
+		response.JSON(w, status, stats)
+	}
+}
@@ -0,0 +1,29 @@
+// Package jsonb stores values in JSON columns. Types kept as JSON implement
+// driver.Valuer and sql.Scanner by calling Value and Scan.
+package jsonb
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Value encodes v for a JSON column.
+func Value(v any) (driver.Value, error) {
+	return json.Marshal(v)
+}
+
+// Scan decodes a JSON column into dst. NULL leaves dst at its zero value.
+func Scan[T any](src any, dst *T) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, dst)
+	case string:
+		return json.Unmarshal([]byte(v), dst)
+	case nil:
+		var zero T
+		*dst = zero
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into %T", src, *dst)
+}
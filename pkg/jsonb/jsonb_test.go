@@ -0,0 +1,51 @@
+package jsonb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	type rules struct {
+		Charges []string `json:"charges"`
+	}
+	tests := []struct {
+		name    string
+		src     any
+		want    rules
+		wantErr bool
+	}{
+		{name: "bytes", src: []byte(`{"charges":["UDF"]}`), want: rules{Charges: []string{"UDF"}}},
+		{name: "string", src: `{"charges":["YQ"]}`, want: rules{Charges: []string{"YQ"}}},
+		{name: "null", src: nil},
+		{name: "other type", src: 42, wantErr: true},
+		{name: "bad json", src: `{`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rules{Charges: []string{"old"}}
+			err := Scan(tt.src, &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Scan() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Scan() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValueRoundTrip(t *testing.T) {
+	in := map[string]float64{"load_factor": 1.2}
+	v, err := Value(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]float64
+	if err := Scan(v, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip = %v, want %v", out, in)
+	}
+}
@@ -18,6 +18,10 @@ type Consumer struct {
 func NewConsumer(cfg *config.KafkaConfig) (*Consumer, error) {
 	kafkaCfg := sarama.NewConfig()
 	kafkaCfg.Consumer.Offsets.Initial = sarama.OffsetNewest
+	if cfg.Consumer.InitialOffset == "oldest" {
+		kafkaCfg.Consumer.Offsets.Initial = sarama.OffsetOldest
+	}
+	kafkaCfg.Consumer.Offsets.AutoCommit.Enable = cfg.EnableAutoCommit
 	kafkaCfg.Consumer.Group.Rebalance.Strategy = sarama.NewBalanceStrategyRoundRobin()
 	kafkaCfg.Version = sarama.V3_4_0_0 // compatible with Kafka 4.x (KRaft)
 
@@ -32,16 +36,7 @@ func NewConsumer(cfg *config.KafkaConfig) (*Consumer, error) {
 
 // RunConsumer listens to Kafka messages
 func (c *Consumer) RunConsumer(ctx context.Context, topics []string, handler sarama.ConsumerGroupHandler) {
-	go func() {
-		for {
-			if err := c.Group.Consume(ctx, topics, handler); err != nil {
-				log.Printf(" Error consuming: %v", err)
-			}
-			if ctx.Err() != nil {
-				return
-			}
-		}
-	}()
+	go c.Consume(ctx, topics, handler)
 	sigterm := make(chan os.Signal, 1)
 	signal.Notify(sigterm, os.Interrupt)
 	<-sigterm
@@ -50,6 +45,19 @@ func (c *Consumer) RunConsumer(ctx context.Context, topics []string, handler sar
 	c.Close()
 }
 
+// Consume hands messages to handler, rejoining the group after every
+// rebalance, until ctx is done.
+func (c *Consumer) Consume(ctx context.Context, topics []string, handler sarama.ConsumerGroupHandler) {
+	for {
+		if err := c.Group.Consume(ctx, topics, handler); err != nil {
+			log.Printf(" Error consuming: %v", err)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
 func (c *Consumer) Close() {
 	if c.Group != nil {
 		if err := c.Group.Close(); err != nil {
@@ -60,6 +68,24 @@ func (c *Consumer) Close() {
 	}
 }
 
+// EventHandler handles one event, given the message key as its type. A
+// message is marked consumed once it returns, even on error, so one bad
+// event cannot block the partition.
+type EventHandler func(ctx context.Context, eventType string, value []byte) error
+
+func (EventHandler) Setup(_ sarama.ConsumerGroupSession) error   { return nil }
+func (EventHandler) Cleanup(_ sarama.ConsumerGroupSession) error { return nil }
+
+func (h EventHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for msg := range claim.Messages() {
+		if err := h(session.Context(), string(msg.Key), msg.Value); err != nil {
+			log.Printf("Failed to handle %s event at %s/%d/%d: %v", msg.Key, msg.Topic, msg.Partition, msg.Offset, err)
+		}
+		session.MarkMessage(msg, "")
+	}
+	return nil
+}
+
 type ExampleHandler struct{}
 
 func (ExampleHandler) Setup(_ sarama.ConsumerGroupSession) error {
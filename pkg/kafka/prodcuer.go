@@ -2,6 +2,7 @@ package kafka
 
 import (
 	"log"
+	"log/slog"
 	"time"
 
 	"airline-booking/pkg/config"
@@ -45,7 +46,7 @@ func (p *Producer) SendMessage(topic string, key string, value string) error {
 		return err
 	}
 
-	log.Printf("Message sent to topic %s [partition=%d, offset=%d]", topic, partition, offset)
+	slog.Debug("Message sent to Kafka", "topic", topic, "partition", partition, "offset", offset)
 	return nil
 }
 
@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"io"
+	"log"
+	"log/slog"
+	"os"
+)
+
+var level = new(slog.LevelVar)
+
+// Init sends slog through a handler whose level can be changed at runtime.
+// The standard log package keeps writing to stderr unfiltered, so its error
+// and fatal messages are never dropped by a raised level.
+func Init() {
+	initTo(os.Stderr)
+}
+
+func initTo(w io.Writer) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level})))
+	// SetDefault routes the log package through the handler at info; undo that
+	log.SetOutput(w)
+	log.SetFlags(log.LstdFlags)
+}
+
+// SetLevel changes the minimum level logged: debug, info, warn or error.
+func SetLevel(name string) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(name)); err != nil {
+		return err
+	}
+	if l != level.Level() {
+		level.Set(l)
+		slog.Info("Log level changed", "level", l.String())
+	}
+	return nil
+}
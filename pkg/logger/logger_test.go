@@ -0,0 +1,44 @@
+package logger
+
+import (
+	"bytes"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestStdLogIsNotFiltered(t *testing.T) {
+	var buf bytes.Buffer
+	initTo(&buf)
+	defer initTo(os.Stderr)
+	defer SetLevel("info")
+
+	if err := SetLevel("error"); err != nil {
+		t.Fatal(err)
+	}
+	slog.Info("filtered slog line")
+	slog.Error("slog error line")
+	log.Printf("Database connection failed: %v", "refused")
+
+	out := buf.String()
+	if strings.Contains(out, "filtered slog line") {
+		t.Errorf("info line logged at level error:\n%s", out)
+	}
+	for _, want := range []string{"slog error line", "Database connection failed: refused"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	defer SetLevel("info")
+	if err := SetLevel("verbose"); err == nil {
+		t.Error("SetLevel accepted an unknown level")
+	}
+	if err := SetLevel("debug"); err != nil || level.Level() != slog.LevelDebug {
+		t.Errorf("SetLevel(debug) = %v, level %v", err, level.Level())
+	}
+}
@@ -0,0 +1,236 @@
+// Package money represents amounts as integer minor units of an ISO 4217
+// currency, e.g. {Amount: 12345, Currency: "INR"} for ₹123.45, so totals
+// add up exactly.
+//
+// In JSON an amount is {"amount": 12345, "currency": "INR"}. A bare number
+// such as 123.45 is read as major units of DefaultCurrency, which keeps
+// clients and stored documents from before amounts had a currency working.
+//
+// In the database an amount is a BIGINT column of minor units next to a
+// currency column. sqlx fills both when they are selected as
+// "<field>.amount" and "<field>.currency", e.g.
+//
+//	SELECT price AS "price.amount", currency AS "price.currency" FROM flights
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the currency of amounts given without one.
+const DefaultCurrency = "INR"
+
+// exponents lists the currencies accepted and their number of minor unit
+// digits, per ISO 4217.
+var exponents = map[string]int{
+	"AED": 2, "AUD": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2, "CNY": 2, "CZK": 2,
+	"DKK": 2, "EGP": 2, "EUR": 2, "GBP": 2, "HKD": 2, "HUF": 2, "IDR": 2, "ILS": 2,
+	"INR": 2, "IQD": 3, "ISK": 0, "JOD": 3, "JPY": 0, "KES": 2, "KRW": 0, "KWD": 3,
+	"LKR": 2, "LYD": 3, "MXN": 2, "MYR": 2, "NOK": 2, "NPR": 2, "NZD": 2, "OMR": 3,
+	"PHP": 2, "PKR": 2, "PLN": 2, "QAR": 2, "RUB": 2, "SAR": 2, "SEK": 2, "SGD": 2,
+	"THB": 2, "TND": 3, "TRY": 2, "TWD": 2, "USD": 2, "VND": 0, "ZAR": 2,
+}
+
+// ErrCurrencyMismatch is returned when combining amounts of two currencies.
+var ErrCurrencyMismatch = errors.New("money: currencies differ")
+
+// Money is an amount in minor units of a currency. The zero value is no
+// money in no particular currency; it adds to amounts of any currency.
+type Money struct {
+	Amount   int64  `db:"amount"`
+	Currency string `db:"currency"`
+}
+
+// New returns amount minor units of currency.
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// FromMajor converts an amount in major units, e.g. 123.45, rounding half
+// to even to the nearest minor unit.
+func FromMajor(major float64, currency string) (Money, error) {
+	exp, ok := exponents[currency]
+	if !ok {
+		return Money{}, fmt.Errorf("money: unknown currency %q", currency)
+	}
+	// The shortest decimal form, so 0.1 is exactly one tenth
+	r := decimal(major)
+	r.Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil)))
+	return Money{Amount: roundRat(r), Currency: currency}, nil
+}
+
+// Valid reports whether the currency is a known ISO 4217 code.
+func Valid(currency string) bool {
+	_, ok := exponents[currency]
+	return ok
+}
+
+// Exponent returns the number of minor unit digits of a currency.
+func Exponent(currency string) int {
+	return exponents[currency]
+}
+
+// IsZero reports whether m is no money at all.
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Major returns the amount in major units, for display and ratios only.
+func (m Money) Major() float64 {
+	return float64(m.Amount) / math.Pow10(exponents[m.Currency])
+}
+
+// Measure lets validation rules such as gt=0 compare amounts.
+func (m Money) Measure() float64 {
+	return float64(m.Amount)
+}
+
+// Add returns m + o. A zero amount takes the currency of the other.
+func (m Money) Add(o Money) (Money, error) {
+	cur, err := common(m, o)
+	return Money{Amount: m.Amount + o.Amount, Currency: cur}, err
+}
+
+// Sub returns m - o. A zero amount takes the currency of the other.
+func (m Money) Sub(o Money) (Money, error) {
+	cur, err := common(m, o)
+	return Money{Amount: m.Amount - o.Amount, Currency: cur}, err
+}
+
+// Times returns m multiplied by a whole number, e.g. a fare by seats.
+func (m Money) Times(n int) Money {
+	return Money{Amount: m.Amount * int64(n), Currency: m.Currency}
+}
+
+// Mul returns m scaled by f, e.g. a pricing multiplier, rounding half to
+// even to the nearest minor unit.
+func (m Money) Mul(f float64) Money {
+	r := decimal(f)
+	r.Mul(r, new(big.Rat).SetInt64(m.Amount))
+	return Money{Amount: roundRat(r), Currency: m.Currency}
+}
+
+// Split divides m into n parts that add up to m exactly, the first parts
+// taking the minor units left over, e.g. 100 into 34, 33, 33.
+func (m Money) Split(n int) []Money {
+	parts := make([]Money, n)
+	each, rest := m.Amount/int64(n), m.Amount%int64(n)
+	for i := range parts {
+		parts[i] = Money{Amount: each, Currency: m.Currency}
+		if int64(i) < rest {
+			parts[i].Amount++
+		}
+	}
+	return parts
+}
+
+// Sum adds amounts of one currency.
+func Sum(amounts ...Money) (Money, error) {
+	var total Money
+	for _, a := range amounts {
+		var err error
+		if total, err = total.Add(a); err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// String formats m in major units, e.g. "123.45 INR".
+func (m Money) String() string {
+	exp := exponents[m.Currency]
+	sign, amount := "", m.Amount
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	s := strconv.FormatInt(amount, 10)
+	if exp > 0 {
+		if len(s) <= exp {
+			s = strings.Repeat("0", exp-len(s)+1) + s
+		}
+		s = s[:len(s)-exp] + "." + s[len(s)-exp:]
+	}
+	return strings.TrimSpace(sign + s + " " + m.Currency)
+}
+
+// MarshalJSON writes m as {"amount": 12345, "currency": "INR"}.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   int64  `json:"amount"`
+		Currency string `json:"currency"`
+	}{m.Amount, m.Currency})
+}
+
+// UnmarshalJSON reads {"amount": 12345, "currency": "INR"}, or a bare
+// number of major units of DefaultCurrency.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) > 0 && data[0] != '{' {
+		var major float64
+		if err := json.Unmarshal(data, &major); err != nil {
+			return errors.New(`money: amounts must be {"amount": <minor units>, "currency": "<ISO 4217 code>"}`)
+		}
+		v, err := FromMajor(major, DefaultCurrency)
+		*m = v
+		return err
+	}
+
+	var v struct {
+		Amount   *int64 `json:"amount"`
+		Currency string `json:"currency"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&v); err != nil || v.Amount == nil {
+		return errors.New(`money: amounts must be {"amount": <minor units>, "currency": "<ISO 4217 code>"}`)
+	}
+	if v.Currency == "" {
+		v.Currency = DefaultCurrency
+	}
+	if !Valid(v.Currency) {
+		return fmt.Errorf("money: unknown currency %q", v.Currency)
+	}
+	*m = Money{Amount: *v.Amount, Currency: v.Currency}
+	return nil
+}
+
+// common returns the currency two amounts share.
+func common(a, b Money) (string, error) {
+	switch {
+	case a.Currency == b.Currency:
+		return a.Currency, nil
+	case a.Currency == "" && a.Amount == 0:
+		return b.Currency, nil
+	case b.Currency == "" && b.Amount == 0:
+		return a.Currency, nil
+	}
+	return a.Currency, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, a.Currency, b.Currency)
+}
+
+// roundRat rounds r half to even to a whole number.
+func roundRat(r *big.Rat) int64 {
+	num, den := r.Num(), r.Denom()
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	// Compare twice the remainder with the denominator
+	twice := new(big.Int).Abs(rem)
+	twice.Lsh(twice, 1)
+	switch c := twice.Cmp(den); {
+	case c > 0, c == 0 && q.Bit(0) == 1:
+		if num.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q.Int64()
+}
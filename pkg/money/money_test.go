@@ -0,0 +1,122 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestFromMajor(t *testing.T) {
+	tests := []struct {
+		major    float64
+		currency string
+		want     int64
+	}{
+		{0.1, "USD", 10},
+		{123.45, "INR", 12345},
+		// Halves round to even
+		{0.125, "USD", 12},
+		{0.135, "USD", 14},
+		{-0.125, "USD", -12},
+		{1.5, "JPY", 2},
+		{2.5, "JPY", 2},
+		{1.2345, "KWD", 1234},
+	}
+	for _, tt := range tests {
+		got, err := FromMajor(tt.major, tt.currency)
+		if err != nil {
+			t.Fatalf("FromMajor(%v, %s) = %v", tt.major, tt.currency, err)
+		}
+		if want := New(tt.want, tt.currency); got != want {
+			t.Errorf("FromMajor(%v, %s) = %v, want %v", tt.major, tt.currency, got, want)
+		}
+	}
+	if _, err := FromMajor(1, "XXX"); err == nil {
+		t.Error("FromMajor with an unknown currency = nil error")
+	}
+}
+
+func TestMul(t *testing.T) {
+	tests := []struct {
+		amount int64
+		f      float64
+		want   int64
+	}{
+		{1000, 1.1, 1100},
+		{333, 1.15, 383},
+		{101, 0.5, 50},
+		{103, 0.5, 52},
+		{-101, 0.5, -50},
+		{-103, 0.5, -52},
+	}
+	for _, tt := range tests {
+		if got := New(tt.amount, "INR").Mul(tt.f); got != New(tt.want, "INR") {
+			t.Errorf("%d.Mul(%v) = %v, want %d", tt.amount, tt.f, got, tt.want)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	got := New(100, "INR").Split(3)
+	want := []Money{New(34, "INR"), New(33, "INR"), New(33, "INR")}
+	if !slices.Equal(got, want) {
+		t.Errorf("Split(3) = %v, want %v", got, want)
+	}
+	if sum, _ := Sum(got...); sum != New(100, "INR") {
+		t.Errorf("parts add up to %v", sum)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	if got, err := (Money{}).Add(New(5, "USD")); err != nil || got != New(5, "USD") {
+		t.Errorf("zero + 5 USD = %v, %v", got, err)
+	}
+	if _, err := New(5, "INR").Add(New(5, "USD")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("INR + USD = %v, want ErrCurrencyMismatch", err)
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		m    Money
+		want string
+	}{
+		{New(5, "USD"), "0.05 USD"},
+		{New(-12345, "INR"), "-123.45 INR"},
+		{New(500, "JPY"), "500 JPY"},
+		{New(1, "KWD"), "0.001 KWD"},
+	}
+	for _, tt := range tests {
+		if got := tt.m.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{in: `123.45`, want: New(12345, DefaultCurrency)},
+		{in: `{"amount": 1999, "currency": "USD"}`, want: New(1999, "USD")},
+		{in: `{"amount": 1999}`, want: New(1999, DefaultCurrency)},
+		{in: `{"amount": 1999, "currency": "XXX"}`, wantErr: true},
+		{in: `{"currency": "USD"}`, wantErr: true},
+		{in: `{"amount": 1, "extra": true}`, wantErr: true},
+		{in: `"12"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var got Money
+		err := json.Unmarshal([]byte(tt.in), &got)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Unmarshal(%s) = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("Unmarshal(%s) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package money
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/jsonb"
+)
+
+var (
+	// ErrUnknownCurrency is returned when asking for a currency that is not an ISO 4217 code.
+	ErrUnknownCurrency = apperr.Validation("unknown_currency", "unknown currency",
+		apperr.FieldError{Field: "currency", Message: "must be an ISO 4217 currency code, e.g. USD"})
+	// ErrNoRate is returned when no exchange rate is loaded for a currency.
+	ErrNoRate = apperr.Validation("exchange_rate_not_found", "no exchange rate for the currency",
+		apperr.FieldError{Field: "currency", Message: "has no exchange rate"})
+)
+
+// Rate converts amounts between two currencies: one unit of From is worth
+// Rate units of To, as published at AsOf. It is stored as JSON, e.g. when
+// locked into a booking.
+type Rate struct {
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	Rate float64   `json:"rate"`
+	AsOf time.Time `json:"as_of"`
+}
+
+// Convert returns m in the currency of the rate, rounding half to even to
+// its minor unit.
+func (r Rate) Convert(m Money) (Money, error) {
+	if m.Currency != r.From && !(m.Currency == "" && m.Amount == 0) {
+		return Money{}, fmt.Errorf("%w: cannot convert %s at a rate from %s", ErrCurrencyMismatch, m.Currency, r.From)
+	}
+	x := decimal(r.Rate)
+	x.Mul(x, new(big.Rat).SetInt64(m.Amount))
+	// Minor units of From to minor units of To
+	shift := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(exponents[r.To]-exponents[r.From]))), nil)
+	if exponents[r.To] >= exponents[r.From] {
+		x.Mul(x, new(big.Rat).SetInt(shift))
+	} else {
+		x.Quo(x, new(big.Rat).SetInt(shift))
+	}
+	return Money{Amount: roundRat(x), Currency: r.To}, nil
+}
+
+// Value implements driver.Valuer.
+func (r Rate) Value() (driver.Value, error) {
+	return jsonb.Value(r)
+}
+
+// Scan implements sql.Scanner.
+func (r *Rate) Scan(src any) error {
+	return jsonb.Scan(src, r)
+}
+
+// RateTable is a set of exchange rates against a base currency: one unit
+// of Base is worth Rates[c] units of currency c.
+type RateTable struct {
+	Base  string             `json:"base"`
+	AsOf  time.Time          `json:"as_of"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// Rates is the exchange rate store. It holds the table loaded last and is
+// safe for concurrent use. A nil or empty store only converts a currency
+// to itself.
+type Rates struct {
+	mu      sync.RWMutex
+	table   RateTable
+	path    string
+	modTime time.Time
+}
+
+// NewRates returns an empty store.
+func NewRates() *Rates {
+	return &Rates{}
+}
+
+// Rate returns the rate from one currency to another, crossing through
+// the base currency of the table.
+func (s *Rates) Rate(from, to string) (Rate, error) {
+	if !Valid(to) {
+		return Rate{}, fmt.Errorf("%w: %q", ErrUnknownCurrency, to)
+	}
+	if from == to {
+		return Rate{From: from, To: to, Rate: 1}, nil
+	}
+	if s == nil {
+		return Rate{}, fmt.Errorf("%w: %s to %s", ErrNoRate, from, to)
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	perBase := func(c string) (float64, bool) {
+		if c == s.table.Base {
+			return 1, true
+		}
+		r, ok := s.table.Rates[c]
+		return r, ok
+	}
+	fromRate, okFrom := perBase(from)
+	toRate, okTo := perBase(to)
+	if !okFrom || !okTo {
+		return Rate{}, fmt.Errorf("%w: %s to %s", ErrNoRate, from, to)
+	}
+	rate, _ := new(big.Rat).Quo(decimal(toRate), decimal(fromRate)).Float64()
+	return Rate{From: from, To: to, Rate: rate, AsOf: s.table.AsOf}, nil
+}
+
+// Convert returns m in another currency at the current rate, and the rate.
+func (s *Rates) Convert(m Money, to string) (Money, Rate, error) {
+	rate, err := s.Rate(m.Currency, to)
+	if err != nil {
+		return Money{}, rate, err
+	}
+	converted, err := rate.Convert(m)
+	return converted, rate, err
+}
+
+// Table returns the rates loaded.
+func (s *Rates) Table() RateTable {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.table
+}
+
+// Set replaces the rates.
+func (s *Rates) Set(t RateTable) {
+	s.mu.Lock()
+	s.table = t
+	s.mu.Unlock()
+}
+
+// LoadFile replaces the rates with those of a .csv or .json rate file. An
+// empty path clears them.
+func (s *Rates) LoadFile(path string) error {
+	var (
+		t       RateTable
+		modTime time.Time
+	)
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open rate file: %w", err)
+		}
+		defer f.Close()
+		if info, err := f.Stat(); err == nil {
+			modTime = info.ModTime()
+		}
+
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			t, err = ParseRatesCSV(f)
+		case ".json":
+			t, err = ParseRatesJSON(f)
+		default:
+			err = errors.New("rate files must be .csv or .json")
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.table, s.path, s.modTime = t, path, modTime
+	return nil
+}
+
+// Watch loads the rate file again whenever it is modified, checking every
+// interval. It blocks until ctx is done.
+func (s *Rates) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.RLock()
+		path, modTime := s.path, s.modTime
+		s.mu.RUnlock()
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err != nil || info.ModTime().Equal(modTime) {
+			continue
+		}
+		if err := s.LoadFile(path); err != nil {
+			log.Printf("Exchange rate reload rejected, keeping current rates: %v", err)
+			continue
+		}
+		log.Printf("Exchange rates reloaded from %s", path)
+	}
+}
+
+// ParseRatesCSV reads rates with the header base,currency,rate,as_of, e.g.
+//
+//	base,currency,rate,as_of
+//	INR,USD,0.01190,2026-10-19
+//
+// as_of is a date or an RFC 3339 time and the same on every row.
+func ParseRatesCSV(r io.Reader) (RateTable, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return RateTable{}, fmt.Errorf("failed to read header: %w", err)
+	}
+	cols := map[string]int{}
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"base", "currency", "rate", "as_of"} {
+		if _, ok := cols[name]; !ok {
+			return RateTable{}, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	t := RateTable{Rates: map[string]float64{}}
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return RateTable{}, err
+		}
+		base := strings.ToUpper(strings.TrimSpace(rec[cols["base"]]))
+		currency := strings.ToUpper(strings.TrimSpace(rec[cols["currency"]]))
+		asOf, err := parseAsOf(rec[cols["as_of"]])
+		if err != nil {
+			return RateTable{}, fmt.Errorf("line %d: %w", line, err)
+		}
+		if t.Base == "" {
+			t.Base, t.AsOf = base, asOf
+		} else if base != t.Base || !asOf.Equal(t.AsOf) {
+			return RateTable{}, fmt.Errorf("line %d: every row must have the base and as_of of the first", line)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(rec[cols["rate"]]), 64)
+		if err != nil {
+			return RateTable{}, fmt.Errorf("line %d: invalid rate %q", line, rec[cols["rate"]])
+		}
+		t.Rates[currency] = rate
+	}
+	return t, t.check()
+}
+
+// ParseRatesJSON reads rates as {"base": "INR", "as_of": "2026-10-19",
+// "rates": {"USD": 0.0119}}.
+func ParseRatesJSON(r io.Reader) (RateTable, error) {
+	var raw struct {
+		Base  string             `json:"base"`
+		AsOf  string             `json:"as_of"`
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return RateTable{}, fmt.Errorf("invalid JSON: %w", err)
+	}
+	asOf, err := parseAsOf(raw.AsOf)
+	if err != nil {
+		return RateTable{}, err
+	}
+	t := RateTable{Base: strings.ToUpper(raw.Base), AsOf: asOf, Rates: map[string]float64{}}
+	for c, rate := range raw.Rates {
+		t.Rates[strings.ToUpper(c)] = rate
+	}
+	return t, t.check()
+}
+
+// check rejects unknown currencies and rates that are not positive.
+func (t RateTable) check() error {
+	if !Valid(t.Base) {
+		return fmt.Errorf("unknown base currency %q", t.Base)
+	}
+	for c, rate := range t.Rates {
+		if !Valid(c) {
+			return fmt.Errorf("unknown currency %q", c)
+		}
+		if rate <= 0 {
+			return fmt.Errorf("rate of %s must be greater than 0", c)
+		}
+	}
+	return nil
+}
+
+func parseAsOf(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, fmt.Errorf("invalid as_of %q, expected a date such as 2026-10-19", s)
+	}
+	return t, nil
+}
+
+// decimal is the exact value of the shortest decimal form of f.
+func decimal(f float64) *big.Rat {
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(f, 'f', -1, 64))
+	return r
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,106 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateConvert(t *testing.T) {
+	tests := []struct {
+		name string
+		rate Rate
+		m    Money
+		want Money
+	}{
+		{"same exponent", Rate{From: "INR", To: "USD", Rate: 0.0119}, New(100000, "INR"), New(1190, "USD")},
+		// 17.5 yen rounds to even
+		{"to fewer digits", Rate{From: "INR", To: "JPY", Rate: 1.75}, New(1000, "INR"), New(18, "JPY")},
+		{"to fewer digits down", Rate{From: "INR", To: "JPY", Rate: 1.75}, New(1100, "INR"), New(19, "JPY")},
+		{"to more digits", Rate{From: "USD", To: "KWD", Rate: 0.307}, New(1000, "USD"), New(3070, "KWD")},
+		{"zero amount", Rate{From: "INR", To: "USD", Rate: 0.0119}, Money{}, New(0, "USD")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rate.Convert(tt.m)
+			if err != nil || got != tt.want {
+				t.Errorf("Convert(%v) = %v, %v, want %v", tt.m, got, err, tt.want)
+			}
+		})
+	}
+
+	_, err := Rate{From: "INR", To: "USD", Rate: 0.0119}.Convert(New(100, "EUR"))
+	if !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Convert of another currency = %v, want ErrCurrencyMismatch", err)
+	}
+}
+
+func TestRatesRate(t *testing.T) {
+	rates := NewRates()
+	rates.Set(RateTable{Base: "INR", Rates: map[string]float64{"USD": 0.012, "EUR": 0.011}})
+
+	tests := []struct {
+		name     string
+		rates    *Rates
+		from, to string
+		want     float64
+		err      error
+	}{
+		{name: "from base", rates: rates, from: "INR", to: "USD", want: 0.012},
+		{name: "to base", rates: rates, from: "USD", to: "INR", want: 1 / 0.012},
+		{name: "cross", rates: rates, from: "USD", to: "EUR", want: 0.011 / 0.012},
+		{name: "same currency without rates", from: "GBP", to: "GBP", want: 1},
+		{name: "no rate", rates: rates, from: "INR", to: "GBP", err: ErrNoRate},
+		{name: "nil store", from: "INR", to: "USD", err: ErrNoRate},
+		{name: "unknown currency", rates: rates, from: "INR", to: "XXX", err: ErrUnknownCurrency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rates.Rate(tt.from, tt.to)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Rate() = %v, want %v", err, tt.err)
+			}
+			if tt.err == nil && math.Abs(got.Rate-tt.want) > 1e-12 {
+				t.Errorf("Rate() = %v, want %v", got.Rate, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRatesCSV(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		in := "# published daily\nBase,Currency,Rate,As_Of\ninr,usd,0.0119,2026-10-19\nINR, EUR ,0.0109,2026-10-19\n"
+		got, err := ParseRatesCSV(strings.NewReader(in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Base != "INR" || !got.AsOf.Equal(time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("base %q as of %v", got.Base, got.AsOf)
+		}
+		if got.Rates["USD"] != 0.0119 || got.Rates["EUR"] != 0.0109 || len(got.Rates) != 2 {
+			t.Errorf("rates = %v", got.Rates)
+		}
+	})
+
+	invalid := []struct {
+		name, in, want string
+	}{
+		{"missing column", "base,currency,rate\nINR,USD,0.0119\n", `missing column "as_of"`},
+		{"mixed base", "base,currency,rate,as_of\nINR,USD,0.0119,2026-10-19\nUSD,EUR,0.92,2026-10-19\n", "line 3"},
+		{"mixed date", "base,currency,rate,as_of\nINR,USD,0.0119,2026-10-19\nINR,EUR,0.0109,2026-10-18\n", "line 3"},
+		{"bad rate", "base,currency,rate,as_of\nINR,USD,abc,2026-10-19\n", `line 2: invalid rate "abc"`},
+		{"bad date", "base,currency,rate,as_of\nINR,USD,0.0119,19/10/2026\n", "line 2: invalid as_of"},
+		{"rate not positive", "base,currency,rate,as_of\nINR,USD,0,2026-10-19\n", "greater than 0"},
+		{"unknown currency", "base,currency,rate,as_of\nINR,XXX,1,2026-10-19\n", `unknown currency "XXX"`},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRatesCSV(strings.NewReader(tt.in))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseRatesCSV() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"airline-booking/pkg/apperr"
+)
+
+// idleTimeout is how long an unused client bucket is kept.
+const idleTimeout = time.Minute
+
+// Limiter is a per-client token bucket whose rate can be changed at runtime.
+type Limiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     int
+	clients   map[string]*bucket
+	lastPrune time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// New creates a limiter that allows everything until SetLimit is called.
+func New() *Limiter {
+	return &Limiter{clients: make(map[string]*bucket), lastPrune: time.Now()}
+}
+
+// SetLimit changes the allowed requests per second and burst size per
+// client. A rate of 0 disables limiting.
+func (l *Limiter) SetLimit(rate float64, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = rate
+	l.burst = burst
+	for _, b := range l.clients {
+		b.tokens = min(b.tokens, float64(burst))
+	}
+}
+
+// Allow reports whether the client identified by key may make a request now.
+func (l *Limiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rate <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	l.prune(now)
+
+	b, ok := l.clients[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.clients[key] = b
+	}
+	b.tokens = min(float64(l.burst), b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (l *Limiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < idleTimeout {
+		return
+	}
+	l.lastPrune = now
+	for key, b := range l.clients {
+		if now.Sub(b.last) > idleTimeout {
+			delete(l.clients, key)
+		}
+	}
+}
+
+// Middleware rejects requests over the limit with 429. Health checks are
+// never limited.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/health/") && !l.Allow(clientIP(r)) {
+			w.Header().Set("Retry-After", "1")
+			apperr.WriteProblem(w, r, http.StatusTooManyRequests, "rate_limited", "Too many requests")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,177 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrCircuitOpen is returned instead of contacting Redis while the breaker is open.
+var ErrCircuitOpen = errors.New("redis circuit breaker is open")
+
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateOpen
+	stateHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case stateOpen:
+		return "open"
+	case stateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitBreaker stops calls to Redis after repeated failures so requests
+// fall back to Postgres immediately instead of waiting on timeouts.
+type CircuitBreaker struct {
+	mu          sync.Mutex
+	state       breakerState
+	failures    int
+	threshold   int
+	openTimeout time.Duration
+	openedAt    time.Time
+	trial       bool
+}
+
+// NewCircuitBreaker creates a breaker that opens after threshold consecutive
+// failures and allows a single trial call once openTimeout has passed.
+func NewCircuitBreaker(threshold int, openTimeout time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = 5
+	}
+	if openTimeout <= 0 {
+		openTimeout = 10 * time.Second
+	}
+	return &CircuitBreaker{threshold: threshold, openTimeout: openTimeout}
+}
+
+// Allow reports whether a call may go through to Redis.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		if time.Since(b.openedAt) < b.openTimeout {
+			return false
+		}
+		b.state = stateHalfOpen
+		b.trial = true
+		return true
+	case stateHalfOpen:
+		// Only one trial call at a time while half-open
+		if b.trial {
+			return false
+		}
+		b.trial = true
+		return true
+	default:
+		return true
+	}
+}
+
+// Success records a successful call and closes the breaker.
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = stateClosed
+	b.failures = 0
+	b.trial = false
+}
+
+// Failure records a failed call and opens the breaker once the threshold is reached.
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.trial = false
+	if b.state == stateHalfOpen || b.failures >= b.threshold {
+		b.state = stateOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// State returns the current breaker state as a string.
+func (b *CircuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state.String()
+}
+
+// record classifies the result of a Redis call. Cache misses and caller
+// cancellations say nothing about Redis health and are ignored.
+func (b *CircuitBreaker) record(err error) {
+	switch {
+	case err == nil, errors.Is(err, redis.Nil):
+		b.Success()
+	case errors.Is(err, context.Canceled):
+		b.mu.Lock()
+		b.trial = false
+		b.mu.Unlock()
+	default:
+		var netErr net.Error
+		if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, redis.ErrClosed) || isConnError(err) {
+			b.Failure()
+			return
+		}
+		// Server replied with an error (wrong type, etc.) so the connection is fine
+		b.Success()
+	}
+}
+
+func isConnError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) || errors.Is(err, net.ErrClosed)
+}
+
+type probeKey struct{}
+
+// breakerHook plugs the circuit breaker into every command sent by the client.
+type breakerHook struct {
+	breaker *CircuitBreaker
+}
+
+func (h breakerHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h breakerHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		// Health probes bypass the breaker so a recovered Redis is noticed quickly
+		if ctx.Value(probeKey{}) != nil {
+			return next(ctx, cmd)
+		}
+		if !h.breaker.Allow() {
+			cmd.SetErr(ErrCircuitOpen)
+			return ErrCircuitOpen
+		}
+		err := next(ctx, cmd)
+		h.breaker.record(err)
+		return err
+	}
+}
+
+func (h breakerHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if !h.breaker.Allow() {
+			for _, cmd := range cmds {
+				cmd.SetErr(ErrCircuitOpen)
+			}
+			return ErrCircuitOpen
+		}
+		err := next(ctx, cmds)
+		h.breaker.record(err)
+		return err
+	}
+}
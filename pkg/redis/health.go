@@ -0,0 +1,41 @@
+package redis
+
+import (
+	"net/http"
+	"time"
+
+	"airline-booking/pkg/response"
+)
+
+// Health describes the current state of the Redis cache.
+type Health struct {
+	Status    string    `json:"status"`
+	Address   string    `json:"address"`
+	Breaker   string    `json:"breaker"`
+	LastError string    `json:"last_error,omitempty"`
+	LastCheck time.Time `json:"last_check"`
+}
+
+// Health returns a snapshot of the cache health.
+func (r *RedisClient) Health() Health {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	status := "up"
+	if !r.healthy {
+		status = "down"
+	}
+	return Health{
+		Status:    status,
+		Address:   r.address,
+		Breaker:   r.Breaker.State(),
+		LastError: r.lastError,
+		LastCheck: r.lastCheck,
+	}
+}
+
+// HealthHandler reports cache health. It always answers 200 because the
+// services keep working from Postgres while Redis is down.
+func (r *RedisClient) HealthHandler(w http.ResponseWriter, _ *http.Request) {
+	response.JSON(w, http.StatusOK, r.Health())
+}
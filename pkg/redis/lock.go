@@ -0,0 +1,188 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrLockNotAcquired is returned when another holder owns the lock.
+	ErrLockNotAcquired = errors.New("lock is held by another owner")
+	// ErrLockLost is returned when the lock expired or was taken over.
+	ErrLockLost = errors.New("lock is no longer held")
+)
+
+// acquireScript sets the lock if free and returns a new fencing token. The
+// token never drops below the server clock in milliseconds, so it keeps
+// increasing even if Redis loses the counter on restart.
+var acquireScript = redis.NewScript(`
+if not redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return 0
+end
+local token = redis.call('INCR', KEYS[2])
+local now = redis.call('TIME')
+local ms = tonumber(now[1]) * 1000 + math.floor(tonumber(now[2]) / 1000)
+if token < ms then
+	token = ms
+	redis.call('SET', KEYS[2], token)
+end
+return token
+`)
+
+// refreshScript extends the lock only if it is still owned by the caller.
+var refreshScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript deletes the lock only if it is still owned by the caller.
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// Locker hands out distributed locks stored in Redis.
+type Locker struct {
+	client *redis.Client
+}
+
+// NewLocker creates a locker on top of the given Redis client.
+func NewLocker(c *RedisClient) *Locker {
+	return &Locker{client: c.GetClient()}
+}
+
+// Lock is a held distributed lock. Token increases every time the lock is
+// acquired so writers can reject work from a holder that lost the lock.
+type Lock struct {
+	Key   string
+	Token int64
+
+	locker *Locker
+	value  string
+	ttl    time.Duration
+
+	mu      sync.Mutex
+	stopped bool
+	stop    chan struct{}
+}
+
+// TryAcquire takes the lock once, returning ErrLockNotAcquired if it is held.
+func (l *Locker) TryAcquire(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	value, err := randomValue()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := acquireScript.Run(ctx, l.client, []string{lockKey(key), fenceKey(key)}, value, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return nil, err
+	}
+	if token == 0 {
+		return nil, ErrLockNotAcquired
+	}
+
+	return &Lock{Key: key, Token: token, locker: l, value: value, ttl: ttl, stop: make(chan struct{})}, nil
+}
+
+// Acquire waits until the lock is taken or ctx is done.
+func (l *Locker) Acquire(ctx context.Context, key string, ttl, retry time.Duration) (*Lock, error) {
+	for {
+		lock, err := l.TryAcquire(ctx, key, ttl)
+		if !errors.Is(err, ErrLockNotAcquired) {
+			return lock, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ErrLockNotAcquired
+		case <-time.After(retry):
+		}
+	}
+}
+
+// Refresh extends the lock TTL, returning ErrLockLost if it is no longer owned.
+func (lk *Lock) Refresh(ctx context.Context) error {
+	ok, err := refreshScript.Run(ctx, lk.locker.client, []string{lockKey(lk.Key)}, lk.value, lk.ttl.Milliseconds()).Int64()
+	if err != nil {
+		return err
+	}
+	if ok == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// KeepAlive renews the lock in the background until Release is called. The
+// returned context is cancelled as soon as a renewal fails, so work guarded
+// by the lock stops once ownership can no longer be guaranteed.
+func (lk *Lock) KeepAlive(ctx context.Context) context.Context {
+	ctx, cancel := context.WithCancel(ctx)
+
+	go func() {
+		defer cancel()
+		ticker := time.NewTicker(lk.ttl / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-lk.stop:
+				return
+			case <-ticker.C:
+				if err := lk.Refresh(ctx); err != nil {
+					log.Printf("Lost lock %s (token %d): %v", lk.Key, lk.Token, err)
+					return
+				}
+			}
+		}
+	}()
+
+	return ctx
+}
+
+// Release stops renewal and frees the lock if it is still owned.
+func (lk *Lock) Release(ctx context.Context) error {
+	lk.mu.Lock()
+	if !lk.stopped {
+		lk.stopped = true
+		close(lk.stop)
+	}
+	lk.mu.Unlock()
+
+	ok, err := releaseScript.Run(ctx, lk.locker.client, []string{lockKey(lk.Key)}, lk.value).Int64()
+	if err != nil {
+		return err
+	}
+	if ok == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+func lockKey(key string) string {
+	return "lock:" + key
+}
+
+func fenceKey(key string) string {
+	return "lock:" + key + ":fence"
+}
+
+func randomValue() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,133 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestLocker(t *testing.T) (*Locker, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return &Locker{client: client}, mr
+}
+
+func TestTryAcquire(t *testing.T) {
+	l, _ := newTestLocker(t)
+	ctx := context.Background()
+
+	first, err := l.TryAcquire(ctx, "seats", time.Second)
+	if err != nil {
+		t.Fatalf("TryAcquire: %v", err)
+	}
+	if _, err := l.TryAcquire(ctx, "seats", time.Second); !errors.Is(err, ErrLockNotAcquired) {
+		t.Fatalf("second TryAcquire error = %v, want ErrLockNotAcquired", err)
+	}
+	if err := first.Release(ctx); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+
+	second, err := l.TryAcquire(ctx, "seats", time.Second)
+	if err != nil {
+		t.Fatalf("TryAcquire after release: %v", err)
+	}
+	if second.Token <= first.Token {
+		t.Errorf("token %d after release, want more than %d", second.Token, first.Token)
+	}
+}
+
+func TestRefreshAfterLoss(t *testing.T) {
+	tests := []struct {
+		name string
+		// lose makes the lock holder lose the lock
+		lose func(t *testing.T, l *Locker, mr *miniredis.Miniredis)
+		want error
+	}{
+		{
+			name: "still held",
+			lose: func(*testing.T, *Locker, *miniredis.Miniredis) {},
+		},
+		{
+			name: "expired",
+			lose: func(_ *testing.T, _ *Locker, mr *miniredis.Miniredis) { mr.FastForward(2 * time.Second) },
+			want: ErrLockLost,
+		},
+		{
+			name: "expired and taken over",
+			lose: func(t *testing.T, l *Locker, mr *miniredis.Miniredis) {
+				mr.FastForward(2 * time.Second)
+				if _, err := l.TryAcquire(context.Background(), "seats", time.Second); err != nil {
+					t.Fatalf("takeover: %v", err)
+				}
+			},
+			want: ErrLockLost,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, mr := newTestLocker(t)
+			ctx := context.Background()
+			lock, err := l.TryAcquire(ctx, "seats", time.Second)
+			if err != nil {
+				t.Fatalf("TryAcquire: %v", err)
+			}
+
+			tt.lose(t, l, mr)
+			if err := lock.Refresh(ctx); !errors.Is(err, tt.want) {
+				t.Errorf("Refresh error = %v, want %v", err, tt.want)
+			}
+			if err := lock.Release(ctx); !errors.Is(err, tt.want) {
+				t.Errorf("Release error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeepAliveCancelsOnLoss(t *testing.T) {
+	l, mr := newTestLocker(t)
+	lock, err := l.TryAcquire(context.Background(), "seats", 150*time.Millisecond)
+	if err != nil {
+		t.Fatalf("TryAcquire: %v", err)
+	}
+	ctx := lock.KeepAlive(context.Background())
+
+	// Renewals keep the lock while it is owned
+	time.Sleep(200 * time.Millisecond)
+	if ctx.Err() != nil {
+		t.Fatal("context cancelled while the lock was still held")
+	}
+
+	// The lock expires, e.g. while the process was paused
+	mr.Del(lockKey("seats"))
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("context not cancelled after the lock was lost")
+	}
+	if err := lock.Release(context.Background()); !errors.Is(err, ErrLockLost) {
+		t.Errorf("Release error = %v, want ErrLockLost", err)
+	}
+}
+
+func TestKeepAliveStopsOnRelease(t *testing.T) {
+	l, _ := newTestLocker(t)
+	lock, err := l.TryAcquire(context.Background(), "seats", 150*time.Millisecond)
+	if err != nil {
+		t.Fatalf("TryAcquire: %v", err)
+	}
+	ctx := lock.KeepAlive(context.Background())
+	if err := lock.Release(context.Background()); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("context not cancelled after release")
+	}
+}
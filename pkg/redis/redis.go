@@ -3,6 +3,7 @@ package redis
 import (
 	"context"
 	"log"
+	"sync"
 	"time"
 
 	"airline-booking/pkg/config"
@@ -11,36 +12,72 @@ import (
 )
 
 type RedisClient struct {
-	Client *redis.Client
-	Ctx    context.Context
+	Client  *redis.Client
+	Ctx     context.Context
+	Breaker *CircuitBreaker
+
+	address  string
+	interval time.Duration
+	stop     chan struct{}
+
+	mu        sync.RWMutex
+	healthy   bool
+	lastError string
+	lastCheck time.Time
 }
 
-// NewRedisClient initializes and tests a Redis connection.
+// NewRedisClient initializes a Redis connection. Redis is only a cache, so an
+// unreachable server is logged and the client keeps retrying in the background
+// while callers fall back to Postgres.
 func NewRedisClient(cfg *config.RedisConfig) *RedisClient {
 	opt := &redis.Options{
-		Addr:         cfg.Address,
-		Password:     cfg.Password,
+		Addr: cfg.Address,
+		// Read on every new connection so rotated passwords are picked up
+		CredentialsProvider: func() (string, string) {
+			return "", cfg.CurrentPassword()
+		},
 		DB:           cfg.DB,
 		PoolSize:     cfg.PoolSize,
 		MinIdleConns: cfg.MinIdleConns,
+		ReadTimeout:  parseDuration(cfg.ReadTimeout),
+		WriteTimeout: parseDuration(cfg.WriteTimeout),
+		DialTimeout:  parseDuration(cfg.DialTimeout),
 	}
 
 	client := redis.NewClient(opt)
+	breaker := NewCircuitBreaker(cfg.Breaker.FailureThreshold, cfg.Breaker.OpenTimeout)
+	client.AddHook(breakerHook{breaker: breaker})
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	interval := cfg.ReconnectInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	r := &RedisClient{
+		Client:   client,
+		Ctx:      context.Background(),
+		Breaker:  breaker,
+		address:  cfg.Address,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
 
 	// Test connection
-	if err := client.Ping(ctx).Err(); err != nil {
-		log.Fatalf("Failed to connect to Redis at %s: %v", cfg.Address, err)
+	if err := r.probe(); err != nil {
+		log.Printf("Redis unavailable at %s, running without cache: %v", cfg.Address, err)
+	} else {
+		log.Printf("Connected to Redis at %s", cfg.Address)
 	}
 
-	log.Printf("Connected to Redis at %s", cfg.Address)
+	go r.monitor()
 
-	return &RedisClient{
-		Client: client,
-		Ctx:    context.Background(),
-	}
+	return r
+}
+
+// parseDuration reads an already validated duration; empty means the client default.
+func parseDuration(value string) time.Duration {
+	d, _ := time.ParseDuration(value)
+	return d
 }
 
 // GetClient returns the underlying Redis client.
@@ -48,11 +85,67 @@ func (r *RedisClient) GetClient() *redis.Client {
 	return r.Client
 }
 
+// Healthy reports whether the last health check against Redis succeeded.
+func (r *RedisClient) Healthy() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.healthy
+}
+
+// probe pings Redis outside the circuit breaker and updates the health state.
+func (r *RedisClient) probe() error {
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), probeKey{}, true), 2*time.Second)
+	defer cancel()
+
+	err := r.Client.Ping(ctx).Err()
+
+	r.mu.Lock()
+	r.healthy = err == nil
+	r.lastCheck = time.Now()
+	if err != nil {
+		r.lastError = err.Error()
+	} else {
+		r.lastError = ""
+	}
+	r.mu.Unlock()
+
+	if err == nil {
+		r.Breaker.Success()
+	}
+	return err
+}
+
+// monitor keeps checking Redis so the cache is picked up again once it recovers.
+func (r *RedisClient) monitor() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			wasHealthy := r.Healthy()
+			err := r.probe()
+			switch {
+			case err != nil && wasHealthy:
+				log.Printf("Lost connection to Redis at %s: %v", r.address, err)
+			case err == nil && !wasHealthy:
+				log.Printf("Reconnected to Redis at %s", r.address)
+			}
+		}
+	}
+}
+
 // Close safely closes the Redis client connection.
 func (r *RedisClient) Close() {
 	if r.Client == nil {
 		return
 	}
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
 	if err := r.Client.Close(); err != nil {
 		log.Printf("Error closing Redis: %v", err)
 	} else {
@@ -0,0 +1,44 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// RunSingleton runs job every interval on at most one instance at a time.
+// Instances that cannot take the job lock skip the run, including while
+// Redis is unavailable. It blocks until ctx is done.
+func (l *Locker) RunSingleton(ctx context.Context, name string, interval time.Duration, job func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.runOnce(ctx, name, interval, job)
+		}
+	}
+}
+
+func (l *Locker) runOnce(ctx context.Context, name string, interval time.Duration, job func(context.Context) error) {
+	lock, err := l.TryAcquire(ctx, "job:"+name, interval)
+	if err != nil {
+		if !errors.Is(err, ErrLockNotAcquired) && !errors.Is(err, ErrCircuitOpen) {
+			log.Printf("Job %s skipped, could not take lock: %v", name, err)
+		}
+		return
+	}
+
+	jobCtx := lock.KeepAlive(ctx)
+	if err := job(jobCtx); err != nil {
+		log.Printf("Job %s failed: %v", name, err)
+	}
+
+	if err := lock.Release(context.Background()); err != nil && !errors.Is(err, ErrLockLost) {
+		log.Printf("Job %s could not release lock: %v", name, err)
+	}
+}
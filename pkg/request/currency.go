@@ -0,0 +1,19 @@
+package request
+
+import (
+	"net/http"
+	"strings"
+
+	"airline-booking/pkg/money"
+)
+
+// Currency reads the currency query parameter amounts are shown in, e.g.
+// ?currency=USD. It returns "" without one and money.ErrUnknownCurrency if
+// it is not an ISO 4217 code.
+func Currency(r *http.Request) (string, error) {
+	currency := strings.ToUpper(r.URL.Query().Get("currency"))
+	if currency != "" && !money.Valid(currency) {
+		return "", money.ErrUnknownCurrency
+	}
+	return currency, nil
+}
@@ -0,0 +1,27 @@
+package request
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"airline-booking/pkg/money"
+)
+
+func TestCurrency(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+		err   error
+	}{
+		{query: "", want: ""},
+		{query: "?currency=usd", want: "USD"},
+		{query: "?currency=XXX", err: money.ErrUnknownCurrency},
+	}
+	for _, tt := range tests {
+		got, err := Currency(httptest.NewRequest("GET", "/flights"+tt.query, nil))
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("Currency(%q) = %q, %v, want %q, %v", tt.query, got, err, tt.want, tt.err)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Package request reads JSON request bodies strictly.
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"airline-booking/pkg/apperr"
+	"airline-booking/pkg/validate"
+)
+
+// MaxBodyBytes is the largest request body Decode accepts.
+const MaxBodyBytes = 1 << 20
+
+// ErrEmptyBody is returned by Decode when the request has no body.
+var ErrEmptyBody = apperr.Validation("empty_body", "request body is empty")
+
+// ErrBodyTooLarge is returned by Decode when the body exceeds MaxBodyBytes.
+var ErrBodyTooLarge = apperr.New(apperr.KindTooLarge, "body_too_large", fmt.Sprintf("request body must not exceed %d bytes", MaxBodyBytes))
+
+// Decode reads a single JSON object from the body into v, rejecting unknown
+// fields, trailing data and bodies over MaxBodyBytes, then validates v. It
+// returns *apperr.Error values ready to be written as problem details. v
+// may already hold values, e.g. for a PATCH; fields absent from the body
+// keep them.
+func Decode(w http.ResponseWriter, r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		return decodeError(err)
+	}
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return apperr.Invalid("request body must contain a single JSON object")
+	}
+	return validate.Check(v)
+}
+
+// decodeError turns a JSON decoding error into a client-facing one.
+func decodeError(err error) error {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+		maxErr    *http.MaxBytesError
+		timeErr   *time.ParseError
+	)
+	switch {
+	case errors.Is(err, io.EOF):
+		return ErrEmptyBody
+	case errors.As(err, &maxErr):
+		return ErrBodyTooLarge
+	case errors.As(err, &syntaxErr):
+		return apperr.Invalid("malformed JSON at offset %d", syntaxErr.Offset)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return apperr.Invalid("malformed JSON: unexpected end of body")
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return apperr.Validation("validation_failed", "request has invalid fields",
+			apperr.FieldError{Field: typeErr.Field, Message: "must be " + jsonType(typeErr.Type)})
+	case errors.As(err, &timeErr):
+		return apperr.Invalid("timestamps must be RFC 3339 with an offset, e.g. 2025-06-01T14:30:00+02:00, got %q", timeErr.Value)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return apperr.Validation("unknown_field", "request has unknown fields",
+			apperr.FieldError{Field: field, Message: "is not a known field"})
+	default:
+		return apperr.Invalid("invalid request body: %v", err)
+	}
+}
+
+// jsonType names the JSON type a Go type is decoded from.
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	}
+	return "a " + t.String()
+}
@@ -0,0 +1,15 @@
+// Package response writes JSON response bodies.
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// JSON answers with status and v encoded as JSON. Errors are answered with
+// apperr.Write instead.
+func JSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,68 @@
+// Package ruleset keeps versioned rules, such as the pricing rules and the
+// tax rules, in a table of their own. Each save adds a version and the
+// latest is in force; readers get it through the cache.
+package ruleset
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"airline-booking/pkg/cache"
+	"airline-booking/pkg/db"
+)
+
+// TTL is how long the rules in force are cached.
+const TTL = time.Minute
+
+// Set is a saved version of the rules. The latest version is in force.
+type Set[T any] struct {
+	Version   int       `db:"version" json:"version"`
+	Rules     T         `db:"rules" json:"rules"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// Store reads and saves the versions of rules of type T, which must be
+// stored as JSON, in a table with version, rules and created_at columns.
+type Store[T any] struct {
+	Cluster *db.Cluster
+	Cache   *cache.Cache
+	// Table holds the versions; Key caches the one in force
+	Table string
+	Key   string
+}
+
+// Latest returns the rules in force. Before any are saved it returns the
+// zero rules at version 0.
+func (s Store[T]) Latest(ctx context.Context) (Set[T], error) {
+	opts := cache.Options{TTL: TTL, Refresh: db.PrimaryForced(ctx)}
+	return cache.GetOrLoad(ctx, s.Cache, s.Key, opts, func(ctx context.Context) (Set[T], error) {
+		var rs Set[T]
+		err := s.Cluster.Reader(ctx).GetContext(ctx, &rs, fmt.Sprintf(`SELECT version, rules, created_at FROM %s ORDER BY version DESC LIMIT 1`, s.Table))
+		if errors.Is(err, sql.ErrNoRows) {
+			return rs, nil
+		}
+		if err != nil {
+			return rs, fmt.Errorf("failed to fetch %s: %w", s.Table, err)
+		}
+		return rs, nil
+	})
+}
+
+// Save puts a new version of the rules in force.
+func (s Store[T]) Save(ctx context.Context, rules T) (Set[T], error) {
+	rs := Set[T]{Rules: rules}
+	err := s.Cluster.Primary.QueryRowxContext(ctx, fmt.Sprintf(`INSERT INTO %s (rules) VALUES ($1) RETURNING version, created_at`, s.Table), rules).
+		Scan(&rs.Version, &rs.CreatedAt)
+	if err != nil {
+		return rs, fmt.Errorf("failed to save %s: %w", s.Table, err)
+	}
+
+	if err := s.Cache.Delete(ctx, s.Key); err != nil {
+		log.Printf("Failed to invalidate %s cache: %v", s.Table, err)
+	}
+	return rs, nil
+}
@@ -0,0 +1,97 @@
+package ruleset
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"regexp"
+	"testing"
+	"time"
+
+	"airline-booking/pkg/cache"
+	"airline-booking/pkg/db"
+	"airline-booking/pkg/jsonb"
+	"airline-booking/pkg/redis"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jmoiron/sqlx"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+type rules struct {
+	Markup float64 `json:"markup"`
+}
+
+func (r rules) Value() (driver.Value, error) { return jsonb.Value(r) }
+
+func (r *rules) Scan(src any) error { return jsonb.Scan(src, r) }
+
+func newStore(t *testing.T) (Store[rules], sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	t.Cleanup(func() { mockDB.Close() })
+
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	s := Store[rules]{
+		Cluster: &db.Cluster{Primary: sqlx.NewDb(mockDB, "sqlmock")},
+		Cache:   cache.New(&redis.RedisClient{Client: client}),
+		Table:   "test_rules",
+		Key:     "test:rules",
+	}
+	return s, mock
+}
+
+const latest = `SELECT version, rules, created_at FROM test_rules ORDER BY version DESC LIMIT 1`
+
+func TestLatestBeforeAnySaved(t *testing.T) {
+	s, mock := newStore(t)
+	mock.ExpectQuery(regexp.QuoteMeta(latest)).WillReturnError(sql.ErrNoRows)
+
+	rs, err := s.Latest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.Version != 0 || rs.Rules != (rules{}) {
+		t.Errorf("Latest() = %+v, want the zero rules", rs)
+	}
+}
+
+func TestSaveReplacesCachedRules(t *testing.T) {
+	s, mock := newStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta(latest)).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "rules", "created_at"}).AddRow(1, []byte(`{"markup":1.1}`), now))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO test_rules (rules) VALUES ($1) RETURNING version, created_at`)).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "created_at"}).AddRow(2, now))
+	mock.ExpectQuery(regexp.QuoteMeta(latest)).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "rules", "created_at"}).AddRow(2, []byte(`{"markup":1.2}`), now))
+
+	if _, err := s.Latest(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Served from the cache
+	if rs, err := s.Latest(ctx); err != nil || rs.Version != 1 {
+		t.Fatalf("Latest() = %+v, %v, want version 1", rs, err)
+	}
+	saved, err := s.Save(ctx, rules{Markup: 1.2})
+	if err != nil || saved.Version != 2 {
+		t.Fatalf("Save() = %+v, %v, want version 2", saved, err)
+	}
+	rs, err := s.Latest(ctx)
+	if err != nil || rs.Version != 2 || rs.Rules.Markup != 1.2 {
+		t.Errorf("Latest() after Save = %+v, %v, want version 2", rs, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,21 @@
+package server
+
+import (
+	"log"
+	"net/http"
+
+	"airline-booking/pkg/config"
+)
+
+// ListenAndServe serves handler on the service's listen address, over TLS
+// when a certificate is configured.
+func ListenAndServe(name string, svc *config.ServiceConfig, handler http.Handler) error {
+	srv := &http.Server{Addr: svc.Listen, Handler: handler}
+
+	if svc.TLS.CertFile != "" {
+		log.Printf("%s listening on %s (TLS)...", name, svc.Listen)
+		return srv.ListenAndServeTLS(svc.TLS.CertFile, svc.TLS.KeyFile)
+	}
+	log.Printf("%s listening on %s...", name, svc.Listen)
+	return srv.ListenAndServe()
+}
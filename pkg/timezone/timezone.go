@@ -0,0 +1,29 @@
+// Package timezone resolves the IANA time zones of airports. The zone
+// database is embedded so zones resolve on hosts without zoneinfo.
+package timezone
+
+import (
+	"sync"
+	"time"
+	_ "time/tzdata"
+)
+
+var locations sync.Map
+
+// Location loads an IANA time zone, caching it. An empty or unknown name
+// is UTC; unknown names are not cached, in case the zone database is
+// updated.
+func Location(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	locations.Store(name, loc)
+	return loc
+}
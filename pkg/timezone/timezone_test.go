@@ -0,0 +1,31 @@
+package timezone
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocation(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "", want: "UTC"},
+		{name: "Asia/Kolkata", want: "Asia/Kolkata"},
+		{name: "Mars/Olympus", want: "UTC"},
+	}
+	for _, tt := range tests {
+		if got := Location(tt.name); got.String() != tt.want {
+			t.Errorf("Location(%q) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+	if Location("Asia/Kolkata") != Location("Asia/Kolkata") {
+		t.Error("Location() did not cache the zone")
+	}
+	if _, ok := locations.Load("Mars/Olympus"); ok {
+		t.Error("Location() cached an unknown zone")
+	}
+	if Location("") != time.UTC {
+		t.Error(`Location("") is not time.UTC`)
+	}
+}
@@ -0,0 +1,300 @@
+// Package validate checks structs against rules declared in `validate`
+// struct tags, e.g.
+//
+//	Seats int `json:"seats" validate:"min=1,max=9"`
+//
+// Supported rules: required, min=N and max=N (value of numbers, length of
+// strings), gt=N, len=N (length of strings and lists), oneof=a b c, code
+// (uppercase letters and digits), datetime (RFC 3339) and timezone (IANA
+// name). Rules other than
+// required skip empty values. Errors are reported under the JSON name of
+// the field. The rules of a type are parsed once, when it is registered or
+// first checked. Types can add cross-field rules by implementing Validator and
+// be compared by min, max and gt by implementing Measurer.
+package validate
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"airline-booking/pkg/apperr"
+)
+
+// Validator is implemented by types with rules that involve several fields.
+// It is called after the tag rules.
+type Validator interface {
+	Validate() []apperr.FieldError
+}
+
+// Measurer is implemented by types that min, max and gt compare by a
+// number of their own, such as an amount of money.
+type Measurer interface {
+	Measure() float64
+}
+
+// Register parses the rules of each struct type given, by a value of it,
+// and panics if one is malformed. Call it from the init of the package
+// declaring the types so a bad tag stops the service at startup rather
+// than a request.
+func Register(types ...any) {
+	for _, v := range types {
+		if _, err := fieldsOf(reflect.Indirect(reflect.ValueOf(v)).Type()); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Struct checks v, a struct or pointer to one, and returns every problem
+// found. A type whose rules are malformed is reported as invalid as a
+// whole; Check returns the error instead.
+func Struct(v any) []apperr.FieldError {
+	problems, err := check(v)
+	if err != nil {
+		log.Print(err)
+		return []apperr.FieldError{{Field: "", Message: "cannot be validated"}}
+	}
+	return problems
+}
+
+// Check validates v and returns an apperr validation error listing every
+// problem, or nil. Malformed rules are returned as a plain error.
+func Check(v any) error {
+	problems, err := check(v)
+	if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return apperr.Validation("validation_failed", "request has invalid fields", problems...)
+	}
+	return nil
+}
+
+func check(v any) ([]apperr.FieldError, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	fields, err := fieldsOf(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []apperr.FieldError
+	for _, f := range fields {
+		for _, r := range f.rules {
+			if msg := r.check(rv.Field(f.index)); msg != "" {
+				problems = append(problems, apperr.FieldError{Field: f.name, Message: msg})
+				break
+			}
+		}
+	}
+
+	if vv, ok := v.(Validator); ok {
+		problems = append(problems, vv.Validate()...)
+	}
+	return problems, nil
+}
+
+// field is a struct field with rules, by its index and JSON name.
+type field struct {
+	index int
+	name  string
+	rules []rule
+}
+
+// rule is one parsed rule; limit is the number of min, max, gt and len.
+type rule struct {
+	name  string
+	arg   string
+	limit float64
+}
+
+// parsed caches the fields of each struct type, or the error parsing them.
+var parsed sync.Map
+
+type parseResult struct {
+	fields []field
+	err    error
+}
+
+// fieldsOf returns the fields of struct type t that have rules, parsing
+// them the first time t is seen.
+func fieldsOf(t reflect.Type) ([]field, error) {
+	if res, ok := parsed.Load(t); ok {
+		return res.(parseResult).fields, res.(parseResult).err
+	}
+	fields, err := parseFields(t)
+	parsed.Store(t, parseResult{fields, err})
+	return fields, err
+}
+
+func parseFields(t reflect.Type) ([]field, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("validate: %s is not a struct", t)
+	}
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("validate")
+		if tag == "" || !sf.IsExported() {
+			continue
+		}
+		f := field{index: i, name: jsonName(sf)}
+		for _, s := range strings.Split(tag, ",") {
+			r, err := parseRule(s, sf.Type)
+			if err != nil {
+				return nil, fmt.Errorf("validate: %s.%s: %w", t, sf.Name, err)
+			}
+			f.rules = append(f.rules, r)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// parseRule parses one rule of a field of type t and checks it applies.
+func parseRule(s string, t reflect.Type) (rule, error) {
+	name, arg, _ := strings.Cut(s, "=")
+	r := rule{name: name, arg: arg}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch name {
+	case "required", "oneof":
+	case "min", "max", "gt", "len":
+		var err error
+		if r.limit, err = strconv.ParseFloat(arg, 64); err != nil || (name == "len" && r.limit != float64(int(r.limit))) {
+			return r, fmt.Errorf("bad %s rule %q", name, s)
+		}
+		if !measurable(t) {
+			return r, fmt.Errorf("%s rule on %s, which cannot be measured", name, t)
+		}
+	case "code", "datetime", "timezone":
+		if t.Kind() != reflect.String {
+			return r, fmt.Errorf("%s rule on %s, which is not a string", name, t)
+		}
+	default:
+		return r, fmt.Errorf("unknown rule %q", s)
+	}
+	return r, nil
+}
+
+// check applies the rule to a field value and returns the problem, if any.
+func (r rule) check(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			if r.name == "required" {
+				return "is required"
+			}
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	if r.name == "required" {
+		if v.IsZero() || (v.Kind() == reflect.String && strings.TrimSpace(v.String()) == "") {
+			return "is required"
+		}
+		return ""
+	}
+	if v.IsZero() && r.name != "min" && r.name != "gt" {
+		return ""
+	}
+
+	switch r.name {
+	case "min", "max", "gt":
+		n, unit := measure(v)
+		switch {
+		case r.name == "min" && n < r.limit:
+			return fmt.Sprintf("must be at least %s%s", r.arg, unit)
+		case r.name == "max" && n > r.limit:
+			return fmt.Sprintf("must be at most %s%s", r.arg, unit)
+		case r.name == "gt" && n <= r.limit:
+			return fmt.Sprintf("must be greater than %s%s", r.arg, unit)
+		}
+	case "len":
+		if n, unit := measure(v); n != r.limit {
+			return fmt.Sprintf("must be exactly %s%s", r.arg, unit)
+		}
+	case "code":
+		if strings.IndexFunc(v.String(), func(r rune) bool { return (r < 'A' || r > 'Z') && (r < '0' || r > '9') }) >= 0 {
+			return "must contain only uppercase letters and digits"
+		}
+	case "oneof":
+		allowed := strings.Fields(r.arg)
+		if !slices.Contains(allowed, fmt.Sprint(v.Interface())) {
+			return "must be one of " + strings.Join(allowed, ", ")
+		}
+	case "datetime":
+		if _, err := time.Parse(time.RFC3339, v.String()); err != nil {
+			return "must be an RFC 3339 timestamp, e.g. 2025-06-01T14:30:00+02:00"
+		}
+	case "timezone":
+		if !isZone(v.String()) {
+			return "must be an IANA time zone, e.g. Asia/Kolkata"
+		}
+	}
+	return ""
+}
+
+// isZone reports whether name is an IANA time zone. LoadLocation also
+// takes "" for UTC and "Local" for the zone of the server, neither of
+// which can be stored.
+func isZone(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+var measurerType = reflect.TypeFor[Measurer]()
+
+// measurable reports whether min, max, gt and len can compare values of t.
+func measurable(t reflect.Type) bool {
+	if t.Implements(measurerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.String, reflect.Slice, reflect.Map:
+		return true
+	}
+	return false
+}
+
+// measure returns the number a min/max rule compares: the value of a number
+// or the length of a string or list.
+func measure(v reflect.Value) (float64, string) {
+	if m, ok := v.Interface().(Measurer); ok {
+		return m.Measure(), ""
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), ""
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), " characters"
+	case reflect.Slice, reflect.Map:
+		return float64(v.Len()), " items"
+	}
+	// parseRule only accepts measurable types
+	return 0, ""
+}
+
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return f.Name
+	}
+	return name
+}
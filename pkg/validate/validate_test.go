@@ -0,0 +1,140 @@
+package validate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"airline-booking/pkg/apperr"
+)
+
+type amount float64
+
+func (a amount) Measure() float64 { return float64(a) }
+
+type payload struct {
+	Name     string   `json:"name" validate:"required,max=5"`
+	Code     string   `json:"code" validate:"len=3,code"`
+	Seats    int      `json:"seats" validate:"min=1,max=9"`
+	Class    string   `json:"class" validate:"oneof=economy business"`
+	At       string   `json:"at" validate:"datetime"`
+	Zone     string   `json:"zone" validate:"timezone"`
+	Tags     []string `json:"tags" validate:"max=2"`
+	Price    amount   `json:"price" validate:"gt=0"`
+	Optional *int     `json:"optional" validate:"min=1"`
+	internal string   `validate:"required"`
+}
+
+func valid() payload {
+	return payload{Name: "Ana", Seats: 1, Price: 10}
+}
+
+func TestStruct(t *testing.T) {
+	zero := 0
+	tests := []struct {
+		name   string
+		change func(p *payload)
+		field  string
+		msg    string
+	}{
+		{name: "valid", change: func(*payload) {}},
+		{name: "required", change: func(p *payload) { p.Name = "  " }, field: "name", msg: "is required"},
+		{name: "max length counts runes", change: func(p *payload) { p.Name = "Zoëää" }},
+		{name: "max length", change: func(p *payload) { p.Name = "Ananya" }, field: "name", msg: "must be at most 5 characters"},
+		{name: "len", change: func(p *payload) { p.Code = "AB" }, field: "code", msg: "must be exactly 3 characters"},
+		{name: "code", change: func(p *payload) { p.Code = "ab1" }, field: "code", msg: "must contain only uppercase letters and digits"},
+		{name: "min applies to zero", change: func(p *payload) { p.Seats = 0 }, field: "seats", msg: "must be at least 1"},
+		{name: "max", change: func(p *payload) { p.Seats = 10 }, field: "seats", msg: "must be at most 9"},
+		{name: "oneof", change: func(p *payload) { p.Class = "first" }, field: "class", msg: "must be one of economy, business"},
+		{name: "datetime", change: func(p *payload) { p.At = "2025-06-01 14:30" }, field: "at"},
+		{name: "timezone", change: func(p *payload) { p.Zone = "Mars/Olympus" }, field: "zone"},
+		{name: "timezone of the server", change: func(p *payload) { p.Zone = "Local" }, field: "zone"},
+		{name: "known timezone", change: func(p *payload) { p.Zone = "Asia/Kolkata" }},
+		{name: "list length", change: func(p *payload) { p.Tags = []string{"a", "b", "c"} }, field: "tags", msg: "must be at most 2 items"},
+		{name: "measurer", change: func(p *payload) { p.Price = 0 }, field: "price", msg: "must be greater than 0"},
+		{name: "nil pointer skipped", change: func(p *payload) { p.Optional = nil }},
+		{name: "pointer checked", change: func(p *payload) { p.Optional = &zero }, field: "optional", msg: "must be at least 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := valid()
+			tt.change(&p)
+			problems := Struct(p)
+			if tt.field == "" {
+				if len(problems) != 0 {
+					t.Fatalf("Struct() = %v, want no problems", problems)
+				}
+				return
+			}
+			if len(problems) != 1 || problems[0].Field != tt.field {
+				t.Fatalf("Struct() = %v, want one problem with %s", problems, tt.field)
+			}
+			if tt.msg != "" && problems[0].Message != tt.msg {
+				t.Errorf("message = %q, want %q", problems[0].Message, tt.msg)
+			}
+		})
+	}
+}
+
+type (
+	unknownRule struct {
+		A string `validate:"email"`
+	}
+	badLimit struct {
+		A int `validate:"min=one"`
+	}
+	fractionalLen struct {
+		A string `validate:"len=2.5"`
+	}
+	unmeasurable struct {
+		A struct{} `validate:"max=1"`
+	}
+	notString struct {
+		A int `validate:"code"`
+	}
+)
+
+func TestMalformedRules(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{name: "unknown rule", v: unknownRule{}, want: `unknown rule "email"`},
+		{name: "bad limit", v: badLimit{}, want: `bad min rule "min=one"`},
+		{name: "fractional len", v: fractionalLen{}, want: `bad len rule "len=2.5"`},
+		{name: "unmeasurable", v: unmeasurable{}, want: "cannot be measured"},
+		{name: "not a string", v: notString{}, want: "is not a string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(tt.v)
+			var appErr *apperr.Error
+			if err == nil || errors.As(err, &appErr) || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Check() = %v, want a plain error containing %q", err, tt.want)
+			}
+			if problems := Struct(tt.v); len(problems) != 1 {
+				t.Errorf("Struct() = %v, want one problem", problems)
+			}
+
+			defer func() {
+				if recover() == nil {
+					t.Error("Register() did not panic")
+				}
+			}()
+			Register(tt.v)
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if err := Check(valid()); err != nil {
+		t.Fatalf("Check() = %v", err)
+	}
+	p := valid()
+	p.Seats = 0
+	var appErr *apperr.Error
+	if err := Check(&p); !errors.As(err, &appErr) || appErr.Kind != apperr.KindValidation {
+		t.Fatalf("Check() = %v, want a validation error", err)
+	}
+}